@@ -12,9 +12,12 @@ import (
 	"syscall"
 	"time"
 
+	"go_integration/internal/audit"
 	"go_integration/internal/config"
+	"go_integration/internal/dedup"
 	"go_integration/internal/email"
 	"go_integration/internal/handlers"
+	"go_integration/internal/logging"
 	"go_integration/internal/pubsub"
 	"go_integration/internal/user"
 )
@@ -27,21 +30,33 @@ func main() {
 }
 
 func run() error {
+	// Load configuration
+	cfg, err := config.LoadFromEnvOrFile()
+	if err != nil {
+		return err
+	}
+
 	// Setup structured logging
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
+	slog.SetDefault(logging.Setup(cfg.LogFormat, cfg.LogLevel))
+	logging.SetRedactEmails(cfg.LogRedactEmails)
 
-	// Load configuration
-	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
 
 	// Create context with signal handling for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	// Initialize Pub/Sub client
-	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
+	client, err := pubsub.NewClient(ctx, cfg.ProjectID, pubsub.Options{
+		Publish: pubsub.PublishOptions{
+			DelayThreshold: cfg.PublishDelayThreshold,
+			CountThreshold: cfg.PublishCountThreshold,
+			ByteThreshold:  cfg.PublishByteThreshold,
+			NumGoroutines:  cfg.PublishNumGoroutines,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create pub/sub client: %w", err)
 	}
@@ -52,27 +67,58 @@ func run() error {
 	}()
 
 	// Ensure topics exist
-	topic, err := client.EnsureTopic(ctx, cfg.EmailTopic)
+	topics, err := client.EnsureAll(ctx, cfg.APITopics())
 	if err != nil {
-		return fmt.Errorf("failed to ensure email topic (%s): %w", cfg.EmailTopic, err)
+		return fmt.Errorf("failed to ensure topics: %w", err)
 	}
 
-	verificationTopic, err := client.EnsureTopic(ctx, cfg.VerificationTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure verification topic (%s): %w", cfg.VerificationTopic, err)
+	// Initialize services
+	publishers := make(map[string]pubsub.Publisher, len(topics))
+	topicIDs := make([]string, 0, len(topics))
+	for _, spec := range cfg.APITopics() {
+		var publisher pubsub.Publisher = pubsub.NewTopicPublisher(topics[spec.Name].Topic, cfg.PublishTimeout)
+		if cfg.PublishGzipThreshold > 0 {
+			publisher = pubsub.NewGzipPublisher(publisher, cfg.PublishGzipThreshold)
+		}
+		publishers[spec.Name] = publisher
+		topicIDs = append(topicIDs, spec.Name)
 	}
-
-	userTopic, err := client.EnsureTopic(ctx, cfg.UserTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure user topic (%s): %w", cfg.UserTopic, err)
+	emailPublisher := publishers[cfg.EmailTopic]
+	verificationPublisher := publishers[cfg.VerificationTopic]
+	userPublisher := publishers[cfg.UserTopic]
+	emailService := email.NewServiceWithVerification(emailPublisher, verificationPublisher)
+	emailService.SetDefaultSubject(cfg.DefaultSubject)
+	verifications := email.NewMemoryVerificationStore(cfg.VerificationStoreTTL)
+	emailService.SetVerificationStore(verifications)
+	auditLogger := audit.NewSlogLogger(nil)
+	emailService.SetAuditLogger(auditLogger)
+	if cfg.DelayTopic != "" {
+		emailService.SetDelayPublisher(publishers[cfg.DelayTopic], cfg.EmailTopic)
 	}
 
-	// Initialize services
-	emailService := email.NewServiceWithVerification(topic, verificationTopic)
+	// Queue email publishes that fail while Pub/Sub is briefly unavailable
+	if cfg.OutboxCapacity > 0 {
+		outboxConfig := email.DefaultOutboxConfig()
+		outboxConfig.Capacity = cfg.OutboxCapacity
+		outboxConfig.MaxAttempts = cfg.OutboxMaxAttempts
+		outboxConfig.Concurrency = cfg.OutboxConcurrency
+
+		outbox := email.NewMemoryOutbox(emailPublisher, outboxConfig)
+		outbox.SetAuditLogger(auditLogger)
+		go outbox.Run(ctx)
+		emailService.SetOutbox(outbox)
+	}
 	emailHandler := handlers.NewEmailHandler(emailService)
+	emailHandler.SetBatchConcurrency(cfg.BatchConcurrency)
+	if cfg.IdempotencyTTL > 0 {
+		emailHandler.SetIdempotencyStore(dedup.NewMemoryIdempotencyStore(cfg.IdempotencyTTL))
+	}
 
-	userService := user.NewService(userTopic)
-	userHandler := handlers.NewUserHandler(userService)
+	userService := user.NewService(userPublisher)
+	userService.SetAuditLogger(auditLogger)
+	userService.SetVerificationStore(verifications)
+	userService.SetBatchConcurrency(cfg.BatchConcurrency)
+	userHandler := handlers.NewUserHandler(userService, cfg.RequestTimeout)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
@@ -80,15 +126,49 @@ func run() error {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 	})
-	
-	mux.HandleFunc("POST /send-email", emailHandler.SendEmail)
-	mux.HandleFunc("POST /send-verification-email", handlers.SendVerificationEmail(emailService))
-	mux.HandleFunc("POST /create-user", userHandler.CreateUser)
+	mux.HandleFunc("GET /health/topics", handlers.TopicHealth(client, topicIDs))
+
+	// Mutating endpoints only accept JSON bodies
+	mux.Handle("POST /send-email", handlers.RequireJSON(http.HandlerFunc(emailHandler.SendEmail)))
+	mux.Handle("POST /validate-emails", handlers.RequireJSON(http.HandlerFunc(emailHandler.ValidateEmails)))
+	mux.Handle("POST /send-verification-email", handlers.RequireJSON(handlers.SendVerificationEmail(emailService)))
+	mux.Handle("POST /resend-verification", handlers.RequireJSON(handlers.ResendVerificationEmail(emailService)))
+	// Every user-creating route is signed when a webhook secret is configured
+	signed := func(next http.HandlerFunc) http.Handler {
+		if cfg.WebhookSecret == "" {
+			return next
+		}
+		return handlers.RequireSignature(cfg.WebhookSecret, next)
+	}
+	mux.Handle("POST /create-user", handlers.RequireJSON(signed(userHandler.CreateUser)))
+	mux.Handle("POST /create-users", handlers.RequireJSON(signed(userHandler.CreateUsers)))
+
+	// Admin endpoints are only exposed when a token is configured
+	if cfg.AdminToken != "" {
+		mux.Handle("POST /publish/{topic}", handlers.RequireBearerToken(cfg.AdminToken, handlers.RequireJSON(handlers.PublishRaw(publishers))))
+		mux.Handle("POST /dlq/replay", handlers.RequireBearerToken(cfg.AdminToken, handlers.ReplayDeadLetters(client, publishers, cfg.DeadLetterSubscriptions, cfg.DLQReplayMax)))
+
+		// The smoke test email is sent directly through Resend, so it needs the worker credentials
+		if cfg.ResendAPIKey != "" && cfg.ResendFromEmail != "" {
+			resend := email.NewResendService(email.ResendConfig{
+				APIKey:    cfg.ResendAPIKey,
+				FromEmail: cfg.ResendFromEmail,
+				FromName:  cfg.ResendFromName,
+				BaseURL:   cfg.ResendBaseURL,
+
+				DialTimeout:           cfg.ResendDialTimeout,
+				TLSHandshakeTimeout:   cfg.ResendTLSHandshakeTimeout,
+				ResponseHeaderTimeout: cfg.ResendResponseHeaderTimeout,
+				Timeout:               cfg.ResendTimeout,
+			})
+			mux.Handle("POST /send-test-email", handlers.RequireBearerToken(cfg.AdminToken, handlers.SendTestEmail(resend, cfg.TestEmailRecipient(), cfg.CompanyName)))
+		}
+	}
 
 	// Configure HTTP server with proper timeouts
 	server := &http.Server{
 		Addr:         ":" + cfg.Host,
-		Handler:      mux,
+		Handler:      handlers.RequestID(handlers.ErrorFormat(cfg.ErrorFormat, handlers.Recover(mux))),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
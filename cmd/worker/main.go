@@ -2,15 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	"go_integration/internal/alert"
+	"go_integration/internal/audit"
 	"go_integration/internal/config"
+	"go_integration/internal/dedup"
 	"go_integration/internal/email"
 	"go_integration/internal/handlers"
+	"go_integration/internal/logging"
 	"go_integration/internal/models"
 	"go_integration/internal/pubsub"
 )
@@ -23,25 +32,65 @@ func main() {
 }
 
 func run() error {
+	// Load configuration
+	cfg, err := config.LoadFromEnvOrFile()
+	if err != nil {
+		return err
+	}
+
 	// Setup structured logging
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
+	slog.SetDefault(logging.Setup(cfg.LogFormat, cfg.LogLevel))
+	logging.SetRedactEmails(cfg.LogRedactEmails)
 
-	// Load configuration
-	cfg := config.Load()
+	if err := cfg.ValidateWorker(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Initialize email sender
+	resend := email.NewResendService(email.ResendConfig{
+		APIKey:       cfg.ResendAPIKey,
+		FromEmail:    cfg.ResendFromEmail,
+		FromName:     cfg.ResendFromName,
+		TrackOpens:   cfg.TrackOpens,
+		TrackClicks:  cfg.TrackClicks,
+		DryRun:       cfg.DryRun,
+		BaseURL:      cfg.ResendBaseURL,
+		MaxEmailSize: cfg.MaxEmailSize,
 
-	// Initialize email service and handlers
-	emailService := email.NewResendService()
-	emailHandler := handlers.NewEmailQueueHandler(emailService)
+		DialTimeout:           cfg.ResendDialTimeout,
+		TLSHandshakeTimeout:   cfg.ResendTLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResendResponseHeaderTimeout,
+		Timeout:               cfg.ResendTimeout,
+	})
+	if cfg.VerifyFromDomain && !cfg.DryRun {
+		checkFromDomains(resend, cfg)
+	}
+
+	var emailService email.Sender = resend
+	if !cfg.IsProduction() && len(cfg.AllowedRecipients) > 0 {
+		emailService = email.NewAllowlistSender(emailService, cfg.AllowedRecipients, cfg.AllowlistRedirectTo)
+	}
+	suppressed := email.NewMemorySuppressionList(cfg.SuppressedRecipients...)
+	emailService = email.NewSuppressionSender(emailService, suppressed)
 
 	// Create context with signal handling for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	// Initialize Pub/Sub client
-	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
+	client, err := pubsub.NewClient(ctx, cfg.ProjectID, pubsub.Options{
+		MaxConcurrency:          cfg.MaxConcurrency,
+		SubscriptionConcurrency: cfg.SubscriptionConcurrency(),
+		NackOnParseError:        cfg.NackOnParseError,
+		MessageTimeout:          cfg.MessageTimeout,
+		ExactlyOnce:             cfg.ExactlyOnceDelivery,
+		Publish: pubsub.PublishOptions{
+			DelayThreshold: cfg.PublishDelayThreshold,
+			CountThreshold: cfg.PublishCountThreshold,
+			ByteThreshold:  cfg.PublishByteThreshold,
+			NumGoroutines:  cfg.PublishNumGoroutines,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create pub/sub client: %w", err)
 	}
@@ -52,81 +101,295 @@ func run() error {
 	}()
 
 	// Ensure topics and subscriptions exist
-	emailTopic, err := client.EnsureTopic(ctx, cfg.EmailTopic)
+	topics, err := client.EnsureAll(ctx, cfg.WorkerTopics())
 	if err != nil {
-		return fmt.Errorf("failed to ensure email topic (%s): %w", cfg.EmailTopic, err)
+		return fmt.Errorf("failed to ensure topics: %w", err)
 	}
+	emailTopic, emailSub := topics[cfg.EmailTopic].Topic, topics[cfg.EmailTopic].Subscription
+	verificationTopic, verificationSub := topics[cfg.VerificationTopic].Topic, topics[cfg.VerificationTopic].Subscription
+	userSub := topics[cfg.UserTopic].Subscription
+	welcomeTopic, welcomeSub := topics[cfg.WelcomeTopic].Topic, topics[cfg.WelcomeTopic].Subscription
 
-	emailSub, err := client.EnsureSubscription(ctx, cfg.EmailSubscription, emailTopic)
+	// Parse email templates once and share them across handlers
+	renderer, err := email.NewTemplateRenderer()
 	if err != nil {
-		return fmt.Errorf("failed to ensure email subscription (%s): %w", cfg.EmailSubscription, err)
+		return fmt.Errorf("failed to load email templates: %w", err)
 	}
 
-	verificationTopic, err := client.EnsureTopic(ctx, cfg.VerificationTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure verification topic (%s): %w", cfg.VerificationTopic, err)
-	}
-
-	verificationSub, err := client.EnsureSubscription(ctx, cfg.VerificationSubscription, verificationTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure verification subscription (%s): %w", cfg.VerificationSubscription, err)
+	// The inline logo stays off until an image file is provided
+	var inlineLogo []byte
+	if cfg.InlineLogoFile != "" {
+		if inlineLogo, err = email.LoadLogo(cfg.InlineLogoFile); err != nil {
+			return fmt.Errorf("failed to load inline logo: %w", err)
+		}
 	}
 
-	userTopic, err := client.EnsureTopic(ctx, cfg.UserTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure user topic (%s): %w", cfg.UserTopic, err)
-	}
+	// Initialize handlers (welcome emails are queued on their own topic)
+	publisher := email.NewServiceWithTopics(
+		pubsub.NewTopicPublisher(emailTopic, cfg.PublishTimeout),
+		pubsub.NewTopicPublisher(verificationTopic, cfg.PublishTimeout),
+		pubsub.NewTopicPublisher(welcomeTopic, cfg.PublishTimeout),
+	)
+	publisher.SetAuditLogger(audit.NewSlogLogger(nil))
+	emailHandler := handlers.NewEmailQueueHandler(emailService, publisher, handlers.QueueHandlerOptions{
+		SeenUsers: dedup.NewMemoryStore(cfg.UserDedupTTL),
+		Brand: email.BrandConfig{
+			CompanyName:    cfg.CompanyName,
+			LogoURL:        cfg.LogoURL,
+			InlineLogo:     inlineLogo,
+			SupportEmail:   cfg.SupportEmail,
+			CompanyAddress: cfg.CompanyAddress,
+		},
+		Renderer:         renderer,
+		FromVerification: cfg.FromVerification,
+		FromWelcome:      cfg.FromWelcome,
+		FromByLocale:     cfg.FromByLocale(),
+		MaxBodyLength:    cfg.MaxBodyLength,
+		BodyMode:         cfg.BodyHTMLMode,
+		RetryJitter:      cfg.RetryJitter,
+		Retries: handlers.RetryConfigs{
+			Email: email.RetryConfig{
+				MaxAttempts: cfg.EmailRetryAttempts,
+				Delay:       cfg.EmailRetryDelay,
+				MaxElapsed:  cfg.EmailRetryMaxElapsed,
+			},
+			Verification: email.RetryConfig{
+				MaxAttempts: cfg.VerificationRetryAttempts,
+				Delay:       cfg.VerificationRetryDelay,
+				MaxElapsed:  cfg.VerificationRetryMaxElapsed,
+			},
+			Welcome: email.RetryConfig{
+				MaxAttempts: cfg.WelcomeRetryAttempts,
+				Delay:       cfg.WelcomeRetryDelay,
+				MaxElapsed:  cfg.WelcomeRetryMaxElapsed,
+			},
+			User: email.RetryConfig{
+				MaxAttempts: cfg.UserRetryAttempts,
+				Delay:       cfg.UserRetryDelay,
+				MaxElapsed:  cfg.UserRetryMaxElapsed,
+			},
+		},
 
-	userSub, err := client.EnsureSubscription(ctx, cfg.UserSubscription, userTopic)
-	if err != nil {
-		return fmt.Errorf("failed to ensure user subscription (%s): %w", cfg.UserSubscription, err)
-	}
+		VerificationPublisher: publisher,
+		FailureNotifier:       failureNotifier(cfg),
+		FromByRecipientDomain: cfg.FromByRecipientDomain,
+	})
 
 	slog.Info("Starting message processing",
 		"email_topic", cfg.EmailTopic,
 		"email_subscription", cfg.EmailSubscription,
+		"email_priority_lanes", cfg.PriorityLanes(),
 		"verification_topic", cfg.VerificationTopic,
 		"verification_subscription", cfg.VerificationSubscription,
 		"user_topic", cfg.UserTopic,
 		"user_subscription", cfg.UserSubscription,
+		"welcome_topic", cfg.WelcomeTopic,
+		"welcome_subscription", cfg.WelcomeSubscription,
 	)
 
+	if cfg.MetricsAddr != "" {
+		go serveMetrics(ctx, cfg.MetricsAddr)
+	}
+	if cfg.WebhookAddr != "" && cfg.ResendWebhookSecret != "" {
+		go serveWebhooks(ctx, cfg.WebhookAddr, handlers.ResendWebhook(cfg.ResendWebhookSecret, suppressed))
+	}
+
+	// Receivers stop pulling on a drain signal (SIGUSR1) or shutdown, while the messages already
+	// received run under workCtx until they finish or the shutdown timeout cancels them
+	receiveCtx, stopReceiving := context.WithCancel(ctx)
+	defer stopReceiving()
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	handlerCtx := pubsub.WithWorkContext(receiveCtx, workCtx)
+
 	// Error channel for goroutine errors
-	errChan := make(chan error, 3)
+	errChan := make(chan error, 6)
+	var wg sync.WaitGroup
+	startReceiver := func(name string, receive func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := receive(); err != nil {
+				errChan <- fmt.Errorf("%s message receiver failed: %w", name, err)
+			}
+		}()
+	}
 
-	// Start receiving email messages
-	go func() {
-		if err := client.Receive(ctx, emailSub, func(ctx context.Context, payload *models.EmailPayload) error {
-			return emailHandler.HandleEmailMessage(ctx, payload)
-		}); err != nil {
-			errChan <- fmt.Errorf("email message receiver failed: %w", err)
-		}
-	}()
+	// Start receiving email messages, through the high and low priority lanes when they are configured
+	handleEmail := func(ctx context.Context, payload *models.EmailPayload) error {
+		return emailHandler.HandleEmailMessage(ctx, payload)
+	}
+	if cfg.PriorityLanes() {
+		emailSubs := topics[cfg.EmailTopic].Subscriptions
+		startReceiver("email-high", func() error {
+			return client.Receive(handlerCtx, emailSubs[cfg.EmailHighSubscription], handleEmail)
+		})
+		startReceiver("email-low", func() error {
+			return client.Receive(handlerCtx, emailSubs[cfg.EmailLowSubscription], handleEmail)
+		})
+	} else {
+		startReceiver("email", func() error {
+			return client.Receive(handlerCtx, emailSub, handleEmail)
+		})
+	}
 
 	// Start receiving verification messages
-	go func() {
-		if err := client.ReceiveVerification(ctx, verificationSub, emailHandler.HandleVerificationMessage); err != nil {
-			errChan <- fmt.Errorf("verification message receiver failed: %w", err)
-		}
-	}()
+	startReceiver("verification", func() error {
+		return client.ReceiveVerification(handlerCtx, verificationSub, emailHandler.HandleVerificationMessage)
+	})
 
 	// Start receiving user creation messages
-	go func() {
-		if err := client.ReceiveUser(ctx, userSub, func(ctx context.Context, payload *models.UserPayload) error {
+	startReceiver("user", func() error {
+		return client.ReceiveUser(handlerCtx, userSub, func(ctx context.Context, payload *models.UserPayload) error {
 			return emailHandler.HandleUserMessage(ctx, payload)
-		}); err != nil {
-			errChan <- fmt.Errorf("user message receiver failed: %w", err)
+		})
+	})
+
+	// Start receiving welcome messages
+	startReceiver("welcome", func() error {
+		return client.ReceiveWelcome(handlerCtx, welcomeSub, emailHandler.HandleWelcomeMessage)
+	})
+
+	// Start releasing scheduled emails held on the delay topic
+	if delay := topics[cfg.DelayTopic]; cfg.DelayTopic != "" {
+		delayPublisher := pubsub.NewTopicPublisher(delay.Topic, cfg.PublishTimeout)
+		targets := map[string]pubsub.Publisher{
+			cfg.EmailTopic: pubsub.NewTopicPublisher(emailTopic, cfg.PublishTimeout),
 		}
-	}()
+		// Held messages are nacked on a drain rather than waited for, as they may be held for minutes
+		startReceiver("delay", func() error {
+			return client.ReceiveDelayed(receiveCtx, delay.Subscription, delayPublisher, targets, pubsub.DelayOptions{
+				MaxHold: cfg.DelayMaxHold,
+				MaxHeld: cfg.DelayMaxHeld,
+			})
+		})
+	}
+
+	go drainOnSignal(receiveCtx, stopReceiving, &wg)
 
 	// Wait for shutdown signal or error
+	var firstErr error
 	select {
-	case err := <-errChan:
-		return err
+	case firstErr = <-errChan:
+		slog.Error("Receiver failed, stopping the remaining receivers", "error", firstErr)
 	case <-ctx.Done():
 		slog.Info("Shutdown signal received")
 	}
 
+	// Stop pulling new messages first, then give the in-flight ones the shutdown timeout to finish
+	stopReceiving()
+	if !waitTimeout(&wg, cfg.ShutdownTimeout) {
+		slog.Warn("In-flight messages did not finish in time, canceling them", "timeout", cfg.ShutdownTimeout)
+		cancelWork()
+		wg.Wait()
+	}
+
+	// Report the failures of every receiver, not just the first one
+	close(errChan)
+	if err := joinErrors(firstErr, errChan); err != nil {
+		return err
+	}
+
 	slog.Info("Worker shutdown completed")
 	return nil
 }
+
+// drainOnSignal stops the receivers on SIGUSR1 so the worker finishes its in-flight messages without
+// pulling new ones, ahead of a deploy. The worker keeps running until the shutdown signal.
+func drainOnSignal(receiveCtx context.Context, stopReceiving context.CancelFunc, wg *sync.WaitGroup) {
+	drain := make(chan os.Signal, 1)
+	signal.Notify(drain, syscall.SIGUSR1)
+	defer signal.Stop(drain)
+
+	select {
+	case <-drain:
+	case <-receiveCtx.Done():
+		return
+	}
+
+	slog.Info("Drain signal received, finishing in-flight messages")
+	stopReceiving()
+	wg.Wait()
+	slog.Info("Drain completed, waiting for shutdown signal")
+}
+
+// waitTimeout waits for wg, reporting false when it is still running after timeout
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// joinErrors joins first with every error left in errs, which must be closed
+func joinErrors(first error, errs <-chan error) error {
+	all := []error{first}
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+// failureNotifier returns the webhook notifier when ALERT_WEBHOOK_URL is set, or nil to disable alerts
+func failureNotifier(cfg *config.Config) alert.FailureNotifier {
+	if cfg.AlertWebhookURL == "" {
+		return nil
+	}
+	return alert.NewWebhookNotifier(cfg.AlertWebhookURL, nil)
+}
+
+// checkFromDomains warns when a configured sender domain is not verified in Resend, so sends would fail with a 403
+func checkFromDomains(resend *email.ResendService, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := resend.CheckFromDomains(ctx, cfg.FromAddresses()...); err != nil {
+		slog.Warn("Sender domain check failed", "error", err)
+		return
+	}
+	slog.Info("Sender domains verified in Resend")
+}
+
+// serveMetrics serves the expvar metrics at /debug/vars until ctx is done
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	serveHTTP(ctx, "metrics", addr, mux)
+}
+
+// serveWebhooks serves the Resend webhook at POST /webhooks/resend until ctx is done
+func serveWebhooks(ctx context.Context, addr string, resendWebhook http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("POST /webhooks/resend", resendWebhook)
+	serveHTTP(ctx, "webhooks", addr, handlers.RequestID(handlers.Recover(mux)))
+}
+
+// serveHTTP serves handler on addr until ctx is done, logging failures under name
+func serveHTTP(ctx context.Context, name, addr string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.Info("Serving "+name, "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP server failed", "server", name, "error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJoinErrors(t *testing.T) {
+	emailsErr := errors.New("emails receiver failed")
+	usersErr := errors.New("users receiver failed")
+	welcomeErr := errors.New("welcome receiver failed")
+
+	tests := []struct {
+		name  string
+		first error
+		rest  []error
+		want  []error
+	}{
+		{name: "single receiver", first: emailsErr, want: []error{emailsErr}},
+		{name: "every receiver", first: emailsErr, rest: []error{usersErr, welcomeErr}, want: []error{emailsErr, usersErr, welcomeErr}},
+		{name: "receivers that stopped cleanly", first: emailsErr, rest: []error{nil, usersErr}, want: []error{emailsErr, usersErr}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(chan error, len(tt.rest))
+			for _, err := range tt.rest {
+				errs <- err
+			}
+			close(errs)
+
+			got := joinErrors(tt.first, errs)
+			for _, want := range tt.want {
+				if !errors.Is(got, want) {
+					t.Errorf("joinErrors() = %v, want it to include %v", got, want)
+				}
+			}
+			if n := len(got.(interface{ Unwrap() []error }).Unwrap()); n != len(tt.want) {
+				t.Errorf("joinErrors() joined %d errors, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		work     time.Duration
+		timeout  time.Duration
+		wantDone bool
+	}{
+		{name: "work finishes in time", work: 10 * time.Millisecond, timeout: time.Second, wantDone: true},
+		{name: "work outlasts the timeout", work: 200 * time.Millisecond, timeout: 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(tt.work)
+			}()
+
+			if got := waitTimeout(&wg, tt.timeout); got != tt.wantDone {
+				t.Errorf("waitTimeout() = %v, want %v", got, tt.wantDone)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestDrainOnSignalFinishesInFlightWork(t *testing.T) {
+	// Catch SIGUSR1 here as well, so a signal sent before drainOnSignal listens does not kill the test
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGUSR1)
+	defer signal.Stop(caught)
+
+	receiveCtx, stopReceiving := context.WithCancel(context.Background())
+	defer stopReceiving()
+
+	// An in-flight message that keeps running for a while after the receivers stop
+	var finished atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-receiveCtx.Done()
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		drainOnSignal(receiveCtx, stopReceiving, &wg)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for receiveCtx.Err() == nil {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatalf("failed to send the drain signal: %v", err)
+		}
+		select {
+		case <-deadline:
+			t.Fatal("receivers were not stopped by the drain signal")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	select {
+	case <-done:
+	case <-deadline:
+		t.Fatal("drain did not complete")
+	}
+	if !finished.Load() {
+		t.Error("drain completed before the in-flight work finished")
+	}
+}
@@ -0,0 +1,88 @@
+// Package alert notifies ops when a message is dropped after every retry failed.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Failure describes a message dropped once its retries were exhausted
+type Failure struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Operation   string    `json:"operation"`
+	MessageID   string    `json:"message_id,omitempty"`
+	MaxAttempts int       `json:"max_attempts"`
+	Error       string    `json:"error"`
+}
+
+// FailureNotifier sends an alert for a dropped message
+type FailureNotifier interface {
+	NotifyFailure(ctx context.Context, failure Failure) error
+}
+
+// NopNotifier discards every failure
+type NopNotifier struct{}
+
+// NotifyFailure does nothing
+func (NopNotifier) NotifyFailure(context.Context, Failure) error {
+	return nil
+}
+
+// DefaultWebhookTimeout bounds a single webhook call so alerting cannot stall message processing
+const DefaultWebhookTimeout = 5 * time.Second
+
+// WebhookNotifier posts failures as JSON to a webhook URL. The "text" field makes the body
+// a valid Slack incoming webhook message; other receivers can read the structured fields.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url (nil client uses one with DefaultWebhookTimeout)
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return &WebhookNotifier{
+		url:    url,
+		client: client,
+	}
+}
+
+// webhookMessage is the body posted by WebhookNotifier
+type webhookMessage struct {
+	Text string `json:"text"`
+	Failure
+}
+
+// NotifyFailure posts failure to the webhook, failing on any non-2xx response
+func (n *WebhookNotifier) NotifyFailure(ctx context.Context, failure Failure) error {
+	body, err := json.Marshal(webhookMessage{
+		Text:    fmt.Sprintf("Message dropped after %d attempts (%s): %s", failure.MaxAttempts, failure.Operation, failure.Error),
+		Failure: failure,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal failure alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send failure alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	failure := Failure{
+		Timestamp:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Operation:   "send_regular_email",
+		MessageID:   "m1",
+		MaxAttempts: 3,
+		Error:       "connection reset",
+	}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookNotifier(server.URL, nil).NotifyFailure(context.Background(), failure)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NotifyFailure error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// The Slack text and the structured fields are both sent
+			if text, _ := got["text"].(string); !strings.Contains(text, "3 attempts") || !strings.Contains(text, "connection reset") {
+				t.Errorf("text = %q, want the attempts and the error", text)
+			}
+			if got["operation"] != "send_regular_email" || got["message_id"] != "m1" {
+				t.Errorf("body = %v, want the failure fields", got)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Close()
+
+	if err := NewWebhookNotifier(server.URL, nil).NotifyFailure(context.Background(), Failure{}); err == nil {
+		t.Error("NotifyFailure succeeded against a closed server")
+	}
+}
@@ -0,0 +1,71 @@
+// Package audit records who triggered which email or user event.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Message types recorded in the audit trail
+const (
+	TypeEmail        = "email"
+	TypeVerification = "verification"
+	TypeWelcome      = "welcome"
+	TypeUser         = "user"
+)
+
+// Entry describes a successfully published message
+type Entry struct {
+	Timestamp     time.Time
+	Type          string
+	RecipientHash string // SHA-256 of the normalized recipient, see HashRecipient
+	MessageID     string
+}
+
+// Logger records audit entries
+type Logger interface {
+	Record(ctx context.Context, entry Entry)
+}
+
+// NewEntry builds an entry for a message published now, hashing the recipient
+func NewEntry(messageType, recipient, messageID string) Entry {
+	return Entry{
+		Timestamp:     time.Now().UTC(),
+		Type:          messageType,
+		RecipientHash: HashRecipient(recipient),
+		MessageID:     messageID,
+	}
+}
+
+// HashRecipient returns the hex SHA-256 of the lowercased, trimmed address so entries can be correlated without storing it
+func HashRecipient(recipient string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(recipient))))
+	return hex.EncodeToString(sum[:])
+}
+
+// SlogLogger writes audit entries to a slog logger
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates an audit Logger writing to logger (nil uses the default logger)
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger.With("audit", true)}
+}
+
+// Record logs entry at info level
+func (l *SlogLogger) Record(ctx context.Context, entry Entry) {
+	l.logger.InfoContext(ctx, "Message published",
+		"timestamp", entry.Timestamp.Format(time.RFC3339Nano),
+		"message_type", entry.Type,
+		"recipient_hash", entry.RecipientHash,
+		"published_message_id", entry.MessageID,
+	)
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHashRecipient(t *testing.T) {
+	want := HashRecipient("ana@example.com")
+
+	tests := []struct {
+		name      string
+		recipient string
+		same      bool
+	}{
+		{name: "same address", recipient: "ana@example.com", same: true},
+		{name: "case and spaces are ignored", recipient: "  Ana@Example.COM ", same: true},
+		{name: "other address", recipient: "eva@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashRecipient(tt.recipient)
+			if (got == want) != tt.same {
+				t.Errorf("HashRecipient(%q) = %s, same as ana@example.com = %v, want %v", tt.recipient, got, got == want, tt.same)
+			}
+			if len(got) != 64 {
+				t.Errorf("hash length = %d, want a hex SHA-256", len(got))
+			}
+		})
+	}
+}
+
+func TestSlogLoggerRecord(t *testing.T) {
+	var logs bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	logger.Record(context.Background(), NewEntry(TypeEmail, "ana@example.com", "msg-1"))
+
+	out := logs.String()
+	if strings.Contains(out, "ana@example.com") {
+		t.Errorf("audit log contains the recipient in plain text: %s", out)
+	}
+	for _, want := range []string{"audit=true", "message_type=email", "published_message_id=msg-1", "recipient_hash=" + HashRecipient("ana@example.com")} {
+		if !strings.Contains(out, want) {
+			t.Errorf("audit log is missing %s: %s", want, out)
+		}
+	}
+}
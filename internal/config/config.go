@@ -1,29 +1,241 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"go_integration/internal/models"
+	"go_integration/internal/pubsub"
 )
 
 // Config holds application configuration
 type Config struct {
 	// General application config
-	ProjectID string
-	Host      string
+	ProjectID   string `yaml:"project_id" json:"project_id"`
+	Host        string `yaml:"host" json:"host"`
+	Environment string `yaml:"environment" json:"environment"`
 
 	// Email processing topic and subscription
-	EmailTopic        string
-	EmailSubscription string
+	EmailTopic        string `yaml:"email_topic" json:"email_topic"`
+	EmailSubscription string `yaml:"email_subscription" json:"email_subscription"`
+
+	// Optional priority lanes on the email topic, replacing EmailSubscription when both are set.
+	// Each lane filters on the priority attribute, and the high one gets more concurrency
+	EmailHighSubscription string `yaml:"email_high_subscription" json:"email_high_subscription"`
+	EmailLowSubscription  string `yaml:"email_low_subscription" json:"email_low_subscription"`
+	EmailHighConcurrency  int    `yaml:"email_high_concurrency" json:"email_high_concurrency"`
+	EmailLowConcurrency   int    `yaml:"email_low_concurrency" json:"email_low_concurrency"`
 
 	// Email verification topic and subscription
-	VerificationTopic        string
-	VerificationSubscription string
+	VerificationTopic        string `yaml:"verification_topic" json:"verification_topic"`
+	VerificationSubscription string `yaml:"verification_subscription" json:"verification_subscription"`
 
 	// User creation topic and subscription
-	UserTopic        string
-	UserSubscription string
+	UserTopic        string `yaml:"user_topic" json:"user_topic"`
+	UserSubscription string `yaml:"user_subscription" json:"user_subscription"`
+
+	// Welcome email topic and subscription
+	WelcomeTopic        string `yaml:"welcome_topic" json:"welcome_topic"`
+	WelcomeSubscription string `yaml:"welcome_subscription" json:"welcome_subscription"`
+
+	// Optional delay topic and subscription holding scheduled emails until their send time (empty disables it)
+	DelayTopic        string        `yaml:"delay_topic" json:"delay_topic"`
+	DelaySubscription string        `yaml:"delay_subscription" json:"delay_subscription"`
+	DelayMaxHold      time.Duration `yaml:"delay_max_hold" json:"delay_max_hold"` // How long the worker holds a message before republishing it to the delay topic
+	DelayMaxHeld      int           `yaml:"delay_max_held" json:"delay_max_held"` // How many scheduled messages the worker holds at once
+
+	// ExtraTopics are ensured alongside the built-in topics, e.g. for new event types
+	ExtraTopics []pubsub.TopicSpec `yaml:"extra_topics" json:"extra_topics"`
+
+	// Redelivery backoff applied by Pub/Sub to the worker subscriptions; extra topics without a retry_policy
+	// use it too (zero keeps the Pub/Sub defaults of 10s and 600s)
+	MinBackoff time.Duration `yaml:"min_backoff" json:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" json:"max_backoff"`
+
+	// AdminToken enables the admin endpoints, authenticated with "Authorization: Bearer <token>" (empty disables them)
+	AdminToken string `yaml:"admin_token" json:"admin_token"`
+
+	// Log output format ("json" or "text") and minimum level ("debug", "info", "warn", "error")
+	LogFormat string `yaml:"log_format" json:"log_format"`
+	LogLevel  string `yaml:"log_level" json:"log_level"`
+
+	// LogRedactEmails masks the local part of recipient addresses in logs ("j***@example.com")
+	LogRedactEmails bool `yaml:"log_redact_emails" json:"log_redact_emails"`
+
+	// ErrorFormat is "problem" for RFC 7807 application/problem+json API errors, or "plain" for plain text
+	ErrorFormat string `yaml:"error_format" json:"error_format"`
+
+	// DLQReplayMax caps the messages replayed by a single POST /dlq/replay call
+	DLQReplayMax int `yaml:"dlq_replay_max" json:"dlq_replay_max"`
+
+	// DeadLetterSubscriptions are the only subscriptions POST /dlq/replay may pull from (empty rejects every replay)
+	DeadLetterSubscriptions []string `yaml:"dead_letter_subscriptions" json:"dead_letter_subscriptions"`
+
+	// WebhookSecret signs server-to-server calls to /create-user and /create-users through the X-Signature header (empty disables verification)
+	WebhookSecret string `yaml:"webhook_secret" json:"webhook_secret"`
+
+	// Resend email delivery (required by the worker)
+	ResendAPIKey    string `yaml:"resend_api_key" json:"resend_api_key"`
+	ResendFromEmail string `yaml:"resend_from_email" json:"resend_from_email"`
+	ResendFromName  string `yaml:"resend_from_name" json:"resend_from_name"`
+	ResendBaseURL   string `yaml:"resend_base_url" json:"resend_base_url"` // Optional: empty uses the public Resend API
+
+	// VerifyFromDomain checks at worker startup that the sender domains are verified in Resend, logging a warning otherwise
+	VerifyFromDomain bool `yaml:"verify_from_domain" json:"verify_from_domain"`
+
+	// TestRecipient receives the smoke test email sent by POST /send-test-email (empty uses ResendFromEmail)
+	TestRecipient string `yaml:"test_recipient" json:"test_recipient"`
+
+	// Resend HTTP timeouts: connect, TLS handshake and response headers (0 uses the defaults), and the whole request (0 disables it)
+	ResendDialTimeout           time.Duration `yaml:"resend_dial_timeout" json:"resend_dial_timeout"`
+	ResendTLSHandshakeTimeout   time.Duration `yaml:"resend_tls_handshake_timeout" json:"resend_tls_handshake_timeout"`
+	ResendResponseHeaderTimeout time.Duration `yaml:"resend_response_header_timeout" json:"resend_response_header_timeout"`
+	ResendTimeout               time.Duration `yaml:"resend_timeout" json:"resend_timeout"`
+
+	// Optional per-type sender addresses, falling back to ResendFromEmail
+	FromVerification string `yaml:"from_verification" json:"from_verification"`
+	FromWelcome      string `yaml:"from_welcome" json:"from_welcome"`
+
+	// Optional per-locale sender addresses for localized emails, taking precedence over the per-type ones
+	FromEN string `yaml:"from_en" json:"from_en"`
+	FromPT string `yaml:"from_pt" json:"from_pt"`
+
+	// FromByRecipientDomain maps a recipient domain to the sender address used for it, e.g. routing
+	// gmail.com through a warmed sending domain; it takes precedence over every other sender
+	FromByRecipientDomain map[string]string `yaml:"from_by_recipient_domain" json:"from_by_recipient_domain"`
+
+	// Default Resend open/click tracking, overridable per payload
+	TrackOpens  bool `yaml:"track_opens" json:"track_opens"`
+	TrackClicks bool `yaml:"track_clicks" json:"track_clicks"`
+
+	// RequestTimeout bounds how long the API spends on a create-user request before answering 504
+	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout"`
+
+	// PublishTimeout bounds how long the API waits for Pub/Sub to acknowledge a publish
+	PublishTimeout time.Duration `yaml:"publish_timeout" json:"publish_timeout"`
+
+	// Outbox for email publishes that fail in the API (capacity 0 disables it); OutboxConcurrency entries are republished at once
+	OutboxCapacity    int `yaml:"outbox_capacity" json:"outbox_capacity"`
+	OutboxMaxAttempts int `yaml:"outbox_max_attempts" json:"outbox_max_attempts"`
+	OutboxConcurrency int `yaml:"outbox_concurrency" json:"outbox_concurrency"`
+
+	// BatchConcurrency caps the publishes in flight at once for a /send-email array or a /create-users request
+	BatchConcurrency int `yaml:"batch_concurrency" json:"batch_concurrency"`
+
+	// MaxConcurrency caps concurrent message processing per subscription (0 keeps the Pub/Sub default)
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
+
+	// RetryJitter randomly shifts the worker retry delays by up to ±RetryJitter (0 disables it)
+	RetryJitter time.Duration `yaml:"retry_jitter" json:"retry_jitter"`
+
+	// Per-type worker retries: attempts per message and the delay between them.
+	// Verification codes expire quickly, so they default to fewer and faster attempts
+	EmailRetryAttempts        int           `yaml:"email_retry_attempts" json:"email_retry_attempts"`
+	EmailRetryDelay           time.Duration `yaml:"email_retry_delay" json:"email_retry_delay"`
+	VerificationRetryAttempts int           `yaml:"verification_retry_attempts" json:"verification_retry_attempts"`
+	VerificationRetryDelay    time.Duration `yaml:"verification_retry_delay" json:"verification_retry_delay"`
+	WelcomeRetryAttempts      int           `yaml:"welcome_retry_attempts" json:"welcome_retry_attempts"`
+	WelcomeRetryDelay         time.Duration `yaml:"welcome_retry_delay" json:"welcome_retry_delay"`
+	UserRetryAttempts         int           `yaml:"user_retry_attempts" json:"user_retry_attempts"`
+	UserRetryDelay            time.Duration `yaml:"user_retry_delay" json:"user_retry_delay"`
+
+	// Per-type retry budget: total time a message may spend on attempts and delays before it is given up (0 disables it)
+	EmailRetryMaxElapsed        time.Duration `yaml:"email_retry_max_elapsed" json:"email_retry_max_elapsed"`
+	VerificationRetryMaxElapsed time.Duration `yaml:"verification_retry_max_elapsed" json:"verification_retry_max_elapsed"`
+	WelcomeRetryMaxElapsed      time.Duration `yaml:"welcome_retry_max_elapsed" json:"welcome_retry_max_elapsed"`
+	UserRetryMaxElapsed         time.Duration `yaml:"user_retry_max_elapsed" json:"user_retry_max_elapsed"`
+
+	// AlertWebhookURL receives a JSON (Slack compatible) alert when a message is dropped after its retries (empty disables it)
+	AlertWebhookURL string `yaml:"alert_webhook_url" json:"alert_webhook_url"`
+
+	// ShutdownTimeout is how long the worker waits for in-flight messages on shutdown before canceling them
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+
+	// MetricsAddr serves the worker metrics (expvar, e.g. queue_latency) at /debug/vars on this address (empty disables it)
+	MetricsAddr string `yaml:"metrics_addr" json:"metrics_addr"`
+
+	// Resend webhooks served by the worker at POST /webhooks/resend on WebhookAddr, adding hard bounces and
+	// complaints to the suppression list. Both must be set to enable it; the secret is the "whsec_..." signing secret
+	WebhookAddr         string `yaml:"webhook_addr" json:"webhook_addr"`
+	ResendWebhookSecret string `yaml:"resend_webhook_secret" json:"resend_webhook_secret"`
+
+	// MessageTimeout bounds how long the worker spends on a single message, retries included (0 disables it)
+	MessageTimeout time.Duration `yaml:"message_timeout" json:"message_timeout"`
+
+	// ExactlyOnceDelivery enables Pub/Sub exactly-once delivery on the worker subscriptions
+	ExactlyOnceDelivery bool `yaml:"exactly_once_delivery" json:"exactly_once_delivery"`
+
+	// NackOnParseError redelivers undecodable messages instead of dropping them
+	NackOnParseError bool `yaml:"nack_on_parse_error" json:"nack_on_parse_error"`
+
+	// PublishGzipThreshold gzip-compresses published payloads of at least this many bytes (0 disables compression)
+	PublishGzipThreshold int `yaml:"publish_gzip_threshold" json:"publish_gzip_threshold"`
+
+	// Pub/Sub publish batching overrides (zero keeps the client library default)
+	PublishDelayThreshold time.Duration `yaml:"publish_delay_threshold" json:"publish_delay_threshold"`
+	PublishCountThreshold int           `yaml:"publish_count_threshold" json:"publish_count_threshold"`
+	PublishByteThreshold  int           `yaml:"publish_byte_threshold" json:"publish_byte_threshold"`
+	PublishNumGoroutines  int           `yaml:"publish_num_goroutines" json:"publish_num_goroutines"`
+
+	// UserDedupTTL is how long processed user IDs are remembered to skip replayed messages
+	UserDedupTTL time.Duration `yaml:"user_dedup_ttl" json:"user_dedup_ttl"`
+
+	// IdempotencyTTL is how long an Idempotency-Key sent to POST /send-email replays its first result (0 disables the header)
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" json:"idempotency_ttl"`
+
+	// VerificationStoreTTL is how long POST /resend-verification can find an issued verification email
+	VerificationStoreTTL time.Duration `yaml:"verification_store_ttl" json:"verification_store_ttl"`
+
+	// Branding rendered in email templates
+	CompanyName    string `yaml:"company_name" json:"company_name"`
+	LogoURL        string `yaml:"logo_url" json:"logo_url"`
+	InlineLogoFile string `yaml:"inline_logo_file" json:"inline_logo_file"` // Optional: image embedded as a CID attachment so clients that block remote images still show the logo
+	SupportEmail   string `yaml:"support_email" json:"support_email"`
+	CompanyAddress string `yaml:"company_address" json:"company_address"` // Optional: physical mailing address shown in the footer
+
+	// MaxEmailSize rejects emails whose content and attachments exceed this many bytes (0 uses the Resend limit)
+	MaxEmailSize int `yaml:"max_email_size" json:"max_email_size"`
+
+	// DefaultSubject is applied to emails sent without a subject instead of rejecting them (empty keeps strict validation)
+	DefaultSubject string `yaml:"default_subject" json:"default_subject"`
+
+	// BodyHTMLMode is "rich" to keep allowlisted HTML tags (bold, links, lists) in email bodies, or "plain" to escape them all
+	BodyHTMLMode string `yaml:"body_html_mode" json:"body_html_mode"`
+
+	// MaxBodyLength truncates email bodies longer than this many characters (0 disables the limit)
+	MaxBodyLength int `yaml:"max_body_length" json:"max_body_length"`
+
+	// DryRun makes the worker log emails instead of sending them
+	DryRun bool `yaml:"dry_run" json:"dry_run"`
+
+	// Sandbox allowlist applied outside production; non-listed recipients are
+	// redirected to AllowlistRedirectTo, or dropped when it is empty
+	AllowedRecipients   []string `yaml:"allowed_recipients" json:"allowed_recipients"`
+	AllowlistRedirectTo string   `yaml:"allowlist_redirect_to" json:"allowlist_redirect_to"`
+
+	// SuppressedRecipients never receive emails, e.g. addresses that hard bounced
+	SuppressedRecipients []string `yaml:"suppressed_recipients" json:"suppressed_recipients"`
+}
+
+// LoadFromEnvOrFile loads configuration from the file named by CONFIG_FILE when it is set, otherwise
+// from environment variables and .env file
+func LoadFromEnvOrFile() (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		cfg, err := LoadFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+		return cfg, nil
+	}
+	return Load(), nil
 }
 
 // Load loads configuration from environment variables and .env file
@@ -33,16 +245,351 @@ func Load() *Config {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	cfg := defaultConfig()
+	applyEnv(cfg)
+	return cfg
+}
+
+// LoadFromFile loads configuration from a YAML or JSON file, with environment variables overriding file values
+func LoadFromFile(path string) (*Config, error) {
+	// Environment variables (including .env) still take precedence over the file
+	_ = godotenv.Load()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := defaultConfig()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		// JSON is valid YAML, so both decode durations written as strings such as "10s"
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	applyEnv(cfg)
+	return cfg, nil
+}
+
+// defaultConfig returns the configuration used when nothing else is set
+func defaultConfig() *Config {
 	return &Config{
-		ProjectID:                getEnv("PUBSUB_PROJECT_ID", "northfi-integration"),
-		Host:                     getEnv("HOST", "8080"),
-		EmailTopic:               getEnv("EMAIL_TOPIC", "northfi.email.processing.v1"),
-		EmailSubscription:        getEnv("EMAIL_SUBSCRIPTION", "northfi.email.processing.worker.v1"),
-		VerificationTopic:        getEnv("VERIFICATION_TOPIC", "northfi.email.verification.v1"),
-		VerificationSubscription: getEnv("VERIFICATION_SUBSCRIPTION", "northfi.email.verification.worker.v1"),
-		UserTopic:                getEnv("USER_TOPIC", "northfi.user.creation.v1"),
-		UserSubscription:         getEnv("USER_SUBSCRIPTION", "northfi.user.creation.worker.v1"),
+		ProjectID:                 "northfi-integration",
+		Host:                      "8080",
+		Environment:               "development",
+		LogFormat:                 "json",
+		ErrorFormat:               "plain",
+		LogLevel:                  "info",
+		EmailTopic:                "northfi.email.processing.v1",
+		EmailSubscription:         "northfi.email.processing.worker.v1",
+		EmailHighConcurrency:      20,
+		EmailLowConcurrency:       5,
+		VerificationTopic:         "northfi.email.verification.v1",
+		VerificationSubscription:  "northfi.email.verification.worker.v1",
+		UserTopic:                 "northfi.user.creation.v1",
+		UserSubscription:          "northfi.user.creation.worker.v1",
+		WelcomeTopic:              "northfi.email.welcome.v1",
+		WelcomeSubscription:       "northfi.email.welcome.worker.v1",
+		UserDedupTTL:              24 * time.Hour,
+		IdempotencyTTL:            10 * time.Minute,
+		VerificationStoreTTL:      24 * time.Hour,
+		PublishTimeout:            10 * time.Second,
+		RequestTimeout:            15 * time.Second,
+		DLQReplayMax:              100,
+		OutboxCapacity:            100,
+		OutboxMaxAttempts:         5,
+		OutboxConcurrency:         4,
+		BatchConcurrency:          32,
+		CompanyName:               "NorthFi",
+		MaxBodyLength:             100000,
+		BodyHTMLMode:              "plain",
+		MessageTimeout:            2 * time.Minute,
+		ShutdownTimeout:           30 * time.Second,
+		EmailRetryAttempts:        3,
+		EmailRetryDelay:           2 * time.Second,
+		VerificationRetryAttempts: 2,
+		VerificationRetryDelay:    500 * time.Millisecond,
+		WelcomeRetryAttempts:      3,
+		WelcomeRetryDelay:         2 * time.Second,
+		UserRetryAttempts:         3,
+		UserRetryDelay:            time.Second,
+		LogoURL:                   "https://northfi.com.br/img/logoNorthPreto.png",
+	}
+}
+
+// applyEnv overrides configuration values with the environment variables that are set
+func applyEnv(cfg *Config) {
+	cfg.ProjectID = getEnv("PUBSUB_PROJECT_ID", cfg.ProjectID)
+	cfg.Host = getEnv("HOST", cfg.Host)
+	cfg.Environment = getEnv("GO_ENV", cfg.Environment)
+	cfg.EmailTopic = getEnv("EMAIL_TOPIC", cfg.EmailTopic)
+	cfg.EmailSubscription = getEnv("EMAIL_SUBSCRIPTION", cfg.EmailSubscription)
+	cfg.EmailHighSubscription = getEnv("EMAIL_HIGH_SUBSCRIPTION", cfg.EmailHighSubscription)
+	cfg.EmailLowSubscription = getEnv("EMAIL_LOW_SUBSCRIPTION", cfg.EmailLowSubscription)
+	cfg.EmailHighConcurrency = getEnvInt("EMAIL_HIGH_CONCURRENCY", cfg.EmailHighConcurrency)
+	cfg.EmailLowConcurrency = getEnvInt("EMAIL_LOW_CONCURRENCY", cfg.EmailLowConcurrency)
+	cfg.VerificationTopic = getEnv("VERIFICATION_TOPIC", cfg.VerificationTopic)
+	cfg.VerificationSubscription = getEnv("VERIFICATION_SUBSCRIPTION", cfg.VerificationSubscription)
+	cfg.UserTopic = getEnv("USER_TOPIC", cfg.UserTopic)
+	cfg.UserSubscription = getEnv("USER_SUBSCRIPTION", cfg.UserSubscription)
+	cfg.WelcomeTopic = getEnv("WELCOME_TOPIC", cfg.WelcomeTopic)
+	cfg.WelcomeSubscription = getEnv("WELCOME_SUBSCRIPTION", cfg.WelcomeSubscription)
+	cfg.LogFormat = getEnv("LOG_FORMAT", cfg.LogFormat)
+	cfg.LogRedactEmails = getEnvBool("LOG_REDACT_EMAILS", cfg.LogRedactEmails)
+	cfg.ErrorFormat = getEnv("ERROR_FORMAT", cfg.ErrorFormat)
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+	cfg.AdminToken = getEnv("ADMIN_TOKEN", cfg.AdminToken)
+	cfg.DLQReplayMax = getEnvInt("DLQ_REPLAY_MAX", cfg.DLQReplayMax)
+	cfg.DeadLetterSubscriptions = getEnvList("DEAD_LETTER_SUBSCRIPTIONS", cfg.DeadLetterSubscriptions)
+	cfg.WebhookSecret = getEnv("WEBHOOK_SECRET", cfg.WebhookSecret)
+	cfg.DelayTopic = getEnv("DELAY_TOPIC", cfg.DelayTopic)
+	cfg.DelaySubscription = getEnv("DELAY_SUBSCRIPTION", cfg.DelaySubscription)
+	cfg.DelayMaxHold = getEnvDuration("DELAY_MAX_HOLD", cfg.DelayMaxHold)
+	cfg.DelayMaxHeld = getEnvInt("DELAY_MAX_HELD", cfg.DelayMaxHeld)
+	cfg.ExtraTopics = getEnvTopics("EXTRA_TOPICS", cfg.ExtraTopics)
+	cfg.ResendAPIKey = getEnv("RESEND_API_KEY", cfg.ResendAPIKey)
+	cfg.ResendFromEmail = getEnv("RESEND_FROM_EMAIL", cfg.ResendFromEmail)
+	cfg.ResendFromName = getEnv("RESEND_FROM_NAME", cfg.ResendFromName)
+	cfg.ResendBaseURL = getEnv("RESEND_BASE_URL", cfg.ResendBaseURL)
+	cfg.VerifyFromDomain = getEnvBool("VERIFY_FROM_DOMAIN", cfg.VerifyFromDomain)
+	cfg.ResendDialTimeout = getEnvDuration("RESEND_DIAL_TIMEOUT", cfg.ResendDialTimeout)
+	cfg.ResendTLSHandshakeTimeout = getEnvDuration("RESEND_TLS_HANDSHAKE_TIMEOUT", cfg.ResendTLSHandshakeTimeout)
+	cfg.ResendResponseHeaderTimeout = getEnvDuration("RESEND_RESPONSE_HEADER_TIMEOUT", cfg.ResendResponseHeaderTimeout)
+	cfg.ResendTimeout = getEnvDuration("RESEND_TIMEOUT", cfg.ResendTimeout)
+	cfg.TestRecipient = getEnv("TEST_RECIPIENT", cfg.TestRecipient)
+	cfg.FromVerification = getEnv("FROM_VERIFICATION", cfg.FromVerification)
+	cfg.FromWelcome = getEnv("FROM_WELCOME", cfg.FromWelcome)
+	cfg.FromEN = getEnv("FROM_EN", cfg.FromEN)
+	cfg.FromPT = getEnv("FROM_PT", cfg.FromPT)
+	cfg.FromByRecipientDomain = getEnvMap("FROM_BY_RECIPIENT_DOMAIN", cfg.FromByRecipientDomain)
+	cfg.TrackOpens = getEnvBool("RESEND_TRACK_OPENS", cfg.TrackOpens)
+	cfg.TrackClicks = getEnvBool("RESEND_TRACK_CLICKS", cfg.TrackClicks)
+	cfg.PublishTimeout = getEnvDuration("PUBLISH_TIMEOUT", cfg.PublishTimeout)
+	cfg.RequestTimeout = getEnvDuration("REQUEST_TIMEOUT", cfg.RequestTimeout)
+	cfg.OutboxCapacity = getEnvInt("OUTBOX_CAPACITY", cfg.OutboxCapacity)
+	cfg.OutboxMaxAttempts = getEnvInt("OUTBOX_MAX_ATTEMPTS", cfg.OutboxMaxAttempts)
+	cfg.OutboxConcurrency = getEnvInt("OUTBOX_CONCURRENCY", cfg.OutboxConcurrency)
+	cfg.BatchConcurrency = getEnvInt("BATCH_CONCURRENCY", cfg.BatchConcurrency)
+	cfg.MaxConcurrency = getEnvInt("MAX_CONCURRENCY", cfg.MaxConcurrency)
+	cfg.RetryJitter = getEnvDuration("RETRY_JITTER", cfg.RetryJitter)
+	cfg.AlertWebhookURL = getEnv("ALERT_WEBHOOK_URL", cfg.AlertWebhookURL)
+	cfg.MinBackoff = getEnvDuration("SUBSCRIPTION_MIN_BACKOFF", cfg.MinBackoff)
+	cfg.MaxBackoff = getEnvDuration("SUBSCRIPTION_MAX_BACKOFF", cfg.MaxBackoff)
+	cfg.ShutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+	cfg.MetricsAddr = getEnv("METRICS_ADDR", cfg.MetricsAddr)
+	cfg.WebhookAddr = getEnv("WEBHOOK_ADDR", cfg.WebhookAddr)
+	cfg.ResendWebhookSecret = getEnv("RESEND_WEBHOOK_SECRET", cfg.ResendWebhookSecret)
+	cfg.MessageTimeout = getEnvDuration("MESSAGE_TIMEOUT", cfg.MessageTimeout)
+	cfg.EmailRetryAttempts = getEnvInt("EMAIL_RETRY_ATTEMPTS", cfg.EmailRetryAttempts)
+	cfg.EmailRetryDelay = getEnvDuration("EMAIL_RETRY_DELAY", cfg.EmailRetryDelay)
+	cfg.VerificationRetryAttempts = getEnvInt("VERIFICATION_RETRY_ATTEMPTS", cfg.VerificationRetryAttempts)
+	cfg.VerificationRetryDelay = getEnvDuration("VERIFICATION_RETRY_DELAY", cfg.VerificationRetryDelay)
+	cfg.WelcomeRetryAttempts = getEnvInt("WELCOME_RETRY_ATTEMPTS", cfg.WelcomeRetryAttempts)
+	cfg.WelcomeRetryDelay = getEnvDuration("WELCOME_RETRY_DELAY", cfg.WelcomeRetryDelay)
+	cfg.UserRetryAttempts = getEnvInt("USER_RETRY_ATTEMPTS", cfg.UserRetryAttempts)
+	cfg.UserRetryDelay = getEnvDuration("USER_RETRY_DELAY", cfg.UserRetryDelay)
+	cfg.EmailRetryMaxElapsed = getEnvDuration("EMAIL_RETRY_MAX_ELAPSED", cfg.EmailRetryMaxElapsed)
+	cfg.VerificationRetryMaxElapsed = getEnvDuration("VERIFICATION_RETRY_MAX_ELAPSED", cfg.VerificationRetryMaxElapsed)
+	cfg.WelcomeRetryMaxElapsed = getEnvDuration("WELCOME_RETRY_MAX_ELAPSED", cfg.WelcomeRetryMaxElapsed)
+	cfg.UserRetryMaxElapsed = getEnvDuration("USER_RETRY_MAX_ELAPSED", cfg.UserRetryMaxElapsed)
+	cfg.ExactlyOnceDelivery = getEnvBool("EXACTLY_ONCE_DELIVERY", cfg.ExactlyOnceDelivery)
+	cfg.NackOnParseError = getEnvBool("NACK_ON_PARSE_ERROR", cfg.NackOnParseError)
+	cfg.PublishGzipThreshold = getEnvInt("PUBLISH_GZIP_THRESHOLD", cfg.PublishGzipThreshold)
+	cfg.PublishDelayThreshold = getEnvDuration("PUBLISH_DELAY_THRESHOLD", cfg.PublishDelayThreshold)
+	cfg.PublishCountThreshold = getEnvInt("PUBLISH_COUNT_THRESHOLD", cfg.PublishCountThreshold)
+	cfg.PublishByteThreshold = getEnvInt("PUBLISH_BYTE_THRESHOLD", cfg.PublishByteThreshold)
+	cfg.PublishNumGoroutines = getEnvInt("PUBLISH_NUM_GOROUTINES", cfg.PublishNumGoroutines)
+	cfg.UserDedupTTL = getEnvDuration("USER_DEDUP_TTL", cfg.UserDedupTTL)
+	cfg.IdempotencyTTL = getEnvDuration("IDEMPOTENCY_TTL", cfg.IdempotencyTTL)
+	cfg.VerificationStoreTTL = getEnvDuration("VERIFICATION_STORE_TTL", cfg.VerificationStoreTTL)
+	cfg.CompanyName = getEnv("COMPANY_NAME", cfg.CompanyName)
+	cfg.LogoURL = getEnv("LOGO_URL", cfg.LogoURL)
+	cfg.InlineLogoFile = getEnv("INLINE_LOGO_FILE", cfg.InlineLogoFile)
+	cfg.SupportEmail = getEnv("SUPPORT_EMAIL", cfg.SupportEmail)
+	cfg.CompanyAddress = getEnv("COMPANY_ADDRESS", cfg.CompanyAddress)
+	cfg.DefaultSubject = getEnv("DEFAULT_SUBJECT", cfg.DefaultSubject)
+	cfg.MaxEmailSize = getEnvInt("MAX_EMAIL_SIZE", cfg.MaxEmailSize)
+	cfg.BodyHTMLMode = getEnv("BODY_HTML_MODE", cfg.BodyHTMLMode)
+	cfg.MaxBodyLength = getEnvInt("MAX_BODY_LENGTH", cfg.MaxBodyLength)
+	cfg.DryRun = getEnvBool("DRY_RUN", cfg.DryRun)
+	cfg.AllowedRecipients = getEnvList("ALLOWED_RECIPIENTS", cfg.AllowedRecipients)
+	cfg.AllowlistRedirectTo = getEnv("ALLOWLIST_REDIRECT_TO", cfg.AllowlistRedirectTo)
+	cfg.SuppressedRecipients = getEnvList("SUPPRESSED_RECIPIENTS", cfg.SuppressedRecipients)
+}
+
+// IsProduction reports whether the application runs in the production environment
+func (c *Config) IsProduction() bool {
+	return strings.EqualFold(c.Environment, "production")
+}
+
+// TestEmailRecipient returns the address of the smoke test email, falling back to the sender address
+func (c *Config) TestEmailRecipient() string {
+	if c.TestRecipient != "" {
+		return c.TestRecipient
 	}
+	return c.ResendFromEmail
+}
+
+// FromAddresses returns every optional sender address, for the sender domain check
+func (c *Config) FromAddresses() []string {
+	addresses := []string{c.FromVerification, c.FromWelcome, c.FromEN, c.FromPT}
+	for _, address := range c.FromByRecipientDomain {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// FromByLocale returns the configured sender addresses keyed by language ("en", "pt")
+func (c *Config) FromByLocale() map[string]string {
+	from := make(map[string]string)
+	if c.FromEN != "" {
+		from["en"] = c.FromEN
+	}
+	if c.FromPT != "" {
+		from["pt"] = c.FromPT
+	}
+	return from
+}
+
+// APITopics returns the topics the API publishes to, without subscriptions
+func (c *Config) APITopics() []pubsub.TopicSpec {
+	specs := []pubsub.TopicSpec{
+		{Name: c.EmailTopic},
+		{Name: c.VerificationTopic},
+		{Name: c.UserTopic},
+	}
+	if c.DelayTopic != "" {
+		specs = append(specs, pubsub.TopicSpec{Name: c.DelayTopic})
+	}
+	for _, extra := range c.ExtraTopics {
+		specs = append(specs, pubsub.TopicSpec{Name: extra.Name})
+	}
+	return specs
+}
+
+// WorkerTopics returns the topics and subscriptions the worker consumes
+func (c *Config) WorkerTopics() []pubsub.TopicSpec {
+	policy := c.RetryPolicy()
+	var specs []pubsub.TopicSpec
+	if c.PriorityLanes() {
+		specs = append(specs,
+			pubsub.TopicSpec{Name: c.EmailTopic, Subscription: c.EmailHighSubscription, RetryPolicy: policy, Filter: pubsub.PriorityFilter(models.PriorityHigh)},
+			pubsub.TopicSpec{Name: c.EmailTopic, Subscription: c.EmailLowSubscription, RetryPolicy: policy, Filter: pubsub.PriorityFilter(models.PriorityLow)},
+		)
+	} else {
+		specs = append(specs, pubsub.TopicSpec{Name: c.EmailTopic, Subscription: c.EmailSubscription, RetryPolicy: policy})
+	}
+	specs = append(specs,
+		pubsub.TopicSpec{Name: c.VerificationTopic, Subscription: c.VerificationSubscription, RetryPolicy: policy},
+		pubsub.TopicSpec{Name: c.UserTopic, Subscription: c.UserSubscription, RetryPolicy: policy},
+		pubsub.TopicSpec{Name: c.WelcomeTopic, Subscription: c.WelcomeSubscription, RetryPolicy: policy},
+	)
+	if c.DelayTopic != "" {
+		specs = append(specs, pubsub.TopicSpec{Name: c.DelayTopic, Subscription: c.DelaySubscription, RetryPolicy: policy})
+	}
+	for _, extra := range c.ExtraTopics {
+		if extra.RetryPolicy.IsZero() {
+			extra.RetryPolicy = policy
+		}
+		specs = append(specs, extra)
+	}
+	return specs
+}
+
+// PriorityLanes reports whether the worker consumes the email topic through the high and low priority subscriptions
+func (c *Config) PriorityLanes() bool {
+	return c.EmailHighSubscription != "" && c.EmailLowSubscription != ""
+}
+
+// SubscriptionConcurrency returns the per-subscription concurrency overrides of the worker
+func (c *Config) SubscriptionConcurrency() map[string]int {
+	if !c.PriorityLanes() {
+		return nil
+	}
+	return map[string]int{
+		c.EmailHighSubscription: c.EmailHighConcurrency,
+		c.EmailLowSubscription:  c.EmailLowConcurrency,
+	}
+}
+
+// RetryPolicy returns the redelivery backoff of the worker subscriptions
+func (c *Config) RetryPolicy() pubsub.RetryPolicy {
+	return pubsub.RetryPolicy{MinBackoff: c.MinBackoff, MaxBackoff: c.MaxBackoff}
+}
+
+// Validate checks the settings required by every binary (API and worker)
+func (c *Config) Validate() error {
+	var missing []string
+
+	if c.ProjectID == "" {
+		missing = append(missing, "PUBSUB_PROJECT_ID")
+	}
+	if c.EmailTopic == "" && c.VerificationTopic == "" && c.UserTopic == "" {
+		missing = append(missing, "EMAIL_TOPIC, VERIFICATION_TOPIC or USER_TOPIC")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required configuration: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// ValidateWorker checks the settings required by the worker, including Resend credentials unless DRY_RUN is set
+func (c *Config) ValidateWorker() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	var missing []string
+
+	if c.EmailTopic != "" && c.EmailSubscription == "" && !c.PriorityLanes() {
+		missing = append(missing, "EMAIL_SUBSCRIPTION")
+	}
+	// The lanes only make sense together, otherwise one priority would never be consumed
+	if c.EmailHighSubscription != "" && c.EmailLowSubscription == "" {
+		missing = append(missing, "EMAIL_LOW_SUBSCRIPTION")
+	}
+	if c.EmailLowSubscription != "" && c.EmailHighSubscription == "" {
+		missing = append(missing, "EMAIL_HIGH_SUBSCRIPTION")
+	}
+	if c.VerificationTopic != "" && c.VerificationSubscription == "" {
+		missing = append(missing, "VERIFICATION_SUBSCRIPTION")
+	}
+	if c.UserTopic != "" && c.UserSubscription == "" {
+		missing = append(missing, "USER_SUBSCRIPTION")
+	}
+	if c.UserTopic != "" && c.WelcomeTopic == "" {
+		missing = append(missing, "WELCOME_TOPIC")
+	}
+	if c.WelcomeTopic != "" && c.WelcomeSubscription == "" {
+		missing = append(missing, "WELCOME_SUBSCRIPTION")
+	}
+	if c.DelayTopic != "" && c.DelaySubscription == "" {
+		missing = append(missing, "DELAY_SUBSCRIPTION")
+	}
+	// A dry run never calls Resend, so it can start without credentials
+	if c.ResendAPIKey == "" && !c.DryRun {
+		missing = append(missing, "RESEND_API_KEY")
+	}
+	if c.ResendFromEmail == "" && !c.DryRun {
+		missing = append(missing, "RESEND_FROM_EMAIL")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required worker configuration: %s", strings.Join(missing, "; "))
+	}
+
+	for _, spec := range c.WorkerTopics() {
+		if err := spec.RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("invalid retry policy for topic %s: %w", spec.Name, err)
+		}
+	}
+	return nil
 }
 
 // getEnv gets an environment variable with a fallback value
@@ -52,3 +599,94 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvBool gets a boolean environment variable with a fallback value
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using %t", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvInt gets an integer environment variable with a fallback value
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "10s", "24h") with a fallback value
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvList gets a comma-separated environment variable as a list with a fallback value
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvMap gets a comma-separated list of "key=value" entries with a fallback value, lowercasing the keys
+func getEnvMap(key string, fallback map[string]string) map[string]string {
+	items := getEnvList(key, nil)
+	if items == nil {
+		return fallback
+	}
+
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		k, v, _ := strings.Cut(item, "=")
+		values[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// getEnvTopics gets a comma-separated list of "topic" or "topic:subscription" entries with a fallback value
+func getEnvTopics(key string, fallback []pubsub.TopicSpec) []pubsub.TopicSpec {
+	items := getEnvList(key, nil)
+	if items == nil {
+		return fallback
+	}
+
+	specs := make([]pubsub.TopicSpec, 0, len(items))
+	for _, item := range items {
+		name, sub, _ := strings.Cut(item, ":")
+		specs = append(specs, pubsub.TopicSpec{
+			Name:         strings.TrimSpace(name),
+			Subscription: strings.TrimSpace(sub),
+		})
+	}
+	return specs
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFromFileDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "json string", file: "config.json", content: `{"publish_timeout": "10s"}`, want: 10 * time.Second},
+		{name: "json nanoseconds are rejected like yaml", file: "config.json", content: `{"publish_timeout": 3000000000}`, wantErr: true},
+		{name: "json invalid", file: "config.json", content: `{"publish_timeout": "soon"}`, wantErr: true},
+		{name: "yaml string", file: "config.yaml", content: "publish_timeout: 1m\n", want: time.Minute},
+		{name: "default", file: "config.json", content: `{}`, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PUBLISH_TIMEOUT", "")
+			cfg, err := LoadFromFile(writeConfigFile(t, tt.file, tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.PublishTimeout != tt.want {
+				t.Errorf("PublishTimeout = %v, want %v", cfg.PublishTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromFileNestedDurations(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+		"extra_topics": [{"name": "digest", "subscription": "digest-sub", "retry_policy": {"min_backoff": "30s", "max_backoff": "5m"}}]
+	}`)
+	t.Setenv("EXTRA_TOPICS", "")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.ExtraTopics) != 1 {
+		t.Fatalf("got %d extra topics, want 1", len(cfg.ExtraTopics))
+	}
+	policy := cfg.ExtraTopics[0].RetryPolicy
+	if policy.MinBackoff != 30*time.Second || policy.MaxBackoff != 5*time.Minute {
+		t.Errorf("retry policy = %+v, want 30s/5m", policy)
+	}
+}
+
+func TestLoadFromEnvOrFile(t *testing.T) {
+	t.Setenv("PUBLISH_TIMEOUT", "")
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.json", `{"publish_timeout": "7s"}`))
+
+	cfg, err := LoadFromEnvOrFile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.PublishTimeout != 7*time.Second {
+		t.Errorf("PublishTimeout = %v, want 7s", cfg.PublishTimeout)
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := LoadFromEnvOrFile(); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestGetEnvMap(t *testing.T) {
+	fallback := map[string]string{"example.com": "fallback@northfi.com.br"}
+
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{name: "unset keeps the fallback", want: fallback},
+		{
+			name:  "entries are trimmed and keys lowercased",
+			value: " Gmail.com = gmail@northfi.com.br ,outlook.com=outlook@northfi.com.br",
+			want:  map[string]string{"gmail.com": "gmail@northfi.com.br", "outlook.com": "outlook@northfi.com.br"},
+		},
+		{
+			name:  "entry without a value maps to empty",
+			value: "gmail.com",
+			want:  map[string]string{"gmail.com": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FROM_BY_RECIPIENT_DOMAIN", tt.value)
+
+			got := getEnvMap("FROM_BY_RECIPIENT_DOMAIN", fallback)
+			if len(got) != len(tt.want) {
+				t.Fatalf("getEnvMap() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("getEnvMap()[%q] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestPriorityLanes(t *testing.T) {
+	tests := []struct {
+		name            string
+		high, low       string
+		wantLanes       bool
+		wantEmailSpecs  int
+		wantConcurrency map[string]int
+	}{
+		{name: "single subscription", wantEmailSpecs: 1},
+		{name: "only one lane configured", high: "emails.high", wantEmailSpecs: 1},
+		{
+			name:            "both lanes",
+			high:            "emails.high",
+			low:             "emails.low",
+			wantLanes:       true,
+			wantEmailSpecs:  2,
+			wantConcurrency: map[string]int{"emails.high": 20, "emails.low": 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.EmailHighSubscription = tt.high
+			cfg.EmailLowSubscription = tt.low
+
+			if got := cfg.PriorityLanes(); got != tt.wantLanes {
+				t.Errorf("PriorityLanes() = %v, want %v", got, tt.wantLanes)
+			}
+
+			var emailSpecs []string
+			for _, spec := range cfg.WorkerTopics() {
+				if spec.Name == cfg.EmailTopic {
+					emailSpecs = append(emailSpecs, spec.Filter)
+				}
+			}
+			if len(emailSpecs) != tt.wantEmailSpecs {
+				t.Fatalf("email subscriptions = %d, want %d", len(emailSpecs), tt.wantEmailSpecs)
+			}
+			// Each lane needs its own filter, otherwise both would receive every message
+			if tt.wantLanes && (emailSpecs[0] == "" || emailSpecs[0] == emailSpecs[1]) {
+				t.Errorf("lane filters = %q, want two distinct filters", emailSpecs)
+			}
+
+			concurrency := cfg.SubscriptionConcurrency()
+			if len(concurrency) != len(tt.wantConcurrency) {
+				t.Fatalf("SubscriptionConcurrency() = %v, want %v", concurrency, tt.wantConcurrency)
+			}
+			for sub, want := range tt.wantConcurrency {
+				if concurrency[sub] != want {
+					t.Errorf("concurrency[%s] = %d, want %d", sub, concurrency[sub], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(*Config) {}},
+		{name: "missing project", modify: func(c *Config) { c.ProjectID = "" }, wantErr: "PUBSUB_PROJECT_ID"},
+		{
+			name:    "no topics",
+			modify:  func(c *Config) { c.EmailTopic, c.VerificationTopic, c.UserTopic = "", "", "" },
+			wantErr: "EMAIL_TOPIC, VERIFICATION_TOPIC or USER_TOPIC",
+		},
+		{name: "one topic is enough", modify: func(c *Config) { c.EmailTopic, c.VerificationTopic = "", "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWorker(t *testing.T) {
+	withResend := func(c *Config) {
+		c.ResendAPIKey = "re_test"
+		c.ResendFromEmail = "no-reply@northfi.com.br"
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{name: "complete", modify: withResend},
+		{name: "missing resend credentials", modify: func(*Config) {}, wantErr: "RESEND_API_KEY; RESEND_FROM_EMAIL"},
+		{name: "dry run without credentials", modify: func(c *Config) { c.DryRun = true }},
+		{
+			name:    "missing subscription",
+			modify:  func(c *Config) { withResend(c); c.UserSubscription = "" },
+			wantErr: "USER_SUBSCRIPTION",
+		},
+		{
+			name:    "half a priority lane",
+			modify:  func(c *Config) { withResend(c); c.EmailHighSubscription = "high" },
+			wantErr: "EMAIL_LOW_SUBSCRIPTION",
+		},
+		{
+			name:    "delay topic without subscription",
+			modify:  func(c *Config) { withResend(c); c.DelayTopic = "delay" },
+			wantErr: "DELAY_SUBSCRIPTION",
+		},
+		{
+			name:    "invalid retry policy",
+			modify:  func(c *Config) { withResend(c); c.MaxBackoff = time.Hour },
+			wantErr: "invalid retry policy",
+		},
+		{
+			name:    "missing project is reported first",
+			modify:  func(c *Config) { withResend(c); c.ProjectID = "" },
+			wantErr: "PUBSUB_PROJECT_ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			tt.modify(cfg)
+
+			err := cfg.ValidateWorker()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWorkerResendCredentialsFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiKey  string
+		from    string
+		dryRun  string
+		wantErr []string
+	}{
+		{name: "both set", apiKey: "re_test", from: "no-reply@northfi.com.br"},
+		{name: "missing key", from: "no-reply@northfi.com.br", wantErr: []string{"RESEND_API_KEY"}},
+		{name: "missing sender", apiKey: "re_test", wantErr: []string{"RESEND_FROM_EMAIL"}},
+		{name: "missing both", wantErr: []string{"RESEND_API_KEY", "RESEND_FROM_EMAIL"}},
+		{name: "dry run", dryRun: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("RESEND_API_KEY", tt.apiKey)
+			t.Setenv("RESEND_FROM_EMAIL", tt.from)
+			t.Setenv("DRY_RUN", tt.dryRun)
+
+			cfg, err := LoadFromEnvOrFile()
+			if err != nil {
+				t.Fatalf("LoadFromEnvOrFile failed: %v", err)
+			}
+
+			err = cfg.ValidateWorker()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v, want it to mention %s", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is what an IdempotencyStore remembers about a request
+type IdempotencyRecord struct {
+	BodyHash  string // Hash of the request body the key was first used with
+	MessageID string // Empty while the first request is still being processed
+	Queued    bool   // Whether MessageID is an outbox tracking ID because the first publish was queued for retry
+}
+
+// IdempotencyStore remembers the result of a request under its idempotency key so a retried request can be replayed
+type IdempotencyStore interface {
+	// Reserve atomically claims key for a request whose body hashes to bodyHash, reporting true when it did.
+	// When the key is already claimed within the store's window it returns the existing record instead.
+	Reserve(ctx context.Context, key, bodyHash string) (IdempotencyRecord, bool, error)
+
+	// Complete records the message ID published for a reserved key, or its tracking ID when queued is true
+	Complete(ctx context.Context, key, messageID string, queued bool) error
+
+	// Release frees a reserved key whose request failed so it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// idempotencyEntry is a record remembered until expiresAt
+type idempotencyEntry struct {
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore whose entries expire after a TTL
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]idempotencyEntry
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory IdempotencyStore keeping keys for ttl
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:       ttl,
+		entries:   make(map[string]idempotencyEntry),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Reserve claims key for bodyHash unless it was claimed within the TTL
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key, bodyHash string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		return entry.record, false, nil
+	}
+
+	record := IdempotencyRecord{BodyHash: bodyHash}
+	s.entries[key] = idempotencyEntry{record: record, expiresAt: now.Add(s.ttl)}
+	return record, true, nil
+}
+
+// Complete records messageID under key for the TTL
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key, messageID string, queued bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		// The reservation expired while the request was processed
+		return nil
+	}
+	entry.record.MessageID = messageID
+	entry.record.Queued = queued
+	entry.expiresAt = s.now().Add(s.ttl)
+	s.entries[key] = entry
+	return nil
+}
+
+// Release forgets key
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// pruneLocked drops expired entries at most once per TTL window; the caller must hold s.mu
+func (s *MemoryIdempotencyStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < s.ttl {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastPrune = now
+}
@@ -0,0 +1,103 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	const ttl = time.Minute
+
+	tests := []struct {
+		name         string
+		setup        func(s *MemoryIdempotencyStore)
+		age          time.Duration
+		wantReserved bool
+		wantRecord   IdempotencyRecord
+	}{
+		{
+			name:         "new key",
+			setup:        func(*MemoryIdempotencyStore) {},
+			wantReserved: true,
+			wantRecord:   IdempotencyRecord{BodyHash: "h1"},
+		},
+		{
+			name:       "in flight",
+			setup:      func(s *MemoryIdempotencyStore) { s.Reserve(ctx, "k", "h0") },
+			wantRecord: IdempotencyRecord{BodyHash: "h0"},
+		},
+		{
+			name: "completed",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Reserve(ctx, "k", "h0")
+				s.Complete(ctx, "k", "msg-1", false)
+			},
+			wantRecord: IdempotencyRecord{BodyHash: "h0", MessageID: "msg-1"},
+		},
+		{
+			name: "released",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Reserve(ctx, "k", "h0")
+				s.Release(ctx, "k")
+			},
+			wantReserved: true,
+			wantRecord:   IdempotencyRecord{BodyHash: "h1"},
+		},
+		{
+			name: "expired",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Reserve(ctx, "k", "h0")
+				s.Complete(ctx, "k", "msg-1", false)
+			},
+			age:          ttl,
+			wantReserved: true,
+			wantRecord:   IdempotencyRecord{BodyHash: "h1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			store := NewMemoryIdempotencyStore(ttl)
+			store.now = func() time.Time { return now }
+
+			tt.setup(store)
+			now = now.Add(tt.age)
+
+			record, reserved, err := store.Reserve(ctx, "k", "h1")
+			if err != nil {
+				t.Fatalf("Reserve failed: %v", err)
+			}
+			if reserved != tt.wantReserved || record != tt.wantRecord {
+				t.Errorf("Reserve = %+v, %v, want %+v, %v", record, reserved, tt.wantRecord, tt.wantReserved)
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreReserveIsAtomic(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reservations := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, reserved, _ := store.Reserve(context.Background(), "k", "h"); reserved {
+				mu.Lock()
+				reservations++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reservations != 1 {
+		t.Errorf("key reserved %d times, want exactly once", reservations)
+	}
+}
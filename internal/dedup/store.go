@@ -0,0 +1,74 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SeenStore records keys that were already processed so replays can be skipped
+type SeenStore interface {
+	// MarkSeen records key and reports whether it had already been seen within the store's window
+	MarkSeen(ctx context.Context, key string) (bool, error)
+
+	// Forget removes key so that a later attempt is processed again
+	Forget(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-process SeenStore whose entries expire after a TTL
+type MemoryStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]time.Time
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an in-memory SeenStore keeping keys for ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:       ttl,
+		entries:   make(map[string]time.Time),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// MarkSeen records key and reports whether it had already been seen within the TTL
+func (s *MemoryStore) MarkSeen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+
+	if expiresAt, ok := s.entries[key]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	s.entries[key] = now.Add(s.ttl)
+	return false, nil
+}
+
+// Forget removes key from the store
+func (s *MemoryStore) Forget(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// pruneLocked drops expired entries at most once per TTL window; the caller must hold s.mu
+func (s *MemoryStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < s.ttl {
+		return
+	}
+
+	for key, expiresAt := range s.entries {
+		if !now.Before(expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastPrune = now
+}
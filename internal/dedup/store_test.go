@@ -0,0 +1,66 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreMarkSeen(t *testing.T) {
+	ctx := context.Background()
+	const ttl = time.Hour
+
+	tests := []struct {
+		name     string
+		setup    func(s *MemoryStore)
+		age      time.Duration
+		wantSeen bool
+	}{
+		{name: "first time", setup: func(*MemoryStore) {}},
+		{name: "replayed", setup: func(s *MemoryStore) { s.MarkSeen(ctx, "u1") }, age: time.Minute, wantSeen: true},
+		{name: "other key", setup: func(s *MemoryStore) { s.MarkSeen(ctx, "u2") }},
+		{name: "expired", setup: func(s *MemoryStore) { s.MarkSeen(ctx, "u1") }, age: ttl},
+		{
+			name: "forgotten",
+			setup: func(s *MemoryStore) {
+				s.MarkSeen(ctx, "u1")
+				s.Forget(ctx, "u1")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			store := NewMemoryStore(ttl)
+			store.now = func() time.Time { return now }
+
+			tt.setup(store)
+			now = now.Add(tt.age)
+
+			seen, err := store.MarkSeen(ctx, "u1")
+			if err != nil {
+				t.Fatalf("MarkSeen failed: %v", err)
+			}
+			if seen != tt.wantSeen {
+				t.Errorf("MarkSeen = %v, want %v", seen, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestMemoryStorePrunes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+	store.lastPrune = now
+
+	ctx := context.Background()
+	store.MarkSeen(ctx, "u1")
+	now = now.Add(2 * time.Hour)
+	store.MarkSeen(ctx, "u2")
+
+	if len(store.entries) != 1 {
+		t.Errorf("store kept %d entries, want only the fresh one", len(store.entries))
+	}
+}
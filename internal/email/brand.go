@@ -0,0 +1,23 @@
+package email
+
+// DefaultLogoURL is the logo rendered in email headers when none is configured
+const DefaultLogoURL = "https://northfi.com.br/img/logoNorthPreto.png"
+
+// BrandConfig holds the company branding rendered in email templates
+type BrandConfig struct {
+	CompanyName  string
+	LogoURL      string
+	InlineLogo   []byte // Optional: image embedded as an inline attachment instead of loading LogoURL
+	SupportEmail string // Optional: rendered as a mailto link in the footer
+
+	// CompanyAddress is the physical mailing address required by anti-spam laws (optional, rendered in the footer)
+	CompanyAddress string
+}
+
+// DefaultBrandConfig returns the NorthFi branding
+func DefaultBrandConfig() BrandConfig {
+	return BrandConfig{
+		CompanyName: "NorthFi",
+		LogoURL:     DefaultLogoURL,
+	}
+}
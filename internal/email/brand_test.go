@@ -0,0 +1,89 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerificationBrand(t *testing.T) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		brand       BrandConfig
+		wantSupport bool
+		wantLogo    string
+	}{
+		{name: "default brand", brand: DefaultBrandConfig(), wantLogo: DefaultLogoURL},
+		{
+			name:        "support email and custom logo",
+			brand:       BrandConfig{CompanyName: "NorthFi", LogoURL: "https://cdn.northfi.com.br/logo.png", SupportEmail: "suporte@northfi.com.br"},
+			wantSupport: true,
+			wantLogo:    "https://cdn.northfi.com.br/logo.png",
+		},
+		{name: "no logo", brand: BrandConfig{CompanyName: "NorthFi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := renderer.Render(TemplateVerification, VerificationEmailData{Username: "Ana", Code: "123456", Brand: tt.brand})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(html, `href="mailto:suporte@northfi.com.br"`); got != tt.wantSupport {
+				t.Errorf("renders the support mailto = %v, want %v", got, tt.wantSupport)
+			}
+			if got := strings.Contains(html, "<img src="); got != (tt.wantLogo != "") {
+				t.Errorf("renders a logo = %v, want %v", got, tt.wantLogo != "")
+			}
+			if tt.wantLogo != "" && !strings.Contains(html, `src="`+tt.wantLogo+`"`) {
+				t.Errorf("rendered HTML does not load the logo %s", tt.wantLogo)
+			}
+		})
+	}
+}
+
+func TestCompanyAddressFooter(t *testing.T) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const address = "Av. Paulista, 1000 - São Paulo, SP"
+	withAddress := DefaultBrandConfig()
+	withAddress.CompanyAddress = address
+
+	templates := []struct {
+		name string
+		data func(BrandConfig) any
+	}{
+		{name: TemplateDefault, data: func(b BrandConfig) any { return DefaultEmailData{Subject: "Oi", Body: "Olá", Brand: b} }},
+		{name: TemplateWelcome, data: func(b BrandConfig) any { return WelcomeEmailData{Username: "Ana", Brand: b} }},
+		{name: TemplateWelcomeBusiness, data: func(b BrandConfig) any { return WelcomeEmailData{Username: "Ana", Brand: b} }},
+		{name: TemplateVerification, data: func(b BrandConfig) any { return VerificationEmailData{Username: "Ana", Code: "123456", Brand: b} }},
+	}
+
+	for _, tmpl := range templates {
+		t.Run(tmpl.name, func(t *testing.T) {
+			html, err := renderer.Render(tmpl.name, tmpl.data(withAddress))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(html, "NorthFi · "+address) {
+				t.Errorf("footer does not render the company address")
+			}
+
+			html, err = renderer.Render(tmpl.name, tmpl.data(DefaultBrandConfig()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(html, "NorthFi · ") {
+				t.Errorf("footer renders an address line without a configured address")
+			}
+		})
+	}
+}
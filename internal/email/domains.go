@@ -0,0 +1,88 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DomainStatusVerified is the status Resend reports for domains that can send emails
+const DomainStatusVerified = "verified"
+
+// Domain is a sending domain registered in Resend
+type Domain struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// domainsResponse is the body returned by GET /domains
+type domainsResponse struct {
+	Data []Domain `json:"data"`
+}
+
+// ListDomains returns the sending domains registered in the Resend account
+func (r *ResendService) ListDomains(ctx context.Context) ([]Domain, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/domains", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseResendError(resp.StatusCode, body)
+	}
+
+	var domains domainsResponse
+	if err := json.Unmarshal(body, &domains); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return domains.Data, nil
+}
+
+// CheckFromDomains reports an error naming every domain of the configured sender and the given
+// addresses that is not verified in Resend, since sends from them are rejected with a 403
+func (r *ResendService) CheckFromDomains(ctx context.Context, addresses ...string) error {
+	domains, err := r.ListDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	var verified []string
+	for _, domain := range domains {
+		if domain.Status == DomainStatusVerified {
+			verified = append(verified, strings.ToLower(domain.Name))
+		}
+	}
+
+	var unverified []string
+	for _, address := range append([]string{r.fromEmail}, addresses...) {
+		at := strings.LastIndex(address, "@")
+		if at < 0 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimRight(address[at+1:], ">"))
+		if !slices.Contains(verified, domain) && !slices.Contains(unverified, domain) {
+			unverified = append(unverified, domain)
+		}
+	}
+
+	if len(unverified) > 0 {
+		return fmt.Errorf("sender domains not verified in Resend: %s", strings.Join(unverified, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package email
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckFromDomains(t *testing.T) {
+	const domains = `{"data":[
+		{"id":"d1","name":"northfi.com.br","status":"verified"},
+		{"id":"d2","name":"mail.northfi.com.br","status":"pending"}
+	]}`
+
+	tests := []struct {
+		name      string
+		addresses []string
+		status    int
+		wantErr   string
+	}{
+		{name: "every domain verified", addresses: []string{"hello@northfi.com.br"}, status: http.StatusOK},
+		{name: "display name form", addresses: []string{"NorthFi <ola@NorthFi.com.br>"}, status: http.StatusOK},
+		{name: "pending domain", addresses: []string{"hello@mail.northfi.com.br"}, status: http.StatusOK, wantErr: "mail.northfi.com.br"},
+		{name: "unknown domain", addresses: []string{"a@other.com", "b@other.com"}, status: http.StatusOK, wantErr: "not verified in Resend: other.com"},
+		{name: "API error", status: http.StatusUnauthorized, wantErr: "401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/domains" || r.Header.Get("Authorization") != "Bearer re_test" {
+					t.Errorf("unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(tt.status)
+				if tt.status == http.StatusOK {
+					w.Write([]byte(domains))
+				} else {
+					w.Write([]byte(`{"name":"missing_api_key","message":"Missing API key"}`))
+				}
+			}))
+			defer server.Close()
+
+			resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL})
+			err := resend.CheckFromDomains(context.Background(), tt.addresses...)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckFromDomains failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckFromDomains error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
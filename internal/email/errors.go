@@ -0,0 +1,101 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrEmailTooLarge is returned before calling Resend when an email exceeds the maximum size
+var ErrEmailTooLarge = errors.New("email too large")
+
+// ErrInvalidHeader is returned before calling Resend when a custom header name is not a valid HTTP field name
+var ErrInvalidHeader = errors.New("invalid email header")
+
+// ResendAPIError is returned when the Resend API responds with a non-success status
+type ResendAPIError struct {
+	StatusCode int
+	Name       string // Resend error name, e.g. "validation_error"
+	Message    string
+
+	// RetryAfterDelay is the wait suggested by the Retry-After header (zero when absent)
+	RetryAfterDelay time.Duration
+}
+
+func (e *ResendAPIError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("resend API returned status %d (%s): %s", e.StatusCode, e.Name, e.Message)
+	}
+	return fmt.Sprintf("resend API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// RetryAfter returns the delay Resend asked for before retrying the request
+func (e *ResendAPIError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
+
+// IsPermanent reports whether retrying the same request cannot succeed
+func (e *ResendAPIError) IsPermanent() bool {
+	switch e.Name {
+	case "rate_limit_exceeded", "application_error", "internal_server_error":
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests
+}
+
+// IsPermanentError reports whether err is a send error that retrying cannot fix
+func IsPermanentError(err error) bool {
+	if errors.Is(err, ErrEmailTooLarge) || errors.Is(err, ErrInvalidHeader) {
+		return true
+	}
+	var apiErr *ResendAPIError
+	return errors.As(err, &apiErr) && apiErr.IsPermanent()
+}
+
+// RetryAfter returns the delay suggested by err when it carries one through a RetryAfter method
+func RetryAfter(err error) (time.Duration, bool) {
+	var suggester interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &suggester) {
+		return 0, false
+	}
+	delay := suggester.RetryAfter()
+	return delay, delay > 0
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// parseResendError builds a ResendAPIError from a Resend error response body
+func parseResendError(statusCode int, body []byte) *ResendAPIError {
+	apiErr := &ResendAPIError{StatusCode: statusCode}
+
+	var parsed struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && (parsed.Name != "" || parsed.Message != "") {
+		apiErr.Name = parsed.Name
+		apiErr.Message = parsed.Message
+		return apiErr
+	}
+
+	// Not a JSON error body, keep the raw response for troubleshooting
+	apiErr.Message = strings.TrimSpace(string(body))
+	return apiErr
+}
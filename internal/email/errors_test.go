@@ -0,0 +1,136 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseResendError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantName      string
+		wantMessage   string
+		wantPermanent bool
+	}{
+		{
+			name:          "forbidden",
+			status:        http.StatusForbidden,
+			body:          `{"statusCode":403,"name":"invalid_access","message":"You can only send testing emails to your own email address"}`,
+			wantName:      "invalid_access",
+			wantMessage:   "You can only send testing emails to your own email address",
+			wantPermanent: true,
+		},
+		{
+			name:          "validation error",
+			status:        http.StatusUnprocessableEntity,
+			body:          `{"statusCode":422,"name":"validation_error","message":"Invalid 'to' field"}`,
+			wantName:      "validation_error",
+			wantMessage:   "Invalid 'to' field",
+			wantPermanent: true,
+		},
+		{
+			name:        "rate limited",
+			status:      http.StatusTooManyRequests,
+			body:        `{"statusCode":429,"name":"rate_limit_exceeded","message":"Too many requests"}`,
+			wantName:    "rate_limit_exceeded",
+			wantMessage: "Too many requests",
+		},
+		{
+			name:        "server error",
+			status:      http.StatusInternalServerError,
+			body:        `{"statusCode":500,"name":"internal_server_error","message":"Unexpected error"}`,
+			wantName:    "internal_server_error",
+			wantMessage: "Unexpected error",
+		},
+		{
+			name:          "plain text body",
+			status:        http.StatusForbidden,
+			body:          " Forbidden \n",
+			wantMessage:   "Forbidden",
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := parseResendError(tt.status, []byte(tt.body))
+
+			if apiErr.StatusCode != tt.status || apiErr.Name != tt.wantName || apiErr.Message != tt.wantMessage {
+				t.Errorf("parseResendError = %+v, want status %d, name %q and message %q", apiErr, tt.status, tt.wantName, tt.wantMessage)
+			}
+			if got := IsPermanentError(fmt.Errorf("send failed: %w", apiErr)); got != tt.wantPermanent {
+				t.Errorf("IsPermanentError = %v, want %v", got, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestResendServiceErrorResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		retryAfter     string
+		wantName       string
+		wantRetryAfter time.Duration
+	}{
+		{name: "forbidden", status: http.StatusForbidden, body: `{"name":"invalid_access","message":"Not allowed"}`, wantName: "invalid_access"},
+		{name: "validation error", status: http.StatusUnprocessableEntity, body: `{"name":"validation_error","message":"Invalid 'to' field"}`, wantName: "validation_error"},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"name":"rate_limit_exceeded","message":"Slow down"}`, retryAfter: "3", wantName: "rate_limit_exceeded", wantRetryAfter: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL})
+			err := resend.SendEmailWithHTMLContext(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>")
+
+			var apiErr *ResendAPIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want a *ResendAPIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Name != tt.wantName {
+				t.Errorf("error = %+v, want status %d and name %q", apiErr, tt.status, tt.wantName)
+			}
+			if got, _ := RetryAfter(err); got != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "seconds", value: "5", want: 5 * time.Second},
+		{name: "negative", value: "-5"},
+		{name: "empty"},
+		{name: "garbage", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package email
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// LogoContentID is the content ID of the inline logo, referenced as "cid:logo" by the templates
+const LogoContentID = "logo"
+
+// LoadLogo reads the image file embedded inline as the brand logo, rejecting anything that is not an image
+func LoadLogo(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logo: %w", err)
+	}
+	if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("logo %s is %s, not an image", path, contentType)
+	}
+	return data, nil
+}
+
+// InlineLogoAttachment returns logo as an inline attachment referenced by LogoContentID
+func InlineLogoAttachment(logo []byte) Attachment {
+	contentType := http.DetectContentType(logo)
+	return Attachment{
+		Filename:    "logo." + strings.TrimPrefix(contentType, "image/"),
+		Content:     logo,
+		ContentType: contentType,
+		ContentID:   LogoContentID,
+	}
+}
+
+// Attachments returns the inline attachments the brand templates reference (none unless InlineLogo is set)
+func (b BrandConfig) Attachments() []Attachment {
+	if len(b.InlineLogo) == 0 {
+		return nil
+	}
+	return []Attachment{InlineLogoAttachment(b.InlineLogo)}
+}
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadLogo(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr bool
+	}{
+		{name: "png", content: pngHeader},
+		{name: "not an image", content: []byte("placeholder"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "logo")
+			if err := os.WriteFile(path, tt.content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadLogo(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadLogo error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadLogo(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("LoadLogo succeeded for a missing file")
+	}
+}
+
+func TestBrandAttachments(t *testing.T) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		logo     []byte
+		wantCID  bool
+		wantFile string
+	}{
+		{name: "no logo file keeps the remote logo"},
+		{name: "inline logo", logo: pngHeader, wantCID: true, wantFile: "logo.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brand := DefaultBrandConfig()
+			brand.InlineLogo = tt.logo
+
+			attachments := brand.Attachments()
+			if got := len(attachments) == 1; got != tt.wantCID {
+				t.Fatalf("attachments = %d, want inline logo %v", len(attachments), tt.wantCID)
+			}
+			if tt.wantCID && (attachments[0].Filename != tt.wantFile || attachments[0].ContentID != LogoContentID) {
+				t.Errorf("attachment = %s (%s), want %s (%s)", attachments[0].Filename, attachments[0].ContentID, tt.wantFile, LogoContentID)
+			}
+
+			html, err := renderer.Render("welcome", WelcomeEmailData{Username: "Ana", Brand: brand})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(html, `src="cid:logo"`); got != tt.wantCID {
+				t.Errorf("renders cid:logo = %v, want %v", got, tt.wantCID)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"go_integration/internal/models"
+)
+
+// MarkdownRenderer renders a Markdown payload body as HTML inside the default template
+type MarkdownRenderer struct {
+	templates *TemplateRenderer
+	brand     BrandConfig
+}
+
+// NewMarkdownRenderer creates a renderer executing the default template with the given branding
+func NewMarkdownRenderer(templates *TemplateRenderer, brand BrandConfig) *MarkdownRenderer {
+	return &MarkdownRenderer{
+		templates: templates,
+		brand:     brand,
+	}
+}
+
+// Render converts the payload body from Markdown and renders it in the default template
+func (r *MarkdownRenderer) Render(payload *models.EmailPayload) (string, string, error) {
+	htmlContent, err := r.templates.Render(TemplateDefault, DefaultEmailData{
+		Subject:  payload.Subject,
+		HTMLBody: MarkdownToHTML(payload.Body),
+		CTA:      ctaFor(payload),
+		Brand:    r.brand,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return payload.Subject, htmlContent, nil
+}
+
+var (
+	// unsafeBlocks are removed together with their content before the remaining tags are stripped
+	unsafeBlocks = regexp.MustCompile(`(?is)<(script|style|iframe|object)\b.*?</(script|style|iframe|object)\s*>`)
+	rawTags      = regexp.MustCompile(`<[^>]*>`)
+
+	headingLine  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletLine   = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	numberedLine = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	quoteLine    = regexp.MustCompile(`^>\s?(.*)$`)
+
+	codeSpan   = regexp.MustCompile("`([^`]+)`")
+	linkSpan   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldSpan   = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicSpan = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// MarkdownToHTML converts a Markdown subset (headings, paragraphs, lists, quotes, code, links and emphasis)
+// into HTML. Raw HTML in the input is stripped, so the result is safe to embed in a template.
+func MarkdownToHTML(markdown string) template.HTML {
+	markdown = unsafeBlocks.ReplaceAllString(markdown, "")
+	markdown = rawTags.ReplaceAllString(markdown, "")
+
+	var out strings.Builder
+	var paragraph []string
+	list := "" // "ul" or "ol" while a list is open
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			fmt.Fprintf(&out, "<p>%s</p>\n", renderInline(strings.Join(paragraph, " ")))
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			fmt.Fprintf(&out, "</%s>\n", list)
+			list = ""
+		}
+	}
+	openList := func(kind string) {
+		if list != kind {
+			closeList()
+			fmt.Fprintf(&out, "<%s>\n", kind)
+			list = kind
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			closeList()
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case headingLine.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			m := headingLine.FindStringSubmatch(trimmed)
+			fmt.Fprintf(&out, "<h%d>%s</h%d>\n", len(m[1]), renderInline(m[2]), len(m[1]))
+		case bulletLine.MatchString(trimmed):
+			flushParagraph()
+			openList("ul")
+			fmt.Fprintf(&out, "<li>%s</li>\n", renderInline(bulletLine.FindStringSubmatch(trimmed)[1]))
+		case numberedLine.MatchString(trimmed):
+			flushParagraph()
+			openList("ol")
+			fmt.Fprintf(&out, "<li>%s</li>\n", renderInline(numberedLine.FindStringSubmatch(trimmed)[1]))
+		case quoteLine.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			fmt.Fprintf(&out, "<blockquote>%s</blockquote>\n", renderInline(quoteLine.FindStringSubmatch(trimmed)[1]))
+		default:
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+
+	flushParagraph()
+	closeList()
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+
+	// Every piece of input text above is escaped before it is written
+	return template.HTML(out.String())
+}
+
+// renderInline escapes text and converts its code spans, links and emphasis to HTML
+func renderInline(text string) string {
+	text = html.EscapeString(text)
+
+	// Code spans are set aside so their content is not formatted
+	var codes []string
+	text = codeSpan.ReplaceAllStringFunc(text, func(match string) string {
+		codes = append(codes, "<code>"+codeSpan.FindStringSubmatch(match)[1]+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	text = linkSpan.ReplaceAllStringFunc(text, func(match string) string {
+		m := linkSpan.FindStringSubmatch(match)
+		if !isSafeLink(html.UnescapeString(m[2])) {
+			return m[1]
+		}
+		return fmt.Sprintf(`<a href="%s" target="_blank">%s</a>`, m[2], m[1])
+	})
+	text = boldSpan.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = italicSpan.ReplaceAllString(text, "<em>$1$2</em>")
+
+	for i, code := range codes {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+	return text
+}
+
+// isSafeLink reports whether url uses a scheme that is safe to link to from an email
+func isSafeLink(url string) bool {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "mailto:")
+}
@@ -0,0 +1,58 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{name: "paragraph", markdown: "Olá\nmundo", want: "<p>Olá mundo</p>\n"},
+		{name: "heading", markdown: "## Título ##", want: "<h2>Título</h2>\n"},
+		{name: "bullet list", markdown: "- um\n- dois", want: "<ul>\n<li>um</li>\n<li>dois</li>\n</ul>\n"},
+		{name: "numbered list", markdown: "1. um\n2) dois", want: "<ol>\n<li>um</li>\n<li>dois</li>\n</ol>\n"},
+		{name: "quote", markdown: "> citação", want: "<blockquote>citação</blockquote>\n"},
+		{name: "emphasis", markdown: "**forte** e *leve*", want: "<p><strong>forte</strong> e <em>leve</em></p>\n"},
+		{name: "code span is not formatted", markdown: "use `**x**`", want: "<p>use <code>**x**</code></p>\n"},
+		{name: "code block is escaped", markdown: "```\na < b\n```", want: "<pre><code>a &lt; b\n</code></pre>\n"},
+		{name: "unclosed code block", markdown: "```\nx", want: "<pre><code>x\n</code></pre>\n"},
+		{
+			name:     "safe link",
+			markdown: "[site](https://northfi.com.br)",
+			want:     `<p><a href="https://northfi.com.br" target="_blank">site</a></p>` + "\n",
+		},
+		{name: "javascript link keeps only the text", markdown: "[clique](javascript:void)", want: "<p>clique</p>\n"},
+		{name: "raw HTML is stripped", markdown: "<b>oi</b><script>alert(1)</script>", want: "<p>oi</p>\n"},
+		{name: "special characters are escaped", markdown: "a & b \"c\"", want: "<p>a &amp; b &#34;c&#34;</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(MarkdownToHTML(tt.markdown)); got != tt.want {
+				t.Errorf("MarkdownToHTML(%q) =\n%q\nwant\n%q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTMLNeverEmitsScripts(t *testing.T) {
+	inputs := []string{
+		"<scr<script>ipt>alert(1)</script>",
+		"[x](JaVaScRiPt:alert(1))",
+		"<img src=x onerror=alert(1)>",
+		"# <style>body{}</style>",
+	}
+
+	for _, input := range inputs {
+		got := strings.ToLower(string(MarkdownToHTML(input)))
+		for _, unsafe := range []string{"<script", "javascript:", "<img", "<style"} {
+			if strings.Contains(got, unsafe) {
+				t.Errorf("MarkdownToHTML(%q) = %q contains %s", input, got, unsafe)
+			}
+		}
+	}
+}
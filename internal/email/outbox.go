@@ -0,0 +1,163 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go_integration/internal/audit"
+	ipubsub "go_integration/internal/pubsub"
+)
+
+var (
+	// ErrPublishQueued is returned with a tracking ID when the immediate publish failed but the message was queued for retry
+	ErrPublishQueued = errors.New("publish failed, message queued for retry")
+
+	// ErrOutboxFull is returned when the outbox cannot accept more messages
+	ErrOutboxFull = errors.New("outbox is full")
+)
+
+// Outbox stores messages whose publish failed so they can be retried in the background.
+// A durable implementation can replace the in-memory one.
+type Outbox interface {
+	Enqueue(ctx context.Context, msg OutboxMessage) (trackingID string, err error)
+}
+
+// OutboxMessage is a message handed to the outbox, with the audit details recorded once it is republished
+type OutboxMessage struct {
+	Data      []byte
+	Attrs     map[string]string
+	Type      string // Audit message type, e.g. audit.TypeEmail
+	Recipient string
+}
+
+// OutboxConfig holds the retry settings of a MemoryOutbox
+type OutboxConfig struct {
+	Capacity    int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// Concurrency is the number of entries republished at once, so a failing entry backing off
+	// does not hold up the others (<= 0 uses 1)
+	Concurrency int
+}
+
+// DefaultOutboxConfig returns the standard outbox configuration
+func DefaultOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		Capacity:    100,
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Concurrency: 4,
+	}
+}
+
+// outboxEntry is a message waiting to be republished
+type outboxEntry struct {
+	trackingID string
+	msg        OutboxMessage
+}
+
+// MemoryOutbox is a bounded in-memory Outbox that republishes with exponential backoff
+type MemoryOutbox struct {
+	publisher   ipubsub.Publisher
+	config      OutboxConfig
+	entries     chan outboxEntry
+	auditLogger audit.Logger
+}
+
+// NewMemoryOutbox creates an in-memory outbox that republishes through publisher
+func NewMemoryOutbox(publisher ipubsub.Publisher, config OutboxConfig) *MemoryOutbox {
+	return &MemoryOutbox{
+		publisher: publisher,
+		config:    config,
+		entries:   make(chan outboxEntry, config.Capacity),
+	}
+}
+
+// SetAuditLogger records every republished message in an audit trail (nil disables auditing)
+func (o *MemoryOutbox) SetAuditLogger(logger audit.Logger) {
+	o.auditLogger = logger
+}
+
+// Enqueue queues a message for background republishing and returns its tracking ID
+func (o *MemoryOutbox) Enqueue(_ context.Context, msg OutboxMessage) (string, error) {
+	trackingID, err := newTrackingID()
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case o.entries <- outboxEntry{trackingID: trackingID, msg: msg}:
+		return trackingID, nil
+	default:
+		return "", ErrOutboxFull
+	}
+}
+
+// Run republishes queued messages with up to Concurrency entries in flight until ctx is canceled
+func (o *MemoryOutbox) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range max(o.config.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case entry := <-o.entries:
+					o.republish(ctx, entry)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pending := len(o.entries); pending > 0 {
+		slog.Warn("Outbox stopped with pending messages", "pending", pending)
+	}
+}
+
+// republish retries a single entry with exponential backoff
+func (o *MemoryOutbox) republish(ctx context.Context, entry outboxEntry) {
+	logger := slog.With("tracking_id", entry.trackingID)
+	delay := o.config.BaseDelay
+
+	for attempt := 1; attempt <= o.config.MaxAttempts; attempt++ {
+		if err := sleepContext(ctx, delay); err != nil {
+			logger.Warn("Outbox retry aborted", "error", err)
+			return
+		}
+
+		id, err := o.publisher.Publish(ctx, entry.msg.Data, entry.msg.Attrs)
+		if err == nil {
+			logger.Info("Outbox message published", "attempt", attempt, "message_id", id)
+			if o.auditLogger != nil {
+				o.auditLogger.Record(ctx, audit.NewEntry(entry.msg.Type, entry.msg.Recipient, id))
+			}
+			return
+		}
+
+		logger.Error("Outbox publish attempt failed", "attempt", attempt, "error", err)
+		delay = min(delay*2, o.config.MaxDelay)
+	}
+
+	logger.Error("Outbox message dropped after all attempts", "max_attempts", o.config.MaxAttempts)
+}
+
+// newTrackingID returns a random identifier for a queued message
+func newTrackingID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate tracking ID: %w", err)
+	}
+	return "outbox-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,120 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/audit"
+)
+
+// stubPublisher fails every publish of the data listed in failing and records the others
+type stubPublisher struct {
+	mu        sync.Mutex
+	failing   map[string]bool
+	published []string
+}
+
+func (p *stubPublisher) Publish(_ context.Context, data []byte, _ map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failing[string(data)] {
+		return "", errors.New("unavailable")
+	}
+	p.published = append(p.published, string(data))
+	return "id-" + string(data), nil
+}
+
+func (p *stubPublisher) snapshot() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+// recordingAuditLogger keeps the audit entries it receives
+type recordingAuditLogger struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (l *recordingAuditLogger) Record(_ context.Context, entry audit.Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordingAuditLogger) snapshot() []audit.Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]audit.Entry(nil), l.entries...)
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryOutboxFailingEntryDoesNotStallOthers(t *testing.T) {
+	publisher := &stubPublisher{failing: map[string]bool{"bad": true}}
+	auditLogger := &recordingAuditLogger{}
+	outbox := NewMemoryOutbox(publisher, OutboxConfig{
+		Capacity:    10,
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Second,
+		Concurrency: 2,
+	})
+	outbox.SetAuditLogger(auditLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, data := range []string{"bad", "good"} {
+		if _, err := outbox.Enqueue(ctx, OutboxMessage{Data: []byte(data), Type: audit.TypeEmail, Recipient: data + "@example.com"}); err != nil {
+			t.Fatalf("Enqueue(%s) failed: %v", data, err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		outbox.Run(ctx)
+		close(done)
+	}()
+
+	waitFor(t, time.Second, func() bool { return len(publisher.snapshot()) == 1 })
+	if got := publisher.snapshot(); got[0] != "good" {
+		t.Errorf("published %v, want [good]", got)
+	}
+
+	entries := auditLogger.snapshot()
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(entries))
+	}
+	want := audit.NewEntry(audit.TypeEmail, "good@example.com", "id-good")
+	if entries[0].Type != want.Type || entries[0].RecipientHash != want.RecipientHash || entries[0].MessageID != want.MessageID {
+		t.Errorf("audit entry = %+v, want %+v", entries[0], want)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestMemoryOutboxFull(t *testing.T) {
+	outbox := NewMemoryOutbox(&stubPublisher{}, OutboxConfig{Capacity: 1, MaxAttempts: 1})
+
+	if _, err := outbox.Enqueue(context.Background(), OutboxMessage{Data: []byte("a")}); err != nil {
+		t.Fatalf("first Enqueue failed: %v", err)
+	}
+	if _, err := outbox.Enqueue(context.Background(), OutboxMessage{Data: []byte("b")}); !errors.Is(err, ErrOutboxFull) {
+		t.Errorf("second Enqueue error = %v, want ErrOutboxFull", err)
+	}
+}
@@ -0,0 +1,97 @@
+package email
+
+import "go_integration/internal/models"
+
+// Renderer renders the subject and HTML body of a regular email payload
+type Renderer interface {
+	Render(payload *models.EmailPayload) (subject, html string, err error)
+}
+
+// DefaultRenderer renders a payload with the embedded template it requests, falling back to sniffing the subject
+type DefaultRenderer struct {
+	templates *TemplateRenderer
+	brand     BrandConfig
+	bodyMode  string
+}
+
+// NewDefaultRenderer creates a renderer executing templates with the given branding.
+// bodyMode is BodyModeRich to keep the allowlisted HTML of the body, otherwise every tag is escaped.
+func NewDefaultRenderer(templates *TemplateRenderer, brand BrandConfig, bodyMode string) *DefaultRenderer {
+	return &DefaultRenderer{
+		templates: templates,
+		brand:     brand,
+		bodyMode:  bodyMode,
+	}
+}
+
+// Render renders the payload with the template returned by TemplateFor
+func (r *DefaultRenderer) Render(payload *models.EmailPayload) (string, string, error) {
+	name := TemplateFor(payload)
+	html, err := r.templates.Render(name, r.templateData(name, payload))
+	if err != nil {
+		return "", "", err
+	}
+	return payload.Subject, html, nil
+}
+
+// TemplateFor returns the template requested by the payload, falling back to sniffing the subject
+func TemplateFor(payload *models.EmailPayload) string {
+	switch payload.Template {
+	case models.TemplateWelcome:
+		return TemplateWelcome
+	case models.TemplateVerification:
+		if payload.DataValue("code") == "" {
+			return TemplateVerificationLink
+		}
+		return TemplateVerification
+	case models.TemplateDefault:
+		return TemplateDefault
+	}
+
+	// Markdown bodies are always rendered in the default template
+	if !payload.IsMarkdown() && IsWelcomeSubject(payload.Subject) {
+		return TemplateWelcome
+	}
+	return TemplateDefault
+}
+
+// templateData builds the data for the named template from the payload fields and its template data
+func (r *DefaultRenderer) templateData(name string, payload *models.EmailPayload) any {
+	username := payload.DataValue("name")
+	if username == "" {
+		username = payload.RecipientName()
+	}
+
+	switch name {
+	case TemplateWelcome:
+		return WelcomeEmailData{
+			Username: username,
+			CTA:      ctaFor(payload),
+			Brand:    r.brand,
+		}
+	case TemplateVerification, TemplateVerificationLink:
+		return VerificationEmailData{
+			Username:  username,
+			Code:      payload.DataValue("code"),
+			VerifyURL: payload.DataValue("verify_url"),
+			ExpiresIn: models.DefaultVerificationTTL,
+			Brand:     r.brand,
+		}
+	default:
+		data := DefaultEmailData{
+			Subject: payload.Subject,
+			Body:    payload.Body,
+			CTA:     ctaFor(payload),
+			Brand:   r.brand,
+		}
+		if r.bodyMode == BodyModeRich {
+			data.HTMLBody = SanitizeHTML(payload.Body)
+		}
+		return data
+	}
+}
+
+// ctaFor returns the call-to-action button of the payload
+func ctaFor(payload *models.EmailPayload) CTA {
+	return CTA{Text: payload.CTAText, URL: payload.CTAURL}
+}
@@ -0,0 +1,62 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"go_integration/internal/models"
+)
+
+func TestDefaultRenderer(t *testing.T) {
+	templates, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		bodyMode string
+		payload  models.EmailPayload
+		want     string
+		dontWant string
+	}{
+		{
+			name:     "plain body is escaped",
+			bodyMode: BodyModePlain,
+			payload:  models.EmailPayload{Subject: "Oi", Body: "<b>Olá</b>"},
+			want:     "&lt;b&gt;Olá&lt;/b&gt;",
+		},
+		{
+			name:     "rich body keeps allowlisted tags",
+			bodyMode: BodyModeRich,
+			payload:  models.EmailPayload{Subject: "Oi", Body: `<b>Olá</b><script>alert(1)</script>`},
+			want:     "<b>Olá</b>",
+			dontWant: "<script>",
+		},
+		{
+			name:    "welcome subject uses the welcome template",
+			payload: models.EmailPayload{Subject: "Bem-vinda", Body: "Olá"},
+			want:    "Bem-vindo(a) à NorthFi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer := NewDefaultRenderer(templates, DefaultBrandConfig(), tt.bodyMode)
+
+			subject, html, err := renderer.Render(&tt.payload)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if subject != tt.payload.Subject {
+				t.Errorf("subject = %q, want %q", subject, tt.payload.Subject)
+			}
+			if !strings.Contains(html, tt.want) {
+				t.Errorf("rendered HTML does not contain %q", tt.want)
+			}
+			if tt.dontWant != "" && strings.Contains(html, tt.dontWant) {
+				t.Errorf("rendered HTML contains %q", tt.dontWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go_integration/internal/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// partialsFile holds the blocks shared by every template (logo, support contact)
+const partialsFile = "templates/partials.html"
+
+// Template names accepted by TemplateRenderer.Render
+const (
+	TemplateDefault      = "default"
+	TemplateWelcome      = "welcome"
+	TemplateVerification = "verification"
+
+	// TemplateVerificationLink is the verification template showing a button to VerifyURL instead of a code
+	TemplateVerificationLink = "verification_link"
+
+	// TemplateWelcomeBusiness is the welcome template sent to business accounts
+	TemplateWelcomeBusiness = "welcome_business"
+)
+
+// welcomeSegmentTemplates maps a user segment to its welcome template
+var welcomeSegmentTemplates = map[string]string{
+	"business":   TemplateWelcomeBusiness,
+	"individual": TemplateWelcome,
+}
+
+// WelcomeTemplateFor returns the welcome template for a user segment, falling back to the default welcome
+func WelcomeTemplateFor(segment string) string {
+	if name, ok := welcomeSegmentTemplates[strings.ToLower(strings.TrimSpace(segment))]; ok {
+		return name
+	}
+	return TemplateWelcome
+}
+
+// CTA is a call-to-action button, rendered only when both Text and URL are set
+type CTA struct {
+	Text string
+	URL  string
+}
+
+// IsSet reports whether the button has both a text and a URL
+func (c CTA) IsSet() bool {
+	return c.Text != "" && c.URL != ""
+}
+
+// DefaultEmailData is the data rendered by the default template
+type DefaultEmailData struct {
+	Subject  string
+	Body     string
+	HTMLBody template.HTML // Optional: trusted HTML rendered instead of Body, e.g. converted Markdown
+	CTA      CTA           // Optional: button rendered below the body
+	Brand    BrandConfig
+}
+
+// WelcomeEmailData is the data rendered by the welcome template
+type WelcomeEmailData struct {
+	Username string
+	CTA      CTA // Optional: replaces the default "access my account" button
+	Brand    BrandConfig
+}
+
+// VerificationEmailData is the data rendered by the verification template
+type VerificationEmailData struct {
+	Username  string
+	Code      string
+	VerifyURL string // Used by the verification link template
+	ExpiresIn time.Duration
+	Brand     BrandConfig
+}
+
+// TemplateRenderer renders the embedded email templates, parsing them once at construction
+type TemplateRenderer struct {
+	templates map[string]*template.Template
+}
+
+// NewTemplateRenderer parses every embedded email template
+func NewTemplateRenderer() (*TemplateRenderer, error) {
+	files, err := fs.Glob(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email templates: %w", err)
+	}
+
+	funcs := template.FuncMap{
+		"expiry": formatExpiry,
+		"code":   models.FormatCode,
+	}
+
+	templates := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		if file == partialsFile {
+			continue
+		}
+
+		base := path.Base(file)
+		tmpl, err := template.New(base).Funcs(funcs).ParseFS(templateFS, file, partialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", base, err)
+		}
+		templates[strings.TrimSuffix(base, ".html")] = tmpl
+	}
+
+	return &TemplateRenderer{templates: templates}, nil
+}
+
+// Render executes the named template with data and returns the resulting HTML
+func (r *TemplateRenderer) Render(name string, data any) (string, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown email template: %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render email template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+var (
+	sharedRenderer     *TemplateRenderer
+	sharedRendererErr  error
+	sharedRendererOnce sync.Once
+)
+
+// SharedTemplateRenderer returns the package-wide renderer, parsing the templates on first use
+func SharedTemplateRenderer() (*TemplateRenderer, error) {
+	sharedRendererOnce.Do(func() {
+		sharedRenderer, sharedRendererErr = NewTemplateRenderer()
+	})
+	return sharedRenderer, sharedRendererErr
+}
@@ -0,0 +1,138 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateRendererRender(t *testing.T) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		data     any
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "default escapes the body",
+			template: TemplateDefault,
+			data:     DefaultEmailData{Subject: "Oi", Body: "<b>Ana</b>", Brand: DefaultBrandConfig()},
+			want:     []string{"&lt;b&gt;Ana&lt;/b&gt;"},
+		},
+		{
+			name:     "welcome renders a custom button",
+			template: TemplateWelcome,
+			data:     WelcomeEmailData{Username: "Ana", CTA: CTA{Text: "Abrir app", URL: "https://northfi.com.br/app"}, Brand: DefaultBrandConfig()},
+			want:     []string{"Abrir app", "https://northfi.com.br/app"},
+		},
+		{
+			name:     "verification formats the code and expiry",
+			template: TemplateVerification,
+			data:     VerificationEmailData{Username: "Ana", Code: "123456", ExpiresIn: time.Hour, Brand: DefaultBrandConfig()},
+			want:     []string{"123 456", "1 hora"},
+		},
+		{
+			name:     "unknown template",
+			template: "missing",
+			data:     DefaultEmailData{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := renderer.Render(tt.template, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("rendered HTML does not contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedTemplateRenderer(t *testing.T) {
+	first, err := SharedTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := SharedTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("SharedTemplateRenderer parsed the templates twice")
+	}
+}
+
+var benchmarkData = VerificationEmailData{Username: "Ana", Code: "123456", ExpiresIn: 15 * time.Minute, Brand: DefaultBrandConfig()}
+
+func BenchmarkRenderParsePerCall(b *testing.B) {
+	for b.Loop() {
+		renderer, err := NewTemplateRenderer()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := renderer.Render(TemplateVerification, benchmarkData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderCached(b *testing.B) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		if _, err := renderer.Render(TemplateVerification, benchmarkData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWelcomeTemplateFor(t *testing.T) {
+	renderer, err := NewTemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		segment  string
+		want     string
+		wantHTML string
+	}{
+		{segment: "business", want: TemplateWelcomeBusiness, wantHTML: "Acessar a conta da empresa"},
+		{segment: " Business ", want: TemplateWelcomeBusiness, wantHTML: "Acessar a conta da empresa"},
+		{segment: "individual", want: TemplateWelcome, wantHTML: "Acessar minha conta"},
+		{segment: "", want: TemplateWelcome, wantHTML: "Acessar minha conta"},
+		{segment: "enterprise", want: TemplateWelcome, wantHTML: "Acessar minha conta"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.segment, func(t *testing.T) {
+			name := WelcomeTemplateFor(tt.segment)
+			if name != tt.want {
+				t.Fatalf("WelcomeTemplateFor(%q) = %q, want %q", tt.segment, name, tt.want)
+			}
+
+			html, err := renderer.Render(name, WelcomeEmailData{Username: "Ana", Brand: DefaultBrandConfig()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(html, tt.wantHTML) {
+				t.Errorf("rendered HTML does not contain %q", tt.wantHTML)
+			}
+		})
+	}
+}
@@ -2,24 +2,104 @@ package email
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
+	"strings"
 	"time"
+
+	"go_integration/internal/logging"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// DefaultResendBaseURL is the Resend API endpoint used when no base URL is configured
+const DefaultResendBaseURL = "https://api.resend.com"
+
+// Default Resend HTTP timeouts, used when ResendConfig leaves them at zero
+const (
+	DefaultResendDialTimeout           = 5 * time.Second
+	DefaultResendTLSHandshakeTimeout   = 5 * time.Second
+	DefaultResendResponseHeaderTimeout = 15 * time.Second
 )
 
+// DefaultMaxEmailSize is the largest email, attachments included, Resend accepts
+const DefaultMaxEmailSize = 40 << 20
+
+// ResendConfig holds the settings used to create a ResendService
+type ResendConfig struct {
+	APIKey    string
+	FromEmail string
+	FromName  string // Optional: display name shown in the recipient's inbox
+
+	// Default open/click tracking, overridable per message through SendOptions
+	TrackOpens  bool
+	TrackClicks bool
+
+	// DryRun logs the intended email instead of calling the Resend API
+	DryRun bool
+
+	// MaxEmailSize rejects emails whose HTML, text and attachments add up to more bytes (0 uses DefaultMaxEmailSize)
+	MaxEmailSize int
+
+	// BaseURL overrides the Resend API endpoint, e.g. a local mock or a regional endpoint (empty uses DefaultResendBaseURL)
+	BaseURL string
+
+	// HTTP timeouts for connecting, the TLS handshake and waiting for the response headers (0 uses the defaults),
+	// and for the whole request including reading the body (0 disables it)
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	Timeout               time.Duration
+
+	// Logger records the Resend ID of every sent email (nil uses the default logger)
+	Logger *slog.Logger
+}
+
 // ResendService handles email sending via Resend API
 type ResendService struct {
-	apiKey    string
-	fromEmail string
+	apiKey       string
+	fromEmail    string
+	fromName     string
+	trackOpens   bool
+	trackClicks  bool
+	dryRun       bool
+	baseURL      string
+	maxEmailSize int
+	httpClient   *http.Client
+	logger       *slog.Logger
 }
 
 // NewResendService creates a new Resend email service
-func NewResendService() *ResendService {
+func NewResendService(cfg ResendConfig) *ResendService {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultResendBaseURL
+	}
+
+	maxEmailSize := cfg.MaxEmailSize
+	if maxEmailSize <= 0 {
+		maxEmailSize = DefaultMaxEmailSize
+	}
+
 	return &ResendService{
-		apiKey:    os.Getenv("RESEND_API_KEY"),
-		fromEmail: os.Getenv("RESEND_FROM_EMAIL"),
+		apiKey:       cfg.APIKey,
+		fromEmail:    cfg.FromEmail,
+		fromName:     cfg.FromName,
+		trackOpens:   cfg.TrackOpens,
+		trackClicks:  cfg.TrackClicks,
+		dryRun:       cfg.DryRun,
+		baseURL:      baseURL,
+		maxEmailSize: maxEmailSize,
+		httpClient:   newResendHTTPClient(cfg),
+		logger:       cfg.Logger,
 	}
 }
 
@@ -30,6 +110,76 @@ type EmailRequest struct {
 	Subject string   `json:"subject"`
 	HTML    string   `json:"html,omitempty"`
 	Text    string   `json:"text,omitempty"`
+
+	TrackOpens  bool  `json:"track_opens,omitempty"`
+	TrackClicks bool  `json:"track_clicks,omitempty"`
+	Tags        []Tag `json:"tags,omitempty"`
+
+	ScheduledAt string `json:"scheduled_at,omitempty"` // RFC 3339 time Resend should deliver the email at
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Attachment is a file sent along with an email; Content is base64-encoded in the request
+type Attachment struct {
+	Filename    string `json:"filename"`
+	Content     []byte `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
+	ContentID   string `json:"content_id,omitempty"` // Optional: embeds the attachment inline, referenced as "cid:<ContentID>"
+}
+
+// Tag is a name/value pair attached to an email for analytics in the Resend dashboard
+type Tag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TypeTag returns the tag identifying the kind of email (e.g. "welcome", "verification", "default")
+func TypeTag(emailType string) Tag {
+	return Tag{Name: "type", Value: emailType}
+}
+
+// UserTag returns the tag tying an email to the user it was sent for
+func UserTag(userID string) Tag {
+	return Tag{Name: "user_id", Value: sanitizeTagValue(userID)}
+}
+
+// sanitizeTagValue replaces the characters Resend rejects in tag values (only ASCII letters, digits, _ and - are allowed)
+func sanitizeTagValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, value)
+}
+
+// SendOptions holds optional per-message settings for a Resend request
+type SendOptions struct {
+	// TrackOpens and TrackClicks override the service defaults when set
+	TrackOpens  *bool
+	TrackClicks *bool
+
+	// Tags are attached to the email for segmentation in the Resend dashboard
+	Tags []Tag
+
+	// From overrides the configured sender address, keeping the configured display name
+	From string
+
+	// ScheduledAt asks Resend to deliver the email at this time instead of immediately
+	ScheduledAt time.Time
+
+	// Text is sent as the plain text alternative of the HTML body
+	Text string
+
+	// Attachments are sent with the email, counting towards the maximum email size
+	Attachments []Attachment
+
+	// Headers are added to the email, e.g. X-Entity-Ref-ID to control threading
+	Headers map[string]string
 }
 
 // EmailResponse represents the Resend API response
@@ -37,123 +187,248 @@ type EmailResponse struct {
 	ID string `json:"id"`
 }
 
+// newResendHTTPClient creates the HTTP client for the Resend API with the configured timeouts
+func newResendHTTPClient(cfg ResendConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   durationOrDefault(cfg.DialTimeout, DefaultResendDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = durationOrDefault(cfg.TLSHandshakeTimeout, DefaultResendTLSHandshakeTimeout)
+	transport.ResponseHeaderTimeout = durationOrDefault(cfg.ResponseHeaderTimeout, DefaultResendResponseHeaderTimeout)
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+}
+
+// durationOrDefault returns d, or fallback when d is not positive
+func durationOrDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
 // SendEmail sends an email using the Resend API
 func (r *ResendService) SendEmail(to, subject, body string) error {
-	// Add delay to avoid rate limit (max 2 requests per second)
-	time.Sleep(600 * time.Millisecond)
+	return r.SendEmailContext(context.Background(), to, subject, body)
+}
+
+// SendText sends a plain text email using the Resend API
+func (r *ResendService) SendText(to, subject, body string) error {
+	_, err := r.doSend(context.Background(), r.newRequest(to, subject, "", SendOptions{Text: body}))
+	return err
+}
+
+// SendEmailContext sends a plain text email using the Resend API, aborting when ctx is canceled
+func (r *ResendService) SendEmailContext(ctx context.Context, to, subject, body string) error {
+	return r.SendEmailWithOptions(ctx, to, subject, "", SendOptions{Text: body})
+}
+
+// SendEmailWithHTML sends an email with HTML content using the Resend API
+func (r *ResendService) SendEmailWithHTML(to, subject, htmlBody string) error {
+	return r.SendEmailWithHTMLContext(context.Background(), to, subject, htmlBody)
+}
+
+// SendEmailWithHTMLContext sends an email with HTML content using the Resend API, aborting when ctx is canceled
+func (r *ResendService) SendEmailWithHTMLContext(ctx context.Context, to, subject, htmlBody string) error {
+	return r.SendEmailWithOptions(ctx, to, subject, htmlBody, SendOptions{})
+}
+
+// SendResult describes an email accepted by Resend
+type SendResult struct {
+	ID          string          // Resend message ID, used to correlate webhook events
+	ProviderRaw json.RawMessage // Raw Resend response body
+}
+
+// SendEmailWithOptions sends an email with HTML content and per-message options using the Resend API
+func (r *ResendService) SendEmailWithOptions(ctx context.Context, to, subject, htmlBody string, opts SendOptions) error {
+	_, err := r.SendEmailWithResult(ctx, to, subject, htmlBody, opts)
+	return err
+}
+
+// SendEmailWithResult sends an email with HTML content and per-message options, returning the Resend message ID.
+// Dry runs return an empty result.
+func (r *ResendService) SendEmailWithResult(ctx context.Context, to, subject, htmlBody string, opts SendOptions) (SendResult, error) {
+	return r.doSend(ctx, r.newRequest(to, subject, htmlBody, opts))
+}
+
+// newRequest builds the Resend request for an email, applying the per-message options over the service defaults
+func (r *ResendService) newRequest(to, subject, htmlBody string, opts SendOptions) EmailRequest {
+	emailReq := EmailRequest{
+		From:        r.fromAddress(opts.From),
+		To:          []string{to},
+		Subject:     subject,
+		HTML:        htmlBody,
+		Text:        opts.Text,
+		TrackOpens:  boolOrDefault(opts.TrackOpens, r.trackOpens),
+		TrackClicks: boolOrDefault(opts.TrackClicks, r.trackClicks),
+		Tags:        opts.Tags,
+		Attachments: opts.Attachments,
+		Headers:     opts.Headers,
+	}
+	if !opts.ScheduledAt.IsZero() {
+		emailReq.ScheduledAt = opts.ScheduledAt.UTC().Format(time.RFC3339)
+	}
+	return emailReq
+}
+
+// doSend validates and posts emailReq to the Resend API, shared by the text and HTML paths.
+// Dry runs only log the email and return an empty result.
+func (r *ResendService) doSend(ctx context.Context, emailReq EmailRequest) (SendResult, error) {
+	// Reject invalid emails locally before waiting on the rate limit
+	if err := r.checkSize(emailReq); err != nil {
+		return SendResult{}, err
+	}
+	if err := checkHeaders(emailReq.Headers); err != nil {
+		return SendResult{}, err
+	}
+
+	to := strings.Join(emailReq.To, ",")
+	if r.dryRun {
+		r.logDryRun(ctx, to, emailReq.Subject, cmp.Or(emailReq.HTML, emailReq.Text))
+		return SendResult{}, nil
+	}
 
 	if r.apiKey == "" {
-		return fmt.Errorf("RESEND_API_KEY not configured")
+		return SendResult{}, fmt.Errorf("RESEND_API_KEY not configured")
 	}
 
 	if r.fromEmail == "" {
-		return fmt.Errorf("RESEND_FROM_EMAIL not configured")
+		return SendResult{}, fmt.Errorf("RESEND_FROM_EMAIL not configured")
 	}
 
-	// Prepare request payload
-	emailReq := EmailRequest{
-		From:    r.fromEmail,
-		To:      []string{to},
-		Subject: subject,
-		Text:    body,
+	// Add delay to avoid rate limit (max 2 requests per second)
+	if err := sleepContext(ctx, 600*time.Millisecond); err != nil {
+		return SendResult{}, err
 	}
 
 	jsonData, err := json.Marshal(emailReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal email request: %w", err)
+		return SendResult{}, fmt.Errorf("failed to marshal email request: %w", err)
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", "https://api.resend.com/emails", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/emails", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return SendResult{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+r.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return SendResult{}, fmt.Errorf("failed to send email: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		// Read response body for error details
-		var respBody bytes.Buffer
-		respBody.ReadFrom(resp.Body)
+		// Read the error response body for more details
+		var errorBody bytes.Buffer
+		errorBody.ReadFrom(resp.Body)
+		apiErr := parseResendError(resp.StatusCode, errorBody.Bytes())
+		apiErr.RetryAfterDelay = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return SendResult{}, apiErr
+	}
 
-		return fmt.Errorf("resend API returned status %d - Response: %s", resp.StatusCode, respBody.String())
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SendResult{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	var emailResp EmailResponse
-	if err := json.NewDecoder(resp.Body).Decode(&emailResp); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(raw, &emailResp); err != nil {
+		return SendResult{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	fmt.Printf("Email enviado com sucesso! ID: %s\n", emailResp.ID)
-	return nil
+	status := "sent"
+	if emailReq.ScheduledAt != "" {
+		status = "scheduled"
+	}
+	r.log(ctx).Info("Email sent", "msg_id", emailResp.ID, "recipient", logging.Recipient(to), "status", status)
+	return SendResult{ID: emailResp.ID, ProviderRaw: raw}, nil
 }
 
-// SendEmailWithHTML sends an email with HTML content using the Resend API
-func (r *ResendService) SendEmailWithHTML(to, subject, htmlBody string) error {
-	// Add delay to avoid rate limit (max 2 requests per second)
-	time.Sleep(600 * time.Millisecond)
-
-	if r.apiKey == "" {
-		return fmt.Errorf("RESEND_API_KEY not configured")
+// checkSize returns ErrEmailTooLarge when the HTML, text and attachments exceed the maximum email size
+func (r *ResendService) checkSize(emailReq EmailRequest) error {
+	size := len(emailReq.HTML) + len(emailReq.Text)
+	for _, attachment := range emailReq.Attachments {
+		size += len(attachment.Content)
 	}
 
-	if r.fromEmail == "" {
-		return fmt.Errorf("RESEND_FROM_EMAIL not configured")
+	if size > r.maxEmailSize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrEmailTooLarge, size, r.maxEmailSize)
 	}
+	return nil
+}
 
-	// Prepare request payload with HTML
-	emailReq := EmailRequest{
-		From:    r.fromEmail,
-		To:      []string{to},
-		Subject: subject,
-		HTML:    htmlBody,
+// checkHeaders returns ErrInvalidHeader when a custom header name is not a valid HTTP field name
+func checkHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("%w: name %q", ErrInvalidHeader, name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("%w: value of %s", ErrInvalidHeader, name)
+		}
 	}
+	return nil
+}
 
-	jsonData, err := json.Marshal(emailReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal email request: %w", err)
+// fromAddress returns the From header for address, falling back to the configured sender when empty
+func (r *ResendService) fromAddress(address string) string {
+	if address == "" {
+		address = r.fromEmail
 	}
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", "https://api.resend.com/emails", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if r.fromName == "" {
+		return address
 	}
+	return fmt.Sprintf("%s <%s>", r.fromName, address)
+}
 
-	req.Header.Set("Authorization", "Bearer "+r.apiKey)
-	req.Header.Set("Content-Type", "application/json")
+// sleepContext waits for d, returning early with the context error when ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	defer resp.Body.Close()
+}
 
-	// Check response
-	if resp.StatusCode != http.StatusOK {
-		// Read the error response body for more details
-		var errorBody bytes.Buffer
-		errorBody.ReadFrom(resp.Body)
-		return fmt.Errorf("resend API returned status %d: %s", resp.StatusCode, errorBody.String())
+// boolOrDefault returns the value of b, or fallback when b is nil
+func boolOrDefault(b *bool, fallback bool) bool {
+	if b == nil {
+		return fallback
 	}
+	return *b
+}
 
-	var emailResp EmailResponse
-	if err := json.NewDecoder(resp.Body).Decode(&emailResp); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+// log returns the configured logger, falling back to the default logger enriched with ctx
+func (r *ResendService) log(ctx context.Context) *slog.Logger {
+	if r.logger != nil {
+		return r.logger
 	}
+	return logging.FromContext(ctx)
+}
 
-	fmt.Printf("Email HTML enviado com sucesso! ID: %s\n", emailResp.ID)
-	return nil
+// logDryRun logs the email that would have been sent, hashing the body to keep logs small
+func (r *ResendService) logDryRun(ctx context.Context, to, subject, body string) {
+	hash := sha256.Sum256([]byte(body))
+	r.log(ctx).Info("Dry run: skipping email delivery",
+		"recipient", logging.Recipient(to),
+		"subject", subject,
+		"body_sha256", hex.EncodeToString(hash[:]),
+		"body_length", len(body),
+	)
 }
@@ -0,0 +1,674 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResendServiceSendPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     func(*ResendService) error
+		wantHTML string
+		wantText string
+	}{
+		{
+			name:     "SendText",
+			send:     func(r *ResendService) error { return r.SendText("ana@example.com", "Oi", "Olá") },
+			wantText: "Olá",
+		},
+		{
+			name: "SendEmailContext",
+			send: func(r *ResendService) error {
+				return r.SendEmailContext(context.Background(), "ana@example.com", "Oi", "Olá")
+			},
+			wantText: "Olá",
+		},
+		{
+			name: "SendEmailWithHTMLContext",
+			send: func(r *ResendService) error {
+				return r.SendEmailWithHTMLContext(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>")
+			},
+			wantHTML: "<p>Olá</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got EmailRequest
+			var auth string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.Write([]byte(`{"id":"re_1"}`))
+			}))
+			defer server.Close()
+
+			resend := NewResendService(ResendConfig{
+				APIKey:      "re_test",
+				FromEmail:   "no-reply@northfi.com.br",
+				FromName:    "NorthFi",
+				BaseURL:     server.URL,
+				TrackClicks: true,
+			})
+
+			if err := tt.send(resend); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+
+			if auth != "Bearer re_test" {
+				t.Errorf("authorization = %q, want the API key", auth)
+			}
+			if got.From != "NorthFi <no-reply@northfi.com.br>" || len(got.To) != 1 || got.To[0] != "ana@example.com" || got.Subject != "Oi" {
+				t.Errorf("request = %+v, want the configured sender, recipient and subject", got)
+			}
+			if got.HTML != tt.wantHTML || got.Text != tt.wantText {
+				t.Errorf("html = %q, text = %q, want %q and %q", got.HTML, got.Text, tt.wantHTML, tt.wantText)
+			}
+			if !got.TrackClicks {
+				t.Error("track_clicks = false, want the service default")
+			}
+		})
+	}
+}
+
+// rawRequestServer returns a Resend API stand-in decoding every request body into a generic map
+func rawRequestServer(t *testing.T, got *map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"id":"re_1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResendServiceTrackingFlags(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name       string
+		config     ResendConfig
+		opts       SendOptions
+		wantOpens  bool
+		wantClicks bool
+	}{
+		{name: "disabled by default"},
+		{name: "config defaults", config: ResendConfig{TrackOpens: true, TrackClicks: true}, wantOpens: true, wantClicks: true},
+		{name: "per-message opt in", opts: SendOptions{TrackOpens: &enabled}, wantOpens: true},
+		{
+			name:      "per-message opt out",
+			config:    ResendConfig{TrackOpens: true, TrackClicks: true},
+			opts:      SendOptions{TrackClicks: &disabled},
+			wantOpens: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := rawRequestServer(t, &got)
+
+			cfg := tt.config
+			cfg.APIKey, cfg.FromEmail, cfg.BaseURL = "re_test", "no-reply@northfi.com.br", server.URL
+			if err := NewResendService(cfg).SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", tt.opts); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+
+			if _, ok := got["track_opens"]; ok != tt.wantOpens {
+				t.Errorf("track_opens present = %v, want %v", ok, tt.wantOpens)
+			}
+			if _, ok := got["track_clicks"]; ok != tt.wantClicks {
+				t.Errorf("track_clicks present = %v, want %v", ok, tt.wantClicks)
+			}
+		})
+	}
+}
+
+func TestResendServiceTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []Tag
+		want []any
+	}{
+		{name: "no tags"},
+		{
+			name: "type and user tags",
+			tags: []Tag{TypeTag("welcome"), UserTag("user@42")},
+			want: []any{
+				map[string]any{"name": "type", "value": "welcome"},
+				map[string]any{"name": "user_id", "value": "user_42"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := rawRequestServer(t, &got)
+
+			resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL})
+			if err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{Tags: tt.tags}); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+
+			tags, ok := got["tags"]
+			if ok != (tt.want != nil) {
+				t.Fatalf("tags present = %v, want %v", ok, tt.want != nil)
+			}
+			if ok && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("tags = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestResendServiceBaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"id":"re_1"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{name: "default endpoint", want: DefaultResendBaseURL},
+		{name: "custom endpoint", baseURL: server.URL, want: server.URL},
+		{name: "trailing slash is trimmed", baseURL: server.URL + "/", want: server.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewResendService(ResendConfig{BaseURL: tt.baseURL}).baseURL; got != tt.want {
+				t.Errorf("baseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL + "/"})
+	if err := resend.SendEmailWithHTMLContext(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>"); err != nil {
+		t.Fatalf("SendEmailWithHTMLContext failed: %v", err)
+	}
+	if path != "/emails" {
+		t.Errorf("request path = %q, want /emails", path)
+	}
+}
+
+func TestResendServiceSendEmailWithResult(t *testing.T) {
+	const response = `{"id":"49a3999c-0ce1-4ea6-ab68-afcd6dc2e794"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL})
+	result, err := resend.SendEmailWithResult(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{})
+	if err != nil {
+		t.Fatalf("SendEmailWithResult failed: %v", err)
+	}
+
+	if result.ID != "49a3999c-0ce1-4ea6-ab68-afcd6dc2e794" {
+		t.Errorf("ID = %q, want the Resend message ID", result.ID)
+	}
+	if string(result.ProviderRaw) != response {
+		t.Errorf("ProviderRaw = %s, want the raw response %s", result.ProviderRaw, response)
+	}
+}
+
+func TestResendServiceScheduledAt(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	tests := []struct {
+		name        string
+		scheduledAt time.Time
+		want        string
+	}{
+		{name: "not scheduled"},
+		{name: "utc", scheduledAt: time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC), want: "2026-01-02T09:00:00Z"},
+		{name: "converted to utc", scheduledAt: time.Date(2026, 1, 2, 9, 0, 0, 0, saoPaulo), want: "2026-01-02T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := rawRequestServer(t, &got)
+
+			resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL})
+			if err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{ScheduledAt: tt.scheduledAt}); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+
+			value, ok := got["scheduled_at"]
+			if ok != (tt.want != "") {
+				t.Fatalf("scheduled_at present = %v, want %v", ok, tt.want != "")
+			}
+			if ok && value != tt.want {
+				t.Errorf("scheduled_at = %v, want %s", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestResendServiceFromAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		fromName string
+		address  string
+		want     string
+	}{
+		{name: "bare configured sender", want: "no-reply@northfi.com.br"},
+		{name: "configured sender with a display name", fromName: "NorthFi", want: "NorthFi <no-reply@northfi.com.br>"},
+		{name: "per-message sender", address: "hello@northfi.com.br", want: "hello@northfi.com.br"},
+		{name: "per-message sender with a display name", fromName: "NorthFi", address: "hello@northfi.com.br", want: "NorthFi <hello@northfi.com.br>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resend := NewResendService(ResendConfig{FromEmail: "no-reply@northfi.com.br", FromName: tt.fromName})
+			if got := resend.fromAddress(tt.address); got != tt.want {
+				t.Errorf("fromAddress(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResendServiceSendEmailWithOptions(t *testing.T) {
+	scheduledAt := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	opts := SendOptions{
+		From:        "hello@northfi.com.br",
+		Tags:        []Tag{TypeTag("default")},
+		Headers:     map[string]string{"X-Entity-Ref-ID": "42"},
+		ScheduledAt: scheduledAt,
+	}
+
+	tests := []struct {
+		name     string
+		html     string
+		text     string
+		wantHTML string
+	}{
+		{name: "html", html: "<p>Olá</p>", text: "Olá", wantHTML: "<p>Olá</p>"},
+		{name: "text only", text: "Olá"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got EmailRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.Write([]byte(`{"id":"re_1"}`))
+			}))
+			defer server.Close()
+
+			resend := NewResendService(ResendConfig{
+				APIKey:    "re_test",
+				FromEmail: "no-reply@northfi.com.br",
+				FromName:  "NorthFi",
+				BaseURL:   server.URL,
+			})
+
+			opts := opts
+			opts.Text = tt.text
+			if err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", tt.html, opts); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+
+			if got.HTML != tt.wantHTML || got.Text != tt.text {
+				t.Errorf("html = %q, text = %q, want %q and %q", got.HTML, got.Text, tt.wantHTML, tt.text)
+			}
+			if got.From != "NorthFi <hello@northfi.com.br>" {
+				t.Errorf("from = %q, want the per-message sender", got.From)
+			}
+			if got.ScheduledAt != "2026-01-02T09:00:00Z" {
+				t.Errorf("scheduled_at = %q, want 2026-01-02T09:00:00Z", got.ScheduledAt)
+			}
+			if len(got.Tags) != 1 || got.Headers["X-Entity-Ref-ID"] != "42" {
+				t.Errorf("tags = %v, headers = %v, want the per-message tags and headers", got.Tags, got.Headers)
+			}
+		})
+	}
+}
+
+func TestResendServiceMaxEmailSize(t *testing.T) {
+	const limit = 1024
+	html := strings.Repeat("a", 24)
+
+	tests := []struct {
+		name        string
+		attachments [][]byte
+		wantErr     error
+	}{
+		{name: "no attachments"},
+		{name: "just under the limit", attachments: [][]byte{make([]byte, 500), make([]byte, limit-len(html)-500)}},
+		{name: "just over the limit", attachments: [][]byte{make([]byte, 500), make([]byte, limit-len(html)-499)}, wantErr: ErrEmailTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resend := NewResendService(ResendConfig{
+				FromEmail:    "no-reply@northfi.com.br",
+				DryRun:       true,
+				MaxEmailSize: limit,
+				Logger:       discardLogger,
+			})
+
+			var opts SendOptions
+			for i, content := range tt.attachments {
+				opts.Attachments = append(opts.Attachments, Attachment{Filename: fmt.Sprintf("file-%d.pdf", i), Content: content})
+			}
+
+			err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", html, opts)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResendServiceSkipsRateLimitDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		dryRun  bool
+		html    string
+		opts    SendOptions
+		wantErr error
+	}{
+		{name: "dry run", dryRun: true, html: "<p>Olá</p>"},
+		{name: "too large", html: strings.Repeat("a", 64), wantErr: ErrEmailTooLarge},
+		{name: "invalid header", html: "<p>Olá</p>", opts: SendOptions{Headers: map[string]string{"Bad Name": "1"}}, wantErr: ErrInvalidHeader},
+		{name: "too large in dry run", dryRun: true, html: strings.Repeat("a", 64), wantErr: ErrEmailTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resend := NewResendService(ResendConfig{
+				APIKey:       "re_test",
+				FromEmail:    "no-reply@northfi.com.br",
+				DryRun:       tt.dryRun,
+				MaxEmailSize: 32,
+				BaseURL:      "http://127.0.0.1:0",
+				Logger:       discardLogger,
+			})
+
+			start := time.Now()
+			err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", tt.html, tt.opts)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed >= 600*time.Millisecond {
+				t.Errorf("took %v, want no rate limit delay", elapsed)
+			}
+		})
+	}
+}
+
+func TestResendServiceCancelMidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(r *ResendService, ctx context.Context) error
+	}{
+		{
+			name: "html",
+			send: func(r *ResendService, ctx context.Context) error {
+				return r.SendEmailWithHTMLContext(ctx, "ana@example.com", "Oi", "<p>Olá</p>")
+			},
+		},
+		{
+			name: "text",
+			send: func(r *ResendService, ctx context.Context) error {
+				return r.SendEmailContext(ctx, "ana@example.com", "Oi", "Olá")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arrived := make(chan struct{})
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(arrived)
+				// Hold the response until the client gives up
+				select {
+				case <-r.Context().Done():
+				case <-release:
+				}
+			}))
+			defer server.Close()
+			defer close(release)
+
+			resend := NewResendService(ResendConfig{
+				APIKey:    "re_test",
+				FromEmail: "no-reply@northfi.com.br",
+				BaseURL:   server.URL,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-arrived
+				cancel()
+			}()
+
+			done := make(chan error, 1)
+			go func() { done <- tt.send(resend, ctx) }()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("err = %v, want context.Canceled", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("send did not return after the context was canceled")
+			}
+		})
+	}
+}
+
+func TestResendServiceLogsMessageID(t *testing.T) {
+	const id = "49a3999c-0ce1-4ea6-ab68-afcd6dc2e794"
+
+	tests := []struct {
+		name       string
+		send       func(*ResendService) error
+		wantStatus string
+	}{
+		{
+			name: "plain text",
+			send: func(r *ResendService) error {
+				return r.SendEmailContext(context.Background(), "ana@example.com", "Oi", "Olá")
+			},
+			wantStatus: "sent",
+		},
+		{
+			name: "html",
+			send: func(r *ResendService) error {
+				_, err := r.SendEmailWithResult(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{})
+				return err
+			},
+			wantStatus: "sent",
+		},
+		{
+			name: "scheduled html",
+			send: func(r *ResendService) error {
+				_, err := r.SendEmailWithResult(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{ScheduledAt: time.Now().Add(time.Hour)})
+				return err
+			},
+			wantStatus: "scheduled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"id":%q}`, id)
+			}))
+			defer server.Close()
+
+			var logs bytes.Buffer
+			resend := NewResendService(ResendConfig{
+				APIKey:    "re_test",
+				FromEmail: "no-reply@northfi.com.br",
+				BaseURL:   server.URL,
+				Logger:    slog.New(slog.NewJSONHandler(&logs, nil)),
+			})
+			if err := tt.send(resend); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to decode the log entry %q: %v", logs.String(), err)
+			}
+			want := map[string]any{"msg_id": id, "recipient": "ana@example.com", "status": tt.wantStatus}
+			for key, value := range want {
+				if entry[key] != value {
+					t.Errorf("%s = %v, want %v", key, entry[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestResendServiceResponseHeaderTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		headerDelay time.Duration
+		wantErr     string
+	}{
+		{name: "headers in time", headerDelay: 0},
+		{name: "headers too late", headerDelay: 500 * time.Millisecond, wantErr: "timeout awaiting response headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.headerDelay)
+				w.Write([]byte(`{"id":"re_123"}`))
+			}))
+			defer server.Close()
+
+			resend := NewResendService(ResendConfig{
+				APIKey:                "re_test",
+				FromEmail:             "no-reply@northfi.com.br",
+				BaseURL:               server.URL,
+				ResponseHeaderTimeout: 100 * time.Millisecond,
+				Logger:                discardLogger,
+			})
+			err := resend.SendEmailContext(context.Background(), "ana@example.com", "Oi", "Olá")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SendEmailContext failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewResendHTTPClientTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        ResendConfig
+		wantTLS    time.Duration
+		wantHeader time.Duration
+		wantTotal  time.Duration
+	}{
+		{
+			name:       "defaults",
+			wantTLS:    DefaultResendTLSHandshakeTimeout,
+			wantHeader: DefaultResendResponseHeaderTimeout,
+		},
+		{
+			name:       "configured",
+			cfg:        ResendConfig{TLSHandshakeTimeout: time.Second, ResponseHeaderTimeout: 2 * time.Second, Timeout: time.Minute},
+			wantTLS:    time.Second,
+			wantHeader: 2 * time.Second,
+			wantTotal:  time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newResendHTTPClient(tt.cfg)
+			transport := client.Transport.(*http.Transport)
+
+			if transport.TLSHandshakeTimeout != tt.wantTLS {
+				t.Errorf("TLSHandshakeTimeout = %v, want %v", transport.TLSHandshakeTimeout, tt.wantTLS)
+			}
+			if transport.ResponseHeaderTimeout != tt.wantHeader {
+				t.Errorf("ResponseHeaderTimeout = %v, want %v", transport.ResponseHeaderTimeout, tt.wantHeader)
+			}
+			if client.Timeout != tt.wantTotal {
+				t.Errorf("Timeout = %v, want %v", client.Timeout, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestResendServiceCustomHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    map[string]any
+		wantErr error
+	}{
+		{name: "no headers"},
+		{
+			name:    "headers are sent under the headers key",
+			headers: map[string]string{"X-Entity-Ref-ID": "42", "List-Unsubscribe": "<mailto:sair@northfi.com.br>"},
+			want:    map[string]any{"X-Entity-Ref-ID": "42", "List-Unsubscribe": "<mailto:sair@northfi.com.br>"},
+		},
+		{name: "invalid name", headers: map[string]string{"X Entity": "42"}, wantErr: ErrInvalidHeader},
+		{name: "invalid value", headers: map[string]string{"X-Entity-Ref-ID": "42\r\nBcc: eve@example.com"}, wantErr: ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := rawRequestServer(t, &got)
+
+			resend := NewResendService(ResendConfig{APIKey: "re_test", FromEmail: "no-reply@northfi.com.br", BaseURL: server.URL, Logger: discardLogger})
+			err := resend.SendEmailWithOptions(context.Background(), "ana@example.com", "Oi", "<p>Olá</p>", SendOptions{Headers: tt.headers})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if got != nil {
+					t.Error("request sent to Resend despite an invalid header")
+				}
+				return
+			}
+
+			headers, ok := got["headers"]
+			if ok != (tt.want != nil) {
+				t.Fatalf("headers present = %v, want %v", ok, tt.want != nil)
+			}
+			if ok && !reflect.DeepEqual(headers, tt.want) {
+				t.Errorf("headers = %v, want %v", headers, tt.want)
+			}
+		})
+	}
+}
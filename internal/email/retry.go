@@ -3,20 +3,66 @@ package email
 import (
 	"context"
 	"log/slog"
-	"strings"
+	"math/rand/v2"
 	"time"
 )
 
-// IsWelcomeSubject checks if an email subject indicates a welcome email
-func IsWelcomeSubject(subject string) bool {
-	subjectLower := strings.ToLower(subject)
-	return strings.Contains(subjectLower, "bem-vindo") || strings.Contains(subjectLower, "welcome")
+// Clock abstracts time so retry delays can be skipped in tests
+type Clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// RealClock is a Clock backed by the system time
+type RealClock struct{}
+
+// Now returns the current system time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep waits for d, returning early with the context error when ctx is done
+func (RealClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleepContext(ctx, d)
 }
 
 // RetryConfig defines retry parameters
 type RetryConfig struct {
 	MaxAttempts int
 	Delay       time.Duration
+	Clock       Clock // Optional: defaults to RealClock
+
+	// MaxElapsed caps the total time spent across attempts and delays (0 disables the budget)
+	MaxElapsed time.Duration
+
+	// Jitter randomly shifts each delay by up to ±Jitter so workers don't retry in lockstep
+	Jitter time.Duration
+	Rand   *rand.Rand // Optional: source for the jitter, defaults to the global source
+}
+
+// clock returns the configured clock, falling back to the system time
+func (c RetryConfig) clock() Clock {
+	if c.Clock == nil {
+		return RealClock{}
+	}
+	return c.Clock
+}
+
+// JitterDelay returns delay shifted by a random amount in [-jitter, jitter], never below zero.
+// A nil rnd uses the global random source.
+func JitterDelay(delay, jitter time.Duration, rnd *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return delay
+	}
+
+	span := int64(2*jitter) + 1
+	var offset int64
+	if rnd != nil {
+		offset = rnd.Int64N(span)
+	} else {
+		offset = rand.Int64N(span)
+	}
+	return max(delay-jitter+time.Duration(offset), 0)
 }
 
 // DefaultRetryConfig returns standard retry configuration
@@ -27,32 +73,103 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
-// ExecuteWithRetry executes a function with retry logic
+// RetryOutcome describes how an operation run by ExecuteWithRetryResult ended
+type RetryOutcome struct {
+	Attempts  int   // Number of times fn was called
+	Succeeded bool  // Whether the last attempt succeeded
+	Aborted   bool  // Whether the context ended the retries, so the message must be redelivered rather than dropped
+	LastErr   error // Error of the last attempt, or the context error when retries were aborted
+}
+
+// Err returns nil when the operation succeeded, and the last error otherwise
+func (o RetryOutcome) Err() error {
+	if o.Succeeded {
+		return nil
+	}
+	return o.LastErr
+}
+
+// ExecuteWithRetry executes a function with retry logic. It returns nil once the attempts are exhausted so the
+// message is acknowledged, and only returns an error when ctx aborted the retries.
 func ExecuteWithRetry(ctx context.Context, config RetryConfig, fn func() error, logger *slog.Logger) error {
-	var lastErr error
+	outcome := retry(ctx, config, fn, logger)
+	if outcome.Aborted {
+		return outcome.LastErr
+	}
+	if !outcome.Succeeded {
+		logger.Error("All retry attempts failed", "max_attempts", config.MaxAttempts, "last_error", outcome.LastErr)
+	}
+	return nil
+}
+
+// ExecuteWithRetryResult executes a function with retry logic like ExecuteWithRetry, reporting the outcome
+func ExecuteWithRetryResult(ctx context.Context, config RetryConfig, fn func() error, logger *slog.Logger) RetryOutcome {
+	return retry(ctx, config, fn, logger)
+}
+
+// retry calls fn up to config.MaxAttempts times until it succeeds. It stops early on a permanent error
+// (see IsPermanentError), when the next delay would exceed config.MaxElapsed, or when ctx is done.
+// Delays honor the wait suggested by a rate-limited error over the jittered config.Delay.
+func retry(ctx context.Context, config RetryConfig, fn func() error, logger *slog.Logger) RetryOutcome {
+	var outcome RetryOutcome
+	clock := config.clock()
+	start := clock.Now()
 
 	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
 		attemptLogger := logger.With("attempt", attempt, "max_attempts", config.MaxAttempts)
-		attemptLogger.Info("Executing attempt")
+		attemptLogger.Info("Starting attempt")
 
+		outcome.Attempts = attempt
 		err := fn()
 		if err == nil {
-			attemptLogger.Info("Operation successful")
-			return nil
+			attemptLogger.Info("Operation completed successfully")
+			outcome.Succeeded = true
+			outcome.LastErr = nil
+			return outcome
 		}
 
-		lastErr = err
+		outcome.LastErr = err
 		attemptLogger.Error("Operation failed", "error", err)
 
-		// If this is not the last attempt, wait before retrying
-		if attempt < config.MaxAttempts {
-			attemptLogger.Info("Waiting before retry", "delay", config.Delay)
-			time.Sleep(config.Delay)
+		// Retrying cannot fix a request Resend rejected as invalid
+		if IsPermanentError(err) {
+			attemptLogger.Warn("Permanent error, skipping remaining retries")
+			return outcome
 		}
-	}
 
-	logger.Error("All retry attempts failed", "max_attempts", config.MaxAttempts, "last_error", lastErr)
-	// Return nil to acknowledge the message and remove it from queue
-	// Even though sending failed, we don't want to keep retrying indefinitely
-	return nil
+		// Stop retrying on shutdown so the message is redelivered instead of dropped
+		if ctx.Err() != nil {
+			attemptLogger.Warn("Context canceled, aborting retries", "error", ctx.Err())
+			outcome.Aborted = true
+			outcome.LastErr = ctx.Err()
+			return outcome
+		}
+
+		if attempt == config.MaxAttempts {
+			break
+		}
+
+		delay := JitterDelay(config.Delay, config.Jitter, config.Rand)
+		if suggested, ok := RetryAfter(err); ok {
+			delay = suggested
+		}
+
+		// Give up early rather than waiting past the retry budget
+		if elapsed := clock.Now().Sub(start); config.MaxElapsed > 0 && elapsed+delay > config.MaxElapsed {
+			attemptLogger.Warn("Retry budget exhausted, skipping remaining retries",
+				"elapsed", elapsed,
+				"max_elapsed", config.MaxElapsed,
+			)
+			return outcome
+		}
+
+		attemptLogger.Info("Waiting before retry", "delay", delay)
+		if err := clock.Sleep(ctx, delay); err != nil {
+			attemptLogger.Warn("Context canceled, aborting retries", "error", err)
+			outcome.Aborted = true
+			outcome.LastErr = err
+			return outcome
+		}
+	}
+	return outcome
 }
@@ -0,0 +1,205 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testClock advances its time on every Sleep instead of waiting
+type testClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+	return nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestExecuteWithRetryResult(t *testing.T) {
+	transient := errors.New("connection reset")
+	permanent := &ResendAPIError{StatusCode: http.StatusUnprocessableEntity, Name: "validation_error"}
+	rateLimited := &ResendAPIError{StatusCode: http.StatusTooManyRequests, RetryAfterDelay: 4 * time.Second}
+
+	tests := []struct {
+		name          string
+		errs          []error
+		config        RetryConfig
+		wantAttempts  int
+		wantSucceeded bool
+		wantSleeps    []time.Duration
+	}{
+		{
+			name:          "first attempt succeeds",
+			config:        RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts:  1,
+			wantSucceeded: true,
+		},
+		{
+			name:          "succeeds on the last attempt",
+			errs:          []error{transient, transient},
+			config:        RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts:  3,
+			wantSucceeded: true,
+			wantSleeps:    []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:         "every attempt fails",
+			errs:         []error{transient, transient, transient},
+			config:       RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 3,
+			wantSleeps:   []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:         "permanent error",
+			errs:         []error{permanent},
+			config:       RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 1,
+		},
+		{
+			name:         "too large is permanent",
+			errs:         []error{ErrEmailTooLarge},
+			config:       RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 1,
+		},
+		{
+			name:          "Retry-After overrides the delay",
+			errs:          []error{rateLimited},
+			config:        RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts:  2,
+			wantSucceeded: true,
+			wantSleeps:    []time.Duration{4 * time.Second},
+		},
+		{
+			name:         "budget exhausted",
+			errs:         []error{transient, transient, transient, transient},
+			config:       RetryConfig{MaxAttempts: 4, Delay: 2 * time.Second, MaxElapsed: 5 * time.Second},
+			wantAttempts: 3,
+			wantSleeps:   []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+			tt.config.Clock = clock
+			calls := 0
+
+			outcome := ExecuteWithRetryResult(context.Background(), tt.config, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}, discardLogger)
+
+			if outcome.Attempts != tt.wantAttempts || calls != tt.wantAttempts {
+				t.Errorf("attempts = %d (calls %d), want %d", outcome.Attempts, calls, tt.wantAttempts)
+			}
+			if outcome.Succeeded != tt.wantSucceeded {
+				t.Errorf("succeeded = %v, want %v", outcome.Succeeded, tt.wantSucceeded)
+			}
+			if outcome.Aborted {
+				t.Error("outcome aborted without a canceled context")
+			}
+			if (outcome.Err() == nil) != tt.wantSucceeded {
+				t.Errorf("Err() = %v with succeeded %v", outcome.Err(), tt.wantSucceeded)
+			}
+			if len(clock.sleeps) != len(tt.wantSleeps) {
+				t.Fatalf("sleeps = %v, want %v", clock.sleeps, tt.wantSleeps)
+			}
+			for i, want := range tt.wantSleeps {
+				if clock.sleeps[i] != want {
+					t.Errorf("sleep[%d] = %v, want %v", i, clock.sleeps[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteWithRetryResultCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	outcome := ExecuteWithRetryResult(ctx, RetryConfig{MaxAttempts: 3, Delay: time.Second, Clock: &testClock{}}, func() error {
+		return errors.New("connection reset")
+	}, discardLogger)
+
+	if !outcome.Aborted || !errors.Is(outcome.Err(), context.Canceled) || outcome.Attempts != 1 {
+		t.Errorf("outcome = %+v, want aborted after 1 attempt with context.Canceled", outcome)
+	}
+}
+
+func TestExecuteWithRetry(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", ctx: context.Background(), wantCalls: 1},
+		{name: "exhausted attempts are acknowledged", ctx: context.Background(), err: errors.New("connection reset"), wantCalls: 3},
+		{name: "canceled context is reported", ctx: canceled, err: errors.New("connection reset"), wantCalls: 1, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := ExecuteWithRetry(tt.ctx, RetryConfig{MaxAttempts: 3, Delay: time.Second, Clock: &testClock{}}, func() error {
+				calls++
+				return tt.err
+			}, discardLogger)
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestJitterDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		delay  time.Duration
+		jitter time.Duration
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "no jitter", delay: time.Second, min: time.Second, max: time.Second},
+		{name: "within bounds", delay: time.Second, jitter: 200 * time.Millisecond, min: 800 * time.Millisecond, max: 1200 * time.Millisecond},
+		{name: "never negative", delay: 100 * time.Millisecond, jitter: time.Second, min: 0, max: 1100 * time.Millisecond},
+	}
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				got := JitterDelay(tt.delay, tt.jitter, rnd)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("JitterDelay = %v, want within [%v, %v]", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package email
+
+import (
+	"html/template"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Body modes controlling how HTML in a payload body is rendered by the default template
+const (
+	// BodyModePlain escapes every tag so the body is shown as written (default)
+	BodyModePlain = "plain"
+
+	// BodyModeRich keeps the allowlisted tags and attributes, stripping everything else
+	BodyModeRich = "rich"
+)
+
+// allowedTags maps each tag kept by SanitizeHTML to the attributes it may carry
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"em":         nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"i":          nil,
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"strong":     nil,
+	"u":          nil,
+	"ul":         nil,
+}
+
+// droppedTags are removed together with their content
+var droppedTags = map[string]bool{
+	"head":     true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+}
+
+// SanitizeHTML keeps the allowlisted tags and attributes of body and escapes its text, dropping
+// every other tag. Scripts, styles and similar elements are removed along with their content.
+func SanitizeHTML(body string) template.HTML {
+	var out strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	dropDepth := 0
+	var open []string // Allowlisted tags still open, closed at the end so the markup stays balanced
+
+	for {
+		tokenType := tokenizer.Next()
+		// The end of the input, or a tokenizer failure whose unparsed remainder is dropped
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if dropDepth == 0 {
+				out.WriteString(html.EscapeString(token.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.Data] {
+				if tokenType == html.StartTagToken {
+					dropDepth++
+				}
+				continue
+			}
+			if attrs, ok := allowedTags[token.Data]; ok && dropDepth == 0 {
+				out.WriteString(openTag(token, attrs))
+				if tokenType == html.StartTagToken && token.Data != "br" {
+					open = append(open, token.Data)
+				}
+			}
+		case html.EndTagToken:
+			if droppedTags[token.Data] {
+				dropDepth = max(dropDepth-1, 0)
+				continue
+			}
+			// Close the innermost matching tag and the ones nested in it; stray end tags are dropped
+			if i := lastIndex(open, token.Data); i >= 0 && dropDepth == 0 {
+				for len(open) > i {
+					out.WriteString("</" + open[len(open)-1] + ">")
+					open = open[:len(open)-1]
+				}
+			}
+		}
+	}
+	for len(open) > 0 {
+		out.WriteString("</" + open[len(open)-1] + ">")
+		open = open[:len(open)-1]
+	}
+
+	// Only allowlisted markup is written, and every text and attribute value is escaped
+	return template.HTML(out.String())
+}
+
+// openTag renders the start tag of token, keeping only the allowed attributes with safe values
+func openTag(token html.Token, allowed []string) string {
+	var b strings.Builder
+	b.WriteString("<" + token.Data)
+	for _, attr := range token.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowed, attr.Key) {
+			continue
+		}
+		if attr.Key == "href" && !isSafeLink(attr.Val) {
+			continue
+		}
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// lastIndex returns the index of the last occurrence of value in values, or -1 if it is not present
+func lastIndex(values []string, value string) int {
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,33 @@
+package email
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "allowed tags are kept", body: "<p>Olá <strong>Ana</strong></p>", want: "<p>Olá <strong>Ana</strong></p>"},
+		{name: "other tags are dropped", body: `<div class="x"><span>oi</span></div>`, want: "oi"},
+		{name: "script content is removed", body: "<p>a</p><script>alert(1)</script><p>b</p>", want: "<p>a</p><p>b</p>"},
+		{name: "content of dropped tags is removed", body: "<object><p>x</p><b>y</b></object>z", want: "z"},
+		{name: "disallowed attributes are removed", body: `<p onclick="x" style="color:red">oi</p>`, want: "<p>oi</p>"},
+		{name: "safe link", body: `<a href="https://northfi.com.br" title="site">site</a>`, want: `<a href="https://northfi.com.br" title="site">site</a>`},
+		{name: "javascript link loses its href", body: `<a href="javascript:alert(1)">x</a>`, want: "<a>x</a>"},
+		{name: "unclosed tags are closed", body: "<ul><li>um", want: "<ul><li>um</li></ul>"},
+		{name: "stray end tags are dropped", body: "oi</p></b>", want: "oi"},
+		{name: "mismatched end tag closes nested tags", body: "<p><b>oi</p>", want: "<p><b>oi</b></p>"},
+		{name: "line break has no end tag", body: "a<br>b", want: "a<br>b"},
+		{name: "text is escaped", body: "a &lt; b & c", want: "a &lt; b &amp; c"},
+		{name: "attribute values are escaped", body: `<a title="&quot;><script>">x</a>`, want: `<a title="&#34;&gt;&lt;script&gt;">x</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(SanitizeHTML(tt.body)); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
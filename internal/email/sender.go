@@ -0,0 +1,104 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"go_integration/internal/logging"
+)
+
+// Sender delivers rendered emails to a recipient
+type Sender interface {
+	SendText(to, subject, body string) error
+	SendEmailContext(ctx context.Context, to, subject, body string) error
+	SendEmailWithHTMLContext(ctx context.Context, to, subject, htmlBody string) error
+	SendEmailWithOptions(ctx context.Context, to, subject, htmlBody string, opts SendOptions) error
+}
+
+// AllowlistSender restricts delivery to an allowlist of recipients, mirroring Resend's test mode
+type AllowlistSender struct {
+	next       Sender
+	allowed    map[string]struct{}
+	redirectTo string
+}
+
+// NewAllowlistSender wraps a Sender so that recipients outside the allowlist are redirected
+// to redirectTo, or dropped when redirectTo is empty
+func NewAllowlistSender(next Sender, allowed []string, redirectTo string) *AllowlistSender {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, addr := range allowed {
+		if addr = normalizeAddress(addr); addr != "" {
+			allowedSet[addr] = struct{}{}
+		}
+	}
+
+	return &AllowlistSender{
+		next:       next,
+		allowed:    allowedSet,
+		redirectTo: strings.TrimSpace(redirectTo),
+	}
+}
+
+// SendText sends the text email if the recipient is allowed, otherwise redirects or drops it
+func (s *AllowlistSender) SendText(to, subject, body string) error {
+	recipient, ok := s.resolve(to, subject)
+	if !ok {
+		return nil
+	}
+	return s.next.SendText(recipient, subject, body)
+}
+
+// SendEmailContext sends the text email if the recipient is allowed, otherwise redirects or drops it
+func (s *AllowlistSender) SendEmailContext(ctx context.Context, to, subject, body string) error {
+	recipient, ok := s.resolve(to, subject)
+	if !ok {
+		return nil
+	}
+	return s.next.SendEmailContext(ctx, recipient, subject, body)
+}
+
+// SendEmailWithHTMLContext sends the email if the recipient is allowed, otherwise redirects or drops it
+func (s *AllowlistSender) SendEmailWithHTMLContext(ctx context.Context, to, subject, htmlBody string) error {
+	recipient, ok := s.resolve(to, subject)
+	if !ok {
+		return nil
+	}
+	return s.next.SendEmailWithHTMLContext(ctx, recipient, subject, htmlBody)
+}
+
+// SendEmailWithOptions sends the email if the recipient is allowed, otherwise redirects or drops it
+func (s *AllowlistSender) SendEmailWithOptions(ctx context.Context, to, subject, htmlBody string, opts SendOptions) error {
+	recipient, ok := s.resolve(to, subject)
+	if !ok {
+		return nil
+	}
+	return s.next.SendEmailWithOptions(ctx, recipient, subject, htmlBody, opts)
+}
+
+// resolve returns the address to deliver to, or false when the email must be dropped
+func (s *AllowlistSender) resolve(to, subject string) (string, bool) {
+	if _, ok := s.allowed[normalizeAddress(to)]; ok {
+		return to, true
+	}
+
+	if s.redirectTo != "" {
+		slog.Warn("Recipient not in allowlist, redirecting email",
+			"recipient", logging.Recipient(to),
+			"redirect_to", logging.Recipient(s.redirectTo),
+			"subject", subject,
+		)
+		return s.redirectTo, true
+	}
+
+	slog.Warn("Recipient not in allowlist, dropping email",
+		"recipient", logging.Recipient(to),
+		"subject", subject,
+	)
+	return "", false
+}
+
+// normalizeAddress trims and lowercases an email address for comparison
+func normalizeAddress(addr string) string {
+	return strings.ToLower(strings.TrimSpace(addr))
+}
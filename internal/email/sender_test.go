@@ -0,0 +1,160 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"go_integration/internal/logging"
+)
+
+// recordingSender records the recipient of every email it is asked to send
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []sentEmail
+}
+
+// sentEmail is an email captured by recordingSender
+type sentEmail struct {
+	To, Subject, HTML string
+	Opts              SendOptions
+}
+
+func (s *recordingSender) record(to, subject, htmlBody string, opts SendOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, sentEmail{To: to, Subject: subject, HTML: htmlBody, Opts: opts})
+	return nil
+}
+
+func (s *recordingSender) SendText(to, subject, body string) error {
+	return s.record(to, subject, "", SendOptions{Text: body})
+}
+
+func (s *recordingSender) SendEmailContext(_ context.Context, to, subject, body string) error {
+	return s.record(to, subject, "", SendOptions{Text: body})
+}
+
+func (s *recordingSender) SendEmailWithHTMLContext(_ context.Context, to, subject, htmlBody string) error {
+	return s.record(to, subject, htmlBody, SendOptions{})
+}
+
+func (s *recordingSender) SendEmailWithOptions(_ context.Context, to, subject, htmlBody string, opts SendOptions) error {
+	return s.record(to, subject, htmlBody, opts)
+}
+
+func (s *recordingSender) recipients() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipients := make([]string, len(s.sent))
+	for i, email := range s.sent {
+		recipients[i] = email.To
+	}
+	return recipients
+}
+
+func TestSuppressionSender(t *testing.T) {
+	tests := []struct {
+		name string
+		to   string
+		want []string
+	}{
+		{name: "normal recipient passes", to: "ana@example.com", want: []string{"ana@example.com"}},
+		{name: "suppressed recipient is skipped", to: "bounced@example.com", want: nil},
+		{name: "suppression ignores case and spaces", to: "  Bounced@Example.com ", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingSender{}
+			sender := NewSuppressionSender(next, NewMemorySuppressionList("bounced@example.com"))
+
+			if err := sender.SendEmailWithOptions(context.Background(), tt.to, "Oi", "<p>Olá</p>", SendOptions{}); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+			if err := sender.SendEmailContext(context.Background(), tt.to, "Oi", "Olá"); err != nil {
+				t.Fatalf("SendEmailContext failed: %v", err)
+			}
+
+			got := next.recipients()
+			if len(got) != 2*len(tt.want) {
+				t.Fatalf("sent to %v, want %v twice", got, tt.want)
+			}
+			for _, to := range got {
+				if to != tt.want[0] {
+					t.Errorf("sent to %q, want %q", to, tt.want[0])
+				}
+			}
+		})
+	}
+}
+
+func TestSuppressionListAdd(t *testing.T) {
+	list := NewMemorySuppressionList()
+	ctx := context.Background()
+
+	if err := list.Add(ctx, " "); err == nil {
+		t.Error("expected an error when suppressing an empty address")
+	}
+	if err := list.Add(ctx, "Ana@Example.com"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if ok, _ := list.Contains(ctx, "ana@example.com"); !ok {
+		t.Error("added address is not suppressed")
+	}
+}
+
+func TestAllowlistSender(t *testing.T) {
+	tests := []struct {
+		name       string
+		redirectTo string
+		to         string
+		want       []string
+	}{
+		{name: "allowed recipient", to: "dev@example.com", want: []string{"dev@example.com"}},
+		{name: "dropped recipient", to: "client@example.com", want: nil},
+		{name: "redirected recipient", redirectTo: "sandbox@example.com", to: "client@example.com", want: []string{"sandbox@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingSender{}
+			sender := NewAllowlistSender(next, []string{"dev@example.com"}, tt.redirectTo)
+
+			if err := sender.SendEmailWithOptions(context.Background(), tt.to, "Oi", "<p>Olá</p>", SendOptions{}); err != nil {
+				t.Fatalf("SendEmailWithOptions failed: %v", err)
+			}
+
+			got := next.recipients()
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Errorf("sent to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistSenderRedactsRedirect(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer logging.SetRedactEmails(false)
+	logging.SetRedactEmails(true)
+
+	sender := NewAllowlistSender(&recordingSender{}, []string{"dev@example.com"}, "sandbox@example.com")
+	if err := sender.SendEmailWithOptions(context.Background(), "client@example.com", "Oi", "<p>Olá</p>", SendOptions{}); err != nil {
+		t.Fatalf("SendEmailWithOptions failed: %v", err)
+	}
+
+	for _, address := range []string{"client@example.com", "sandbox@example.com"} {
+		if strings.Contains(logs.String(), address) {
+			t.Errorf("log contains %s in plain text: %s", address, logs.String())
+		}
+	}
+	if !strings.Contains(logs.String(), "redirect_to=s***@example.com") {
+		t.Errorf("log is missing the redacted redirect address: %s", logs.String())
+	}
+}
@@ -2,10 +2,17 @@ package email
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
+	"go_integration/internal/audit"
+	"go_integration/internal/logging"
 	"go_integration/internal/models"
+	ipubsub "go_integration/internal/pubsub"
 
 	"cloud.google.com/go/pubsub"
 )
@@ -20,52 +27,217 @@ const (
 
 // Service handles email-related operations
 type Service struct {
-	emailTopic        *pubsub.Topic
-	verificationTopic *pubsub.Topic
+	emailPublisher        ipubsub.Publisher
+	verificationPublisher ipubsub.Publisher
+	welcomePublisher      ipubsub.Publisher
+	outbox                Outbox
+	defaultSubject        string
+	verifications         VerificationStore
+	auditLogger           audit.Logger
+
+	// Emails scheduled in the future are held on the delay topic, then released to delayTarget
+	delayPublisher ipubsub.Publisher
+	delayTarget    string
 }
 
 // NewService creates a new email service
-func NewService(emailTopic *pubsub.Topic) *Service {
+func NewService(emailPublisher ipubsub.Publisher) *Service {
 	return &Service{
-		emailTopic: emailTopic,
+		emailPublisher: emailPublisher,
 	}
 }
 
 // NewServiceWithVerification creates a new email service with verification support
-func NewServiceWithVerification(emailTopic, verificationTopic *pubsub.Topic) *Service {
+func NewServiceWithVerification(emailPublisher, verificationPublisher ipubsub.Publisher) *Service {
+	return NewServiceWithTopics(emailPublisher, verificationPublisher, nil)
+}
+
+// NewServiceWithTopics creates a new email service with verification and welcome support
+func NewServiceWithTopics(emailPublisher, verificationPublisher, welcomePublisher ipubsub.Publisher) *Service {
 	return &Service{
-		emailTopic:        emailTopic,
-		verificationTopic: verificationTopic,
+		emailPublisher:        emailPublisher,
+		verificationPublisher: verificationPublisher,
+		welcomePublisher:      welcomePublisher,
 	}
 }
 
-// SendEmail publishes an email message to the topic
+// SetOutbox enables queuing email messages whose immediate publish fails (nil disables it)
+func (s *Service) SetOutbox(outbox Outbox) {
+	s.outbox = outbox
+}
+
+// SetVerificationStore records each published verification email so it can be resent (nil disables resending)
+func (s *Service) SetVerificationStore(store VerificationStore) {
+	s.verifications = store
+}
+
+// SetDelayPublisher holds emails scheduled in the future on the delay topic until their scheduled time,
+// when the worker republishes them to targetTopic (nil leaves the scheduling to Resend)
+func (s *Service) SetDelayPublisher(publisher ipubsub.Publisher, targetTopic string) {
+	s.delayPublisher = publisher
+	s.delayTarget = targetTopic
+}
+
+// SetAuditLogger records every successful publish in an audit trail (nil disables auditing)
+func (s *Service) SetAuditLogger(logger audit.Logger) {
+	s.auditLogger = logger
+}
+
+// audit records a successful publish when an audit logger is configured
+func (s *Service) audit(ctx context.Context, messageType, recipient, messageID string) {
+	if s.auditLogger != nil {
+		s.auditLogger.Record(ctx, audit.NewEntry(messageType, recipient, messageID))
+	}
+}
+
+// SetDefaultSubject makes SendEmail fill in subject for emails sent without one (empty keeps rejecting them)
+func (s *Service) SetDefaultSubject(subject string) {
+	s.defaultSubject = strings.TrimSpace(subject)
+}
+
+// SendEmail publishes an email message to the topic.
+// When the publish fails but the message is queued in the outbox, it returns the tracking ID with ErrPublishQueued.
 func (s *Service) SendEmail(ctx context.Context, payload *models.EmailPayload) (string, error) {
+	payload.Normalize()
+	if strings.TrimSpace(payload.Subject) == "" && s.defaultSubject != "" {
+		payload.Subject = s.defaultSubject
+	}
 	if err := payload.Validate(); err != nil {
 		return "", fmt.Errorf("invalid payload: %w", err)
 	}
 
+	if s.delayPublisher != nil && payload.ScheduledAt.After(time.Now()) {
+		return s.sendDelayed(ctx, payload)
+	}
+
 	data, err := payload.ToJSON()
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	result := s.emailTopic.Publish(ctx, &pubsub.Message{Data: data})
-	id, err := result.Get(ctx)
+	attrs := ipubsub.PriorityAttributes(payload.GetPriority())
+	id, err := s.emailPublisher.Publish(ctx, data, attrs)
 	if err != nil {
-		return "", fmt.Errorf("failed to publish message: %w", err)
+		if s.outbox == nil {
+			return "", fmt.Errorf("failed to publish message: %w", err)
+		}
+
+		trackingID, queueErr := s.outbox.Enqueue(ctx, OutboxMessage{
+			Data:      data,
+			Attrs:     attrs,
+			Type:      audit.TypeEmail,
+			Recipient: payload.To,
+		})
+		if queueErr != nil {
+			return "", fmt.Errorf("failed to publish message: %w", errors.Join(err, queueErr))
+		}
+
+		log.Printf("Publish failed, email message queued with tracking ID %s: %v", trackingID, err)
+		return trackingID, ErrPublishQueued
 	}
 
 	log.Printf("Published email message with ID: %s", id)
+	s.audit(ctx, audit.TypeEmail, payload.To, id)
 	return id, nil
 }
 
+// sendDelayed publishes a scheduled email to the delay topic, which releases it at its scheduled time
+func (s *Service) sendDelayed(ctx context.Context, payload *models.EmailPayload) (string, error) {
+	deliverAt := payload.ScheduledAt
+
+	// The delay topic does the scheduling, so Resend sends the email as soon as it is released
+	delayed := *payload
+	delayed.ScheduledAt = time.Time{}
+	data, err := delayed.ToJSON()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// The priority is kept when the delay topic releases the message to the email topic
+	attrs := ipubsub.DelayAttributes(s.delayTarget, deliverAt)
+	attrs[ipubsub.PriorityAttribute] = payload.GetPriority()
+	id, err := s.delayPublisher.Publish(ctx, data, attrs)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish delayed message: %w", err)
+	}
+
+	log.Printf("Published email message with ID %s, delayed until %s", id, deliverAt.UTC().Format(time.RFC3339))
+	s.audit(ctx, audit.TypeEmail, payload.To, id)
+	return id, nil
+}
+
+// DefaultBatchConcurrency is the number of publishes SendEmailBatch awaits at once when no limit is given
+const DefaultBatchConcurrency = 32
+
+// ErrDuplicateRecipient is reported for batch items skipped because an earlier item has the same recipient
+var ErrDuplicateRecipient = errors.New("duplicate recipient")
+
+// BatchOptions holds optional settings for SendEmailBatch
+type BatchOptions struct {
+	// Concurrency caps the publishes in flight at once (<= 0 uses DefaultBatchConcurrency)
+	Concurrency int
+
+	// DedupeRecipients skips items whose normalized recipient, compared case-insensitively, appeared earlier in the batch
+	DedupeRecipients bool
+}
+
+// SendEmailBatch publishes a batch of email messages, returning the message ID or error for each item by index.
+// Items queued in the outbox report their tracking ID with ErrPublishQueued, and skipped duplicates ErrDuplicateRecipient.
+func (s *Service) SendEmailBatch(ctx context.Context, payloads []*models.EmailPayload, opts BatchOptions) ([]string, []error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	ids := make([]string, len(payloads))
+	errs := make([]error, len(payloads))
+	seen := make(map[string]int) // Recipient key to the index of its first item
+
+	// Publish concurrently so the Pub/Sub client can batch the messages, bounded by the semaphore
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, payload := range payloads {
+		if payload == nil {
+			errs[i] = fmt.Errorf("invalid payload: empty email")
+			continue
+		}
+
+		if opts.DedupeRecipients {
+			key := recipientKey(payload.To)
+			if first, ok := seen[key]; ok && key != "" {
+				log.Printf("Skipping batch item %d: recipient already in item %d", i, first)
+				errs[i] = fmt.Errorf("%w: same as item %d", ErrDuplicateRecipient, first)
+				continue
+			}
+			seen[key] = i
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ids[i], errs[i] = s.SendEmail(ctx, payload)
+		}()
+	}
+	wg.Wait()
+
+	return ids, errs
+}
+
+// recipientKey returns the normalized, lowercased address of a recipient, used to spot duplicates in a batch
+func recipientKey(to string) string {
+	_, address := models.NormalizeAddress(to)
+	return strings.ToLower(address)
+}
+
 // PublishVerificationEmail publishes a verification email message to the verification topic
 func (s *Service) PublishVerificationEmail(ctx context.Context, payload *models.VerificationEmailPayload) error {
-	if s.verificationTopic == nil {
+	if s.verificationPublisher == nil {
 		return fmt.Errorf("verification topic not configured")
 	}
 
+	payload.Normalize()
 	if err := payload.Validate(); err != nil {
 		return fmt.Errorf("invalid payload: %w", err)
 	}
@@ -75,13 +247,65 @@ func (s *Service) PublishVerificationEmail(ctx context.Context, payload *models.
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	result := s.verificationTopic.Publish(ctx, &pubsub.Message{Data: data})
-	id, err := result.Get(ctx)
+	id, err := s.verificationPublisher.Publish(ctx, data, ipubsub.PriorityAttributes(models.PriorityHigh))
 	if err != nil {
 		return fmt.Errorf("failed to publish verification message: %w", err)
 	}
 
 	log.Printf("Published verification email message with ID: %s", id)
+	s.audit(ctx, audit.TypeVerification, payload.To, id)
+
+	if s.verifications != nil {
+		if err := s.verifications.Save(ctx, payload); err != nil {
+			log.Printf("Failed to store verification email for %s: %v", logging.Recipient(payload.To), err)
+		}
+	}
+	return nil
+}
+
+// ResendVerificationEmail republishes the latest verification email issued to address with a fresh expiry.
+// It returns ErrVerificationNotFound when none was issued.
+func (s *Service) ResendVerificationEmail(ctx context.Context, address string, now time.Time) (*models.VerificationEmailPayload, error) {
+	if s.verifications == nil {
+		return nil, fmt.Errorf("verification store not configured")
+	}
+
+	_, address = models.NormalizeAddress(address)
+	payload, err := s.verifications.Latest(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.SetExpiry(now)
+	if err := s.PublishVerificationEmail(ctx, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// PublishWelcome publishes a welcome email message to the welcome topic
+func (s *Service) PublishWelcome(ctx context.Context, payload *models.WelcomeEmailPayload) error {
+	if s.welcomePublisher == nil {
+		return fmt.Errorf("welcome topic not configured")
+	}
+
+	payload.Normalize()
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	data, err := payload.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	id, err := s.welcomePublisher.Publish(ctx, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to publish welcome message: %w", err)
+	}
+
+	log.Printf("Published welcome email message with ID: %s", id)
+	s.audit(ctx, audit.TypeWelcome, payload.Email, id)
 	return nil
 }
 
@@ -97,7 +321,7 @@ func ProcessMessage(ctx context.Context, msg *pubsub.Message, handler MessageHan
 		return
 	}
 
-	log.Printf("Processing email: To=%s, Subject=%s", payload.To, payload.Subject)
+	log.Printf("Processing email: To=%s, Subject=%s", logging.Recipient(payload.To), payload.Subject)
 
 	if err := handler(ctx, payload); err != nil {
 		log.Printf("Failed to process message: %v", err)
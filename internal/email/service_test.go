@@ -0,0 +1,373 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/logging"
+	"go_integration/internal/models"
+	ipubsub "go_integration/internal/pubsub"
+)
+
+// attrsPublisher records the data and attributes of the last published message, failing with err when set
+type attrsPublisher struct {
+	data  []byte
+	attrs map[string]string
+	err   error
+}
+
+func (p *attrsPublisher) Publish(_ context.Context, data []byte, attrs map[string]string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.data, p.attrs = data, attrs
+	return "msg-1", nil
+}
+
+func TestSendEmailPublish(t *testing.T) {
+	unavailable := errors.New("pubsub unavailable")
+
+	tests := []struct {
+		name       string
+		publishErr error
+		wantID     string
+	}{
+		{name: "published", wantID: "msg-1"},
+		{name: "publish error", publishErr: unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &attrsPublisher{err: tt.publishErr}
+			service := NewService(publisher)
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"}
+			id, err := service.SendEmail(context.Background(), payload)
+			if !errors.Is(err, tt.publishErr) {
+				t.Fatalf("SendEmail error = %v, want %v", err, tt.publishErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("SendEmail = %q, want %q", id, tt.wantID)
+			}
+			if tt.publishErr == nil && !strings.Contains(string(publisher.data), `"to":"ana@example.com"`) {
+				t.Errorf("published %s, want the payload", publisher.data)
+			}
+		})
+	}
+}
+
+func TestSendEmailDefaultSubject(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultSubject string
+		subject        string
+		wantSubject    string
+		wantErr        bool
+	}{
+		{name: "strict by default", wantErr: true},
+		{name: "default applied", defaultSubject: "Aviso NorthFi", subject: " ", wantSubject: "Aviso NorthFi"},
+		{name: "explicit subject kept", defaultSubject: "Aviso NorthFi", subject: "Oi", wantSubject: "Oi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&attrsPublisher{})
+			service.SetDefaultSubject(tt.defaultSubject)
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: tt.subject, Body: "Olá"}
+			_, err := service.SendEmail(context.Background(), payload)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrMissingSubject) {
+					t.Errorf("SendEmail error = %v, want ErrMissingSubject", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendEmail failed: %v", err)
+			}
+			if payload.Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", payload.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestSendEmailPriorityAttribute(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   models.EmailPayload
+		wantLane  string
+		wantDelay bool
+	}{
+		{name: "bulk email", payload: models.EmailPayload{Body: "Olá"}, wantLane: models.PriorityLow},
+		{name: "explicit high", payload: models.EmailPayload{Body: "Olá", Priority: models.PriorityHigh}, wantLane: models.PriorityHigh},
+		{
+			name:      "scheduled email keeps its lane",
+			payload:   models.EmailPayload{Body: "Olá", Priority: models.PriorityHigh, ScheduledAt: time.Now().Add(time.Hour)},
+			wantLane:  models.PriorityHigh,
+			wantDelay: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emails, delay := &attrsPublisher{}, &attrsPublisher{}
+			service := NewService(emails)
+			if tt.wantDelay {
+				service.SetDelayPublisher(delay, "emails")
+			}
+
+			payload := tt.payload
+			payload.To, payload.Subject = "ana@example.com", "Oi"
+			if _, err := service.SendEmail(context.Background(), &payload); err != nil {
+				t.Fatalf("SendEmail failed: %v", err)
+			}
+
+			published := emails
+			if tt.wantDelay {
+				published = delay
+			}
+			if got := published.attrs[ipubsub.PriorityAttribute]; got != tt.wantLane {
+				t.Errorf("priority attribute = %q, want %q", got, tt.wantLane)
+			}
+		})
+	}
+}
+
+func TestSendEmailDelayTopic(t *testing.T) {
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		scheduledAt time.Time
+		delay       bool
+		wantDelayed bool
+	}{
+		{name: "future email is held on the delay topic", scheduledAt: future, delay: true, wantDelayed: true},
+		{name: "past email is published right away", scheduledAt: time.Now().Add(-time.Hour), delay: true},
+		{name: "unscheduled email is published right away", delay: true},
+		{name: "future email without a delay topic is left to Resend", scheduledAt: future},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emails, delay := &attrsPublisher{}, &attrsPublisher{}
+			service := NewService(emails)
+			if tt.delay {
+				service.SetDelayPublisher(delay, "emails")
+			}
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá", ScheduledAt: tt.scheduledAt}
+			if _, err := service.SendEmail(context.Background(), payload); err != nil {
+				t.Fatalf("SendEmail failed: %v", err)
+			}
+
+			if (delay.data != nil) != tt.wantDelayed || (emails.data != nil) == tt.wantDelayed {
+				t.Fatalf("delayed = %v, want %v", delay.data != nil, tt.wantDelayed)
+			}
+			if !tt.wantDelayed {
+				return
+			}
+
+			if got := delay.attrs[ipubsub.TargetTopicAttribute]; got != "emails" {
+				t.Errorf("target topic = %q, want %q", got, "emails")
+			}
+			if got := delay.attrs[ipubsub.DeliverAtAttribute]; got != future.UTC().Format(time.RFC3339) {
+				t.Errorf("deliver at = %q, want %q", got, future.UTC().Format(time.RFC3339))
+			}
+
+			// The delay topic does the scheduling, so the released email must not be scheduled again
+			var published models.EmailPayload
+			if err := json.Unmarshal(delay.data, &published); err != nil {
+				t.Fatalf("failed to decode the delayed payload: %v", err)
+			}
+			if !published.ScheduledAt.IsZero() {
+				t.Errorf("delayed payload kept scheduled_at %s", published.ScheduledAt)
+			}
+		})
+	}
+}
+
+// failingVerificationStore fails every save
+type failingVerificationStore struct{}
+
+func (failingVerificationStore) Save(context.Context, *models.VerificationEmailPayload) error {
+	return errors.New("store unavailable")
+}
+
+func (failingVerificationStore) Latest(context.Context, string) (*models.VerificationEmailPayload, error) {
+	return nil, ErrVerificationNotFound
+}
+
+func TestPublishVerificationEmailRedactsStoreFailure(t *testing.T) {
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+	defer logging.SetRedactEmails(false)
+	logging.SetRedactEmails(true)
+
+	service := NewServiceWithVerification(&attrsPublisher{}, &attrsPublisher{})
+	service.SetVerificationStore(failingVerificationStore{})
+	payload := &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"}
+	if err := service.PublishVerificationEmail(context.Background(), payload); err != nil {
+		t.Fatalf("PublishVerificationEmail failed: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "Failed to store verification email") {
+		t.Fatalf("log is missing the store failure: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "ana@example.com") {
+		t.Errorf("log contains the recipient in plain text: %s", logs.String())
+	}
+}
+
+func TestPublishWelcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   models.WelcomeEmailPayload
+		noTopic   bool
+		wantError bool
+	}{
+		{name: "publishes to the welcome topic", payload: models.WelcomeEmailPayload{Name: "Ana", Email: " ana@EXAMPLE.com"}},
+		{name: "invalid payload", payload: models.WelcomeEmailPayload{Email: "ana@example.com"}, wantError: true},
+		{name: "welcome topic not configured", payload: models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com"}, noTopic: true, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emails, welcome := &attrsPublisher{}, &attrsPublisher{}
+			service := NewServiceWithTopics(emails, nil, welcome)
+			if tt.noTopic {
+				service = NewService(emails)
+			}
+
+			payload := tt.payload
+			err := service.PublishWelcome(context.Background(), &payload)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("PublishWelcome error = %v, wantError %v", err, tt.wantError)
+			}
+			if emails.data != nil {
+				t.Error("welcome email was published to the email topic")
+			}
+			if tt.wantError {
+				return
+			}
+			if !strings.Contains(string(welcome.data), `"email":"ana@example.com"`) {
+				t.Errorf("published %s, want the normalized recipient", welcome.data)
+			}
+		})
+	}
+}
+
+// concurrencyPublisher records the largest number of publishes in flight at once
+type concurrencyPublisher struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	count    int
+}
+
+func (p *concurrencyPublisher) Publish(context.Context, []byte, map[string]string) (string, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.count++
+	id := fmt.Sprintf("msg-%d", p.count)
+	p.peak = max(p.peak, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return id, nil
+}
+
+func TestSendEmailBatchConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantMax     int
+	}{
+		{name: "bounded", concurrency: 2, wantMax: 2},
+		{name: "default", wantMax: DefaultBatchConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &concurrencyPublisher{}
+			service := NewService(publisher)
+
+			payloads := make([]*models.EmailPayload, 100)
+			for i := range payloads {
+				payloads[i] = &models.EmailPayload{To: fmt.Sprintf("user%d@example.com", i), Subject: "Oi", Body: "Olá"}
+			}
+
+			ids, errs := service.SendEmailBatch(context.Background(), payloads, BatchOptions{Concurrency: tt.concurrency})
+			if err := errors.Join(errs...); err != nil {
+				t.Fatalf("SendEmailBatch failed: %v", err)
+			}
+			if len(ids) != len(payloads) || slices.Contains(ids, "") {
+				t.Errorf("got %d IDs with missing ones, want one per email", len(ids))
+			}
+			if publisher.peak > tt.wantMax {
+				t.Errorf("%d publishes in flight, want at most %d", publisher.peak, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestSendEmailBatchDedupeRecipients(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          []string
+		dedupe      bool
+		wantSkipped []int
+	}{
+		{name: "distinct recipients", to: []string{"ana@example.com", "bia@example.com"}, dedupe: true},
+		{name: "exact duplicate", to: []string{"ana@example.com", "bia@example.com", "ana@example.com"}, dedupe: true, wantSkipped: []int{2}},
+		{name: "different case", to: []string{"ana@example.com", "Ana@Example.COM"}, dedupe: true, wantSkipped: []int{1}},
+		{name: "surrounding whitespace", to: []string{"ana@example.com", "  ana@example.com "}, dedupe: true, wantSkipped: []int{1}},
+		{name: "display name", to: []string{"ana@example.com", "Ana Souza <ana@example.com>"}, dedupe: true, wantSkipped: []int{1}},
+		{name: "duplicates kept without dedupe", to: []string{"ana@example.com", "ana@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &concurrencyPublisher{}
+			service := NewService(publisher)
+
+			payloads := make([]*models.EmailPayload, len(tt.to))
+			for i, to := range tt.to {
+				payloads[i] = &models.EmailPayload{To: to, Subject: "Oi", Body: "Olá"}
+			}
+
+			_, errs := service.SendEmailBatch(context.Background(), payloads, BatchOptions{DedupeRecipients: tt.dedupe})
+
+			var skipped []int
+			for i, err := range errs {
+				switch {
+				case errors.Is(err, ErrDuplicateRecipient):
+					skipped = append(skipped, i)
+				case err != nil:
+					t.Errorf("item %d failed: %v", i, err)
+				}
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped items %v, want %v", skipped, tt.wantSkipped)
+			}
+			if want := len(tt.to) - len(tt.wantSkipped); publisher.count != want {
+				t.Errorf("published %d emails, want %d", publisher.count, want)
+			}
+		})
+	}
+}
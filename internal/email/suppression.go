@@ -0,0 +1,117 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go_integration/internal/logging"
+)
+
+// SuppressionList holds addresses that must not receive emails, e.g. after a hard bounce
+type SuppressionList interface {
+	Contains(ctx context.Context, address string) (bool, error)
+	Add(ctx context.Context, address string) error
+}
+
+// MemorySuppressionList is an in-process SuppressionList
+type MemorySuppressionList struct {
+	mu        sync.RWMutex
+	addresses map[string]struct{}
+}
+
+// NewMemorySuppressionList creates an in-memory suppression list seeded with addresses
+func NewMemorySuppressionList(addresses ...string) *MemorySuppressionList {
+	list := &MemorySuppressionList{addresses: make(map[string]struct{}, len(addresses))}
+	for _, addr := range addresses {
+		if addr = normalizeAddress(addr); addr != "" {
+			list.addresses[addr] = struct{}{}
+		}
+	}
+	return list
+}
+
+// Contains reports whether address is suppressed
+func (l *MemorySuppressionList) Contains(_ context.Context, address string) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, ok := l.addresses[normalizeAddress(address)]
+	return ok, nil
+}
+
+// Add suppresses address
+func (l *MemorySuppressionList) Add(_ context.Context, address string) error {
+	address = normalizeAddress(address)
+	if address == "" {
+		return fmt.Errorf("cannot suppress an empty address")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.addresses[address] = struct{}{}
+	return nil
+}
+
+// SuppressionSender skips recipients found in a SuppressionList before delegating to the next Sender
+type SuppressionSender struct {
+	next       Sender
+	suppressed SuppressionList
+}
+
+// NewSuppressionSender wraps a Sender so that suppressed recipients are skipped
+func NewSuppressionSender(next Sender, suppressed SuppressionList) *SuppressionSender {
+	return &SuppressionSender{
+		next:       next,
+		suppressed: suppressed,
+	}
+}
+
+// SendText sends the text email unless the recipient is suppressed
+func (s *SuppressionSender) SendText(to, subject, body string) error {
+	if skip, err := s.skip(context.Background(), to, subject); skip || err != nil {
+		return err
+	}
+	return s.next.SendText(to, subject, body)
+}
+
+// SendEmailContext sends the text email unless the recipient is suppressed
+func (s *SuppressionSender) SendEmailContext(ctx context.Context, to, subject, body string) error {
+	if skip, err := s.skip(ctx, to, subject); skip || err != nil {
+		return err
+	}
+	return s.next.SendEmailContext(ctx, to, subject, body)
+}
+
+// SendEmailWithHTMLContext sends the email unless the recipient is suppressed
+func (s *SuppressionSender) SendEmailWithHTMLContext(ctx context.Context, to, subject, htmlBody string) error {
+	if skip, err := s.skip(ctx, to, subject); skip || err != nil {
+		return err
+	}
+	return s.next.SendEmailWithHTMLContext(ctx, to, subject, htmlBody)
+}
+
+// SendEmailWithOptions sends the email unless the recipient is suppressed
+func (s *SuppressionSender) SendEmailWithOptions(ctx context.Context, to, subject, htmlBody string, opts SendOptions) error {
+	if skip, err := s.skip(ctx, to, subject); skip || err != nil {
+		return err
+	}
+	return s.next.SendEmailWithOptions(ctx, to, subject, htmlBody, opts)
+}
+
+// skip reports whether the email to the recipient must be dropped
+func (s *SuppressionSender) skip(ctx context.Context, to, subject string) (bool, error) {
+	suppressed, err := s.suppressed.Contains(ctx, to)
+	if err != nil {
+		return false, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+	if suppressed {
+		slog.Warn("Recipient is suppressed, skipping email",
+			"recipient", logging.Recipient(to),
+			"subject", subject,
+		)
+	}
+	return suppressed, nil
+}
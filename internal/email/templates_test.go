@@ -0,0 +1,111 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerificationTemplates(t *testing.T) {
+	const verifyURL = "https://northfi.com.br/verify?token=abc&user=1"
+
+	tests := []struct {
+		name     string
+		html     string
+		want     []string
+		dontWant []string
+	}{
+		{
+			name:     "code",
+			html:     GetVerificationEmailHTML("Ana", DefaultBrandConfig(), "123456", 10*time.Minute),
+			want:     []string{`class="verification-code"`, "123 456", "10 minutos"},
+			dontWant: []string{"Verificar email"},
+		},
+		{
+			name:     "link",
+			html:     GetVerificationLinkEmailHTML("Ana", "NorthFi", verifyURL),
+			want:     []string{`<a href="https://northfi.com.br/verify?token=abc&amp;user=1" target="_blank" class="btn">Verificar email</a>`},
+			dontWant: []string{`class="verification-code"`},
+		},
+		{
+			name:     "unsafe link is neutralized",
+			html:     GetVerificationLinkEmailHTML("Ana", "NorthFi", "javascript:alert(1)"),
+			dontWant: []string{`href="javascript:`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.html == "" {
+				t.Fatal("template rendered empty")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(tt.html, want) {
+					t.Errorf("rendered HTML does not contain %q", want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(tt.html, dontWant) {
+					t.Errorf("rendered HTML contains %q", dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestCTAButton(t *testing.T) {
+	cta := CTA{Text: "Abrir app", URL: "https://northfi.com.br/app"}
+	const button = `<a href="https://northfi.com.br/app" target="_blank" class="btn">Abrir app</a>`
+
+	tests := []struct {
+		name     string
+		html     string
+		want     []string
+		dontWant []string
+	}{
+		{
+			name: "default with a button",
+			html: GetDefaultEmailHTML("Oi", "Olá", "NorthFi", cta),
+			want: []string{button},
+		},
+		{
+			name:     "default without a button",
+			html:     GetDefaultEmailHTML("Oi", "Olá", "NorthFi", CTA{}),
+			dontWant: []string{`class="btn"`},
+		},
+		{
+			name:     "default with only a text",
+			html:     GetDefaultEmailHTML("Oi", "Olá", "NorthFi", CTA{Text: "Abrir app"}),
+			dontWant: []string{`class="btn"`},
+		},
+		{
+			name:     "welcome with a custom button",
+			html:     GetWelcomeEmailHTML("Ana", "NorthFi", cta),
+			want:     []string{button},
+			dontWant: []string{"Acessar minha conta"},
+		},
+		{
+			name: "welcome keeps its default button",
+			html: GetWelcomeEmailHTML("Ana", "NorthFi", CTA{}),
+			want: []string{"Acessar minha conta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.html == "" {
+				t.Fatal("template rendered empty")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(tt.html, want) {
+					t.Errorf("rendered HTML does not contain %q", want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(tt.html, dontWant) {
+					t.Errorf("rendered HTML contains %q", dontWant)
+				}
+			}
+		})
+	}
+}
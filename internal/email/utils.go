@@ -0,0 +1,39 @@
+package email
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// welcomeSubjectPatterns lists lowercase fragments that identify a welcome email subject
+var welcomeSubjectPatterns = []string{
+	"bem-vindo",
+	"bem-vinda",
+	"bem vindo",
+	"bem vinda",
+	"boas-vindas",
+	"boas vindas",
+	"welcome",
+}
+
+// IsWelcomeSubject checks if an email subject indicates a welcome email
+func IsWelcomeSubject(subject string) bool {
+	subjectLower := strings.ToLower(subject)
+	for _, pattern := range welcomeSubjectPatterns {
+		if strings.Contains(subjectLower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// TruncateBody shortens body to at most maxRunes characters, ending it with an ellipsis.
+// It reports whether the body was truncated; maxRunes <= 0 disables the limit.
+func TruncateBody(body string, maxRunes int) (string, bool) {
+	if maxRunes <= 0 || utf8.RuneCountInString(body) <= maxRunes {
+		return body, false
+	}
+
+	runes := []rune(body)
+	return string(runes[:maxRunes-1]) + "…", true
+}
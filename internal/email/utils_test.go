@@ -0,0 +1,27 @@
+package email
+
+import "testing"
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		maxRunes      int
+		want          string
+		wantTruncated bool
+	}{
+		{name: "under the limit", body: "Olá", maxRunes: 10, want: "Olá"},
+		{name: "exactly the limit", body: "Olá, Ana", maxRunes: 8, want: "Olá, Ana"},
+		{name: "over the limit", body: "Olá, Ana Souza", maxRunes: 8, want: "Olá, An…", wantTruncated: true},
+		{name: "limit disabled", body: "Olá, Ana Souza", want: "Olá, Ana Souza"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := TruncateBody(tt.body, tt.maxRunes)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("TruncateBody(%q, %d) = %q, %v, want %q, %v", tt.body, tt.maxRunes, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go_integration/internal/models"
+)
+
+// ErrVerificationNotFound is returned when no verification email was issued to an address
+var ErrVerificationNotFound = errors.New("no verification email issued for this address")
+
+// DefaultVerificationStoreTTL is how long a MemoryVerificationStore keeps a verification email resendable
+const DefaultVerificationStoreTTL = 24 * time.Hour
+
+// VerificationStore remembers the last verification email issued to each address so it can be resent
+type VerificationStore interface {
+	Save(ctx context.Context, payload *models.VerificationEmailPayload) error
+	Latest(ctx context.Context, address string) (*models.VerificationEmailPayload, error)
+}
+
+// storedVerification is a verification email kept by MemoryVerificationStore until expiresAt
+type storedVerification struct {
+	payload   models.VerificationEmailPayload
+	expiresAt time.Time
+}
+
+// MemoryVerificationStore is an in-process VerificationStore whose entries expire after a TTL
+type MemoryVerificationStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	payloads  map[string]storedVerification
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryVerificationStore creates an empty in-memory verification store keeping each email resendable
+// for ttl after it was issued (<= 0 uses DefaultVerificationStoreTTL)
+func NewMemoryVerificationStore(ttl time.Duration) *MemoryVerificationStore {
+	if ttl <= 0 {
+		ttl = DefaultVerificationStoreTTL
+	}
+	return &MemoryVerificationStore{
+		ttl:       ttl,
+		payloads:  make(map[string]storedVerification),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Save records payload as the latest verification issued to its recipient
+func (s *MemoryVerificationStore) Save(_ context.Context, payload *models.VerificationEmailPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+	s.payloads[normalizeAddress(payload.To)] = storedVerification{payload: *payload, expiresAt: now.Add(s.ttl)}
+	return nil
+}
+
+// Latest returns a copy of the latest verification issued to address, or ErrVerificationNotFound when
+// none was issued within the TTL
+func (s *MemoryVerificationStore) Latest(_ context.Context, address string) (*models.VerificationEmailPayload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+
+	key := normalizeAddress(address)
+	stored, ok := s.payloads[key]
+	if !ok || !now.Before(stored.expiresAt) {
+		delete(s.payloads, key)
+		return nil, ErrVerificationNotFound
+	}
+	return &stored.payload, nil
+}
+
+// pruneLocked drops expired entries at most once per TTL window; the caller must hold s.mu
+func (s *MemoryVerificationStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < s.ttl {
+		return
+	}
+
+	for key, stored := range s.payloads {
+		if !now.Before(stored.expiresAt) {
+			delete(s.payloads, key)
+		}
+	}
+	s.lastPrune = now
+}
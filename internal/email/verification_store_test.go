@@ -0,0 +1,67 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go_integration/internal/models"
+)
+
+func TestMemoryVerificationStore(t *testing.T) {
+	const ttl = time.Hour
+
+	tests := []struct {
+		name     string
+		saved    string
+		lookup   string
+		age      time.Duration
+		wantCode string
+		wantErr  error
+	}{
+		{name: "found", saved: "ana@example.com", lookup: "ana@example.com", age: time.Minute, wantCode: "123456"},
+		{name: "address is normalized", saved: "ana@example.com", lookup: "  Ana@Example.com ", wantCode: "123456"},
+		{name: "never issued", saved: "ana@example.com", lookup: "eva@example.com", wantErr: ErrVerificationNotFound},
+		{name: "expired", saved: "ana@example.com", lookup: "ana@example.com", age: ttl, wantErr: ErrVerificationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			store := NewMemoryVerificationStore(ttl)
+			store.now = func() time.Time { return now }
+
+			ctx := context.Background()
+			if err := store.Save(ctx, &models.VerificationEmailPayload{To: tt.saved, Code: "123456"}); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			now = now.Add(tt.age)
+
+			got, err := store.Latest(ctx, tt.lookup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Latest error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestMemoryVerificationStorePrunes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryVerificationStore(time.Hour)
+	store.now = func() time.Time { return now }
+	store.lastPrune = now
+
+	ctx := context.Background()
+	store.Save(ctx, &models.VerificationEmailPayload{To: "ana@example.com", Code: "111111"})
+	now = now.Add(2 * time.Hour)
+
+	// Touching any address evicts every expired entry, not just the one looked up
+	store.Save(ctx, &models.VerificationEmailPayload{To: "eva@example.com", Code: "222222"})
+	if len(store.payloads) != 1 {
+		t.Errorf("store kept %d entries, want only the fresh one", len(store.payloads))
+	}
+}
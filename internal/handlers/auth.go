@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RequireBearerToken rejects requests whose Authorization header does not carry token
+func RequireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSignature rejects requests whose X-Signature header is not the hex HMAC-SHA256 of the body under secret.
+// The header may carry a "sha256=" prefix. The body is restored so next can decode it.
+func RequireSignature(secret string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature := strings.TrimPrefix(strings.TrimSpace(r.Header.Get("X-Signature")), "sha256=")
+		if signature == "" {
+			writeError(w, r, "Missing signature", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, fmt.Sprintf("Failed to read body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		provided, err := hex.DecodeString(signature)
+		if err != nil || !hmac.Equal(provided, signBody(secret, body)) {
+			writeError(w, r, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signBody returns the HMAC-SHA256 of body under secret
+func signBody(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
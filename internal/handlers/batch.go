@@ -0,0 +1,20 @@
+package handlers
+
+import "bytes"
+
+// maxBatchSize caps the number of items accepted by a single batch request
+const maxBatchSize = 1000
+
+// batchItemResult reports the outcome of a single item in a batch request
+type batchItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// isJSONArray reports whether the first non-space byte of body opens a JSON array
+func isJSONArray(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
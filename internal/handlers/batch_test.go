@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_integration/internal/email"
+)
+
+// batchResponse is the data of a POST /send-email batch response
+type batchResponse struct {
+	Data struct {
+		Succeeded int               `json:"succeeded"`
+		Skipped   int               `json:"skipped"`
+		Failed    int               `json:"failed"`
+		Results   []batchItemResult `json:"results"`
+	} `json:"data"`
+}
+
+func TestSendEmailBatch(t *testing.T) {
+	const ana = `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`
+	const eva = `{"to":"eva@example.com","subject":"Oi","body":"Olá"}`
+	const invalid = `{"to":"","subject":"Oi","body":"Olá"}`
+
+	tests := []struct {
+		name         string
+		query        string
+		body         string
+		wantStatus   int
+		wantStatuses []string
+	}{
+		{name: "every item published", body: "[" + ana + "," + eva + "]", wantStatus: http.StatusOK, wantStatuses: []string{"published", "published"}},
+		{name: "invalid item fails alone", body: "[" + ana + "," + invalid + "]", wantStatus: http.StatusOK, wantStatuses: []string{"published", "error"}},
+		{name: "duplicates kept by default", body: "[" + ana + "," + ana + "]", wantStatus: http.StatusOK, wantStatuses: []string{"published", "published"}},
+		{name: "duplicates skipped", query: "?dedupe=true", body: "[" + ana + "," + ana + "]", wantStatus: http.StatusOK, wantStatuses: []string{"published", "skipped"}},
+		{name: "invalid dedupe", query: "?dedupe=maybe", body: "[" + ana + "]", wantStatus: http.StatusBadRequest},
+		{name: "empty batch", body: "[]", wantStatus: http.StatusBadRequest},
+		{name: "too many items", body: "[" + strings.Repeat(ana+",", maxBatchSize) + ana + "]", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEmailHandler(email.NewService(&fakePublisher{}))
+			req := httptest.NewRequest(http.MethodPost, "/send-email"+tt.query, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.SendEmail(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp batchResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Data.Results) != len(tt.wantStatuses) {
+				t.Fatalf("results = %+v, want %d items", resp.Data.Results, len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				if got := resp.Data.Results[i]; got.Index != i || got.Status != want {
+					t.Errorf("results[%d] = %+v, want status %s", i, got, want)
+				}
+			}
+			if total := resp.Data.Succeeded + resp.Data.Skipped + resp.Data.Failed; total != len(tt.wantStatuses) {
+				t.Errorf("counts add up to %d, want %d", total, len(tt.wantStatuses))
+			}
+		})
+	}
+}
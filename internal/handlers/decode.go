@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeJSON decodes a JSON body into v, rejecting fields v does not declare so typos are not silently dropped
+func decodeJSON(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		// encoding/json has no typed error for unknown fields, only this message
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("unknown field %s", field)
+		}
+		return err
+	}
+	return nil
+}
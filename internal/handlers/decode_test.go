@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "known fields", body: `{"email":"ana@example.com"}`},
+		{name: "unknown field", body: `{"email":"ana@example.com","emial":"x"}`, wantErr: `unknown field "emial"`},
+		{name: "malformed", body: `{"email":`, wantErr: "unexpected EOF"},
+		{name: "wrong type", body: `{"email":42}`, wantErr: "cannot unmarshal number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req resendVerificationRequest
+			err := decodeJSON(strings.NewReader(tt.body), &req)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("decodeJSON failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodeJSON error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"go_integration/internal/pubsub"
+)
+
+// DeadLetterReplayer republishes messages from a dead-letter subscription
+type DeadLetterReplayer interface {
+	ReplayDeadLetters(ctx context.Context, subID string, publisher pubsub.Publisher, maxMessages int) (int, error)
+}
+
+// ReplayDeadLetters handles POST /dlq/replay?subscription=...&topic=...&max=... requests, republishing
+// messages from one of the configured dead-letter subscriptions to one of the allowed topics; max defaults
+// to and is capped by maxMessages
+func ReplayDeadLetters(replayer DeadLetterReplayer, publishers map[string]pubsub.Publisher, deadLetterSubs []string, maxMessages int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		subID := query.Get("subscription")
+		if subID == "" {
+			writeError(w, r, "subscription is required", http.StatusBadRequest)
+			return
+		}
+		// Only dead-letter subscriptions may be drained; pulling from a live one would steal its messages
+		if !slices.Contains(deadLetterSubs, subID) {
+			writeError(w, r, fmt.Sprintf("Unknown dead-letter subscription: %s", subID), http.StatusBadRequest)
+			return
+		}
+
+		topicID := query.Get("topic")
+		publisher, ok := publishers[topicID]
+		if !ok {
+			writeError(w, r, fmt.Sprintf("Unknown topic: %s", topicID), http.StatusNotFound)
+			return
+		}
+
+		limit := maxMessages
+		if raw := query.Get("max"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				writeError(w, r, "max must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = min(parsed, maxMessages)
+		}
+
+		replayed, err := replayer.ReplayDeadLetters(r.Context(), subID, publisher, limit)
+		if err != nil {
+			log.Printf("Failed to replay dead letters from %s to %s after %d messages: %v", subID, topicID, replayed, err)
+			writeError(w, r, fmt.Sprintf("Failed to replay dead letters: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Replayed %d dead-letter messages from %s to %s", replayed, subID, topicID)
+
+		writeJSONSuccess(w, r, http.StatusOK, map[string]interface{}{
+			"message":  fmt.Sprintf("%d mensagens reprocessadas", replayed),
+			"replayed": replayed,
+			"topic":    topicID,
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_integration/internal/pubsub"
+)
+
+// fakeReplayer records the replay it was asked for
+type fakeReplayer struct {
+	subID string
+	max   int
+}
+
+func (r *fakeReplayer) ReplayDeadLetters(_ context.Context, subID string, _ pubsub.Publisher, maxMessages int) (int, error) {
+	r.subID = subID
+	r.max = maxMessages
+	return maxMessages, nil
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantMax    int
+	}{
+		{name: "configured subscription", query: "subscription=emails.dlq&topic=emails", wantStatus: http.StatusOK, wantMax: 100},
+		{name: "max is capped", query: "subscription=emails.dlq&topic=emails&max=500", wantStatus: http.StatusOK, wantMax: 100},
+		{name: "max below cap", query: "subscription=emails.dlq&topic=emails&max=10", wantStatus: http.StatusOK, wantMax: 10},
+		{name: "live subscription", query: "subscription=emails.worker&topic=emails", wantStatus: http.StatusBadRequest},
+		{name: "missing subscription", query: "topic=emails", wantStatus: http.StatusBadRequest},
+		{name: "unknown topic", query: "subscription=emails.dlq&topic=other", wantStatus: http.StatusNotFound},
+		{name: "invalid max", query: "subscription=emails.dlq&topic=emails&max=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := &fakeReplayer{}
+			handler := ReplayDeadLetters(replayer, map[string]pubsub.Publisher{"emails": &fakePublisher{}}, []string{"emails.dlq"}, 100)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dlq/replay?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if replayer.subID != "" {
+					t.Errorf("replayed %s on a rejected request", replayer.subID)
+				}
+				return
+			}
+			if replayer.max != tt.wantMax {
+				t.Errorf("max = %d, want %d", replayer.max, tt.wantMax)
+			}
+		})
+	}
+}
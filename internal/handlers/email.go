@@ -1,18 +1,32 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 
+	"go_integration/internal/dedup"
 	"go_integration/internal/email"
 	"go_integration/internal/models"
 )
 
+// IdempotencyKeyHeader lets a client retry POST /send-email without publishing the email twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header so clients cannot fill the store with large keys
+const maxIdempotencyKeyLength = 255
+
 // EmailHandler handles HTTP requests for sending emails
 type EmailHandler struct {
-	emailService *email.Service
+	emailService     *email.Service
+	idempotency      dedup.IdempotencyStore
+	batchConcurrency int
 }
 
 // NewEmailHandler creates a new email handler
@@ -22,30 +36,222 @@ func NewEmailHandler(emailService *email.Service) *EmailHandler {
 	}
 }
 
-// SendEmail handles POST /send-email requests
+// SetBatchConcurrency caps the publishes in flight at once for an array of emails (<= 0 uses email.DefaultBatchConcurrency)
+func (h *EmailHandler) SetBatchConcurrency(concurrency int) {
+	h.batchConcurrency = concurrency
+}
+
+// SetIdempotencyStore enables the Idempotency-Key header on single email requests: a repeated key returns
+// the message ID of the first publish instead of publishing again, or 422 when it comes with another body
+func (h *EmailHandler) SetIdempotencyStore(store dedup.IdempotencyStore) {
+	h.idempotency = store
+}
+
+// SendEmail handles POST /send-email requests with a single email object or an array of emails
 func (h *EmailHandler) SendEmail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, fmt.Sprintf("Failed to read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if isJSONArray(body) {
+		h.sendEmailBatch(w, r, body)
 		return
 	}
 
 	var payload models.EmailPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if err := decodeJSON(bytes.NewReader(body), &payload); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		writeError(w, r, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
 		return
 	}
+	idempotent := key != "" && h.idempotency != nil
+	if idempotent {
+		// Reserve the key before publishing so concurrent retries cannot both publish
+		bodyHash := hashBody(body)
+		record, reserved, err := h.idempotency.Reserve(r.Context(), key, bodyHash)
+		if err != nil {
+			writeError(w, r, fmt.Sprintf("Failed to check idempotency key: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			replayIdempotent(w, r, record, bodyHash)
+			return
+		}
+	}
+
+	id, err := h.emailService.SendEmail(r.Context(), &payload)
+	if idempotent {
+		// A queued email is still published by the outbox, so a retry must not publish it again
+		queued := errors.Is(err, email.ErrPublishQueued)
+		if err == nil || queued {
+			if err := h.idempotency.Complete(r.Context(), key, id, queued); err != nil {
+				log.Printf("Failed to save idempotency key for message %s: %v", id, err)
+			}
+		} else if err := h.idempotency.Release(r.Context(), key); err != nil {
+			log.Printf("Failed to release idempotency key: %v", err)
+		}
+	}
 
-	id, err := h.emailService.SendEmail(context.Background(), &payload)
+	if errors.Is(err, email.ErrPublishQueued) {
+		writeJSONSuccess(w, r, http.StatusAccepted, map[string]string{
+			"message":     "Mensagem enfileirada para nova tentativa de publicação",
+			"tracking_id": id,
+		})
+		return
+	}
+	if isValidationError(err) {
+		writeValidationError(w, r, err)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to send email: %v", err), http.StatusInternalServerError)
+		writeError(w, r, fmt.Sprintf("Failed to send email: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]string{
+	writeJSONSuccess(w, r, http.StatusOK, map[string]string{
 		"message": fmt.Sprintf("Mensagem publicada com ID: %s", id),
 		"id":      id,
+	})
+}
+
+// replayIdempotent answers a request whose idempotency key was already used: with the first message ID when
+// the body matches, or an error when the key was reused for another body or its first request is still running
+func replayIdempotent(w http.ResponseWriter, r *http.Request, record dedup.IdempotencyRecord, bodyHash string) {
+	if record.BodyHash != bodyHash {
+		writeError(w, r, fmt.Sprintf("%s was already used with a different request body", IdempotencyKeyHeader), http.StatusUnprocessableEntity)
+		return
+	}
+	if record.MessageID == "" {
+		writeError(w, r, fmt.Sprintf("A request with this %s is still being processed", IdempotencyKeyHeader), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	if record.Queued {
+		writeJSONSuccess(w, r, http.StatusAccepted, map[string]string{
+			"message":     "Mensagem enfileirada para nova tentativa de publicação",
+			"tracking_id": record.MessageID,
+		})
+		return
+	}
+	writeJSONSuccess(w, r, http.StatusOK, map[string]string{
+		"message": fmt.Sprintf("Mensagem publicada com ID: %s", record.MessageID),
+		"id":      record.MessageID,
+	})
+}
+
+// hashBody returns the hex SHA-256 of a request body, identifying it for idempotency checks
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// sendEmailBatch publishes each email of a JSON array, reporting a result per item.
+// With ?dedupe=true, items whose recipient appeared earlier in the batch are skipped.
+func (h *EmailHandler) sendEmailBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	dedupe := false
+	if raw := r.URL.Query().Get("dedupe"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, r, "dedupe must be a boolean", http.StatusBadRequest)
+			return
+		}
+		dedupe = parsed
+	}
+
+	var payloads []*models.EmailPayload
+	if err := decodeJSON(bytes.NewReader(body), &payloads); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(payloads) == 0 {
+		writeError(w, r, "At least one email is required", http.StatusBadRequest)
+		return
+	}
+	if len(payloads) > maxBatchSize {
+		writeError(w, r, fmt.Sprintf("Too many emails: maximum is %d per request", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	ids, errs := h.emailService.SendEmailBatch(r.Context(), payloads, email.BatchOptions{
+		DedupeRecipients: dedupe,
+		Concurrency:      h.batchConcurrency,
+	})
+
+	results := make([]batchItemResult, len(payloads))
+	succeeded, skipped := 0, 0
+	for i := range payloads {
+		results[i] = batchItemResult{Index: i}
+		switch err := errs[i]; {
+		case errors.Is(err, email.ErrDuplicateRecipient):
+			results[i].Status = "skipped"
+			results[i].Error = err.Error()
+			skipped++
+		case errors.Is(err, email.ErrPublishQueued):
+			results[i].Status = "queued"
+			results[i].ID = ids[i]
+			succeeded++
+		case err != nil:
+			results[i].Status = "error"
+			results[i].Error = err.Error()
+		default:
+			results[i].Status = "published"
+			results[i].ID = ids[i]
+			succeeded++
+		}
+	}
+
+	writeJSONSuccess(w, r, http.StatusOK, map[string]interface{}{
+		"message":   fmt.Sprintf("%d de %d mensagens publicadas", succeeded, len(payloads)),
+		"succeeded": succeeded,
+		"skipped":   skipped,
+		"failed":    len(payloads) - succeeded - skipped,
+		"results":   results,
+	})
+}
+
+// ValidateEmails handles POST /validate-emails requests, reporting which emails of a JSON array are valid without publishing them
+func (h *EmailHandler) ValidateEmails(w http.ResponseWriter, r *http.Request) {
+	var payloads []*models.EmailPayload
+	if err := decodeJSON(r.Body, &payloads); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(payloads) == 0 {
+		writeError(w, r, "At least one email is required", http.StatusBadRequest)
+		return
+	}
+	if len(payloads) > maxBatchSize {
+		writeError(w, r, fmt.Sprintf("Too many emails: maximum is %d per request", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	results := models.ValidateEmailBatch(payloads)
+	valid := 0
+	for _, result := range results {
+		if result.Valid {
+			valid++
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSONSuccess(w, r, http.StatusOK, map[string]interface{}{
+		"message": fmt.Sprintf("%d de %d mensagens válidas", valid, len(payloads)),
+		"valid":   valid,
+		"invalid": len(payloads) - valid,
+		"results": results,
+	})
 }
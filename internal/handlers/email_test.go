@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/dedup"
+	"go_integration/internal/email"
+	"go_integration/internal/models"
+)
+
+// fakePublisher records the published messages, failing with err when it is set
+type fakePublisher struct {
+	mu    sync.Mutex
+	err   error
+	data  [][]byte
+	attrs []map[string]string
+}
+
+func (p *fakePublisher) Publish(_ context.Context, data []byte, attrs map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return "", p.err
+	}
+	p.data = append(p.data, data)
+	p.attrs = append(p.attrs, attrs)
+	return "msg-" + strconv.Itoa(len(p.data)), nil
+}
+
+func (p *fakePublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.data)
+}
+
+func TestSendEmailStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		body       string
+		publishErr error
+		wantStatus int
+		wantFields []string
+	}{
+		{
+			name:       "published",
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid recipient",
+			format:     ErrorFormatProblem,
+			body:       `{"to":"not-an-address","subject":"Oi","body":"Olá"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []string{""},
+		},
+		{
+			name:       "invalid priority and template",
+			format:     ErrorFormatProblem,
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá","priority":"urgent","template":"nope"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []string{"template", "priority"},
+		},
+		{
+			name:       "invalid payload in plain format",
+			format:     ErrorFormatPlain,
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá","priority":"urgent"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "publish failure",
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`,
+			publishErr: errors.New("unavailable"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEmailHandler(email.NewService(&fakePublisher{err: tt.publishErr}))
+			req := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			ErrorFormat(tt.format, http.HandlerFunc(handler.SendEmail)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+			if tt.format != ErrorFormatProblem {
+				if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+					t.Errorf("Content-Type = %q, want text/plain", ct)
+				}
+				return
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", ct)
+			}
+			var problem problemDetails
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			if problem.Status != http.StatusBadRequest || len(problem.Errors) != len(tt.wantFields) {
+				t.Fatalf("problem = %+v, want %d errors", problem, len(tt.wantFields))
+			}
+			for i, field := range tt.wantFields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("errors[%d].field = %q, want %q", i, problem.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestSendEmailIdempotencyKey(t *testing.T) {
+	const body = `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`
+	const otherBody = `{"to":"ana@example.com","subject":"Oi","body":"Tchau"}`
+
+	tests := []struct {
+		name        string
+		first       string
+		firstErr    error
+		queue       bool
+		retry       string
+		wantStatus  int
+		wantPublish int
+	}{
+		{name: "same body replays", first: body, retry: body, wantStatus: http.StatusOK, wantPublish: 1},
+		{name: "different body is rejected", first: body, retry: otherBody, wantStatus: http.StatusUnprocessableEntity, wantPublish: 1},
+		{name: "failed first request can be retried", first: body, firstErr: errors.New("unavailable"), retry: body, wantStatus: http.StatusOK, wantPublish: 1},
+		{name: "queued first request replays its tracking ID", first: body, firstErr: errors.New("unavailable"), queue: true, retry: body, wantStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{err: tt.firstErr}
+			service := email.NewService(publisher)
+			if tt.queue {
+				// The outbox is not run, so a queued email is never republished
+				service.SetOutbox(email.NewMemoryOutbox(publisher, email.DefaultOutboxConfig()))
+			}
+			handler := NewEmailHandler(service)
+			handler.SetIdempotencyStore(dedup.NewMemoryIdempotencyStore(time.Minute))
+
+			send := func(body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(body))
+				req.Header.Set(IdempotencyKeyHeader, "key-1")
+				rec := httptest.NewRecorder()
+				handler.SendEmail(rec, req)
+				return rec
+			}
+
+			first := send(tt.first)
+			publisher.mu.Lock()
+			publisher.err = nil
+			publisher.mu.Unlock()
+
+			rec := send(tt.retry)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := publisher.count(); got != tt.wantPublish {
+				t.Errorf("published %d emails, want %d", got, tt.wantPublish)
+			}
+			if tt.queue && !strings.Contains(rec.Body.String(), trackingID(t, first)) {
+				t.Errorf("replay %s does not carry the first tracking ID", rec.Body)
+			}
+		})
+	}
+}
+
+// trackingID returns the tracking ID of a queued /send-email response
+func trackingID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response struct {
+		Data struct {
+			TrackingID string `json:"tracking_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Data.TrackingID == "" {
+		t.Fatalf("response %s has no tracking ID: %v", rec.Body, err)
+	}
+	return response.Data.TrackingID
+}
+
+func TestSendEmailIdempotencyKeyInFlight(t *testing.T) {
+	const body = `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`
+	store := dedup.NewMemoryIdempotencyStore(time.Minute)
+	hash := sha256.Sum256([]byte(body))
+	store.Reserve(context.Background(), "key-1", hex.EncodeToString(hash[:]))
+
+	publisher := &fakePublisher{}
+	handler := NewEmailHandler(email.NewService(publisher))
+	handler.SetIdempotencyStore(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	rec := httptest.NewRecorder()
+	handler.SendEmail(rec, req)
+
+	if rec.Code != http.StatusConflict || publisher.count() != 0 {
+		t.Errorf("status = %d with %d publishes, want %d without publishing", rec.Code, publisher.count(), http.StatusConflict)
+	}
+}
+
+func TestValidateEmails(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantValid   int
+		wantInvalid int
+	}{
+		{
+			name:        "mixed batch",
+			body:        `[{"to":"ana@example.com","subject":"Oi","body":"Olá"},{"to":"ana","subject":"Oi","body":"Olá"},{"to":"bia@example.com"}]`,
+			wantStatus:  http.StatusOK,
+			wantValid:   1,
+			wantInvalid: 2,
+		},
+		{name: "empty batch", body: `[]`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", body: `[{"to":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			handler := NewEmailHandler(email.NewService(publisher))
+			req := httptest.NewRequest(http.MethodPost, "/validate-emails", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ValidateEmails(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if publisher.count() != 0 {
+				t.Error("validating emails published messages")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					Valid   int                            `json:"valid"`
+					Invalid int                            `json:"invalid"`
+					Results []models.BatchValidationResult `json:"results"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Data.Valid != tt.wantValid || response.Data.Invalid != tt.wantInvalid || len(response.Data.Results) != tt.wantValid+tt.wantInvalid {
+				t.Errorf("report = %+v, want %d valid and %d invalid", response.Data, tt.wantValid, tt.wantInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// TopicChecker reports whether a Pub/Sub topic exists
+type TopicChecker interface {
+	TopicExists(ctx context.Context, topicID string) (bool, error)
+}
+
+// TopicHealth handles GET /health/topics requests, reporting the existence of each configured topic
+func TopicHealth(checker TopicChecker, topics []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := make(map[string]bool, len(topics))
+		healthy := true
+
+		for _, topicID := range topics {
+			exists, err := checker.TopicExists(r.Context(), topicID)
+			if err != nil {
+				slog.Error("Failed to check topic existence", "topic", topicID, "error", err)
+			}
+			results[topicID] = exists
+			if !exists {
+				healthy = false
+			}
+		}
+
+		status := "healthy"
+		statusCode := http.StatusOK
+		if !healthy {
+			status = "unhealthy"
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"topics": results,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeTopicChecker reports the topics it holds as existing, failing every check with err when it is set
+type fakeTopicChecker struct {
+	existing map[string]bool
+	err      error
+}
+
+func (c fakeTopicChecker) TopicExists(_ context.Context, topicID string) (bool, error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	return c.existing[topicID], nil
+}
+
+func TestTopicHealth(t *testing.T) {
+	topics := []string{"emails", "users"}
+
+	tests := []struct {
+		name       string
+		checker    fakeTopicChecker
+		wantStatus int
+		wantBody   string
+		wantTopics map[string]bool
+	}{
+		{
+			name:       "every topic exists",
+			checker:    fakeTopicChecker{existing: map[string]bool{"emails": true, "users": true}},
+			wantStatus: http.StatusOK,
+			wantBody:   "healthy",
+			wantTopics: map[string]bool{"emails": true, "users": true},
+		},
+		{
+			name:       "missing topic",
+			checker:    fakeTopicChecker{existing: map[string]bool{"emails": true}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "unhealthy",
+			wantTopics: map[string]bool{"emails": true, "users": false},
+		},
+		{
+			name:       "client error",
+			checker:    fakeTopicChecker{err: errors.New("permission denied")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "unhealthy",
+			wantTopics: map[string]bool{"emails": false, "users": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			TopicHealth(tt.checker, topics).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/topics", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var body struct {
+				Status string          `json:"status"`
+				Topics map[string]bool `json:"topics"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Status != tt.wantBody {
+				t.Errorf("status = %q, want %q", body.Status, tt.wantBody)
+			}
+			for topic, want := range tt.wantTopics {
+				if got, ok := body.Topics[topic]; !ok || got != want {
+					t.Errorf("topics[%s] = %v, want %v", topic, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"go_integration/internal/pubsub"
+)
+
+// PublishRaw handles POST /publish/{topic} requests, publishing the raw JSON body to one of the allowed topics
+func PublishRaw(publishers map[string]pubsub.Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		topicID := r.PathValue("topic")
+		publisher, ok := publishers[topicID]
+		if !ok {
+			writeError(w, r, fmt.Sprintf("Unknown topic: %s", topicID), http.StatusNotFound)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, fmt.Sprintf("Failed to read body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if !json.Valid(body) {
+			writeError(w, r, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		id, err := publisher.Publish(r.Context(), body, nil)
+		if err != nil {
+			log.Printf("Failed to publish raw message to %s: %v", topicID, err)
+			writeError(w, r, "Failed to publish message", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Raw message %s published to %s", id, topicID)
+
+		writeJSONSuccess(w, r, http.StatusOK, map[string]string{
+			"message": fmt.Sprintf("Mensagem publicada com ID: %s", id),
+			"id":      id,
+			"topic":   topicID,
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_integration/internal/pubsub"
+)
+
+func TestPublishRaw(t *testing.T) {
+	tests := []struct {
+		name        string
+		topic       string
+		body        string
+		publishErr  error
+		wantStatus  int
+		wantPublish int
+	}{
+		{name: "configured topic", topic: "emails", body: `{"to":"ana@example.com"}`, wantStatus: http.StatusOK, wantPublish: 1},
+		{name: "unknown topic", topic: "other", body: `{}`, wantStatus: http.StatusNotFound},
+		{name: "invalid JSON", topic: "emails", body: `{"to":`, wantStatus: http.StatusBadRequest},
+		{name: "publish failure", topic: "emails", body: `{}`, publishErr: errors.New("unavailable"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{err: tt.publishErr}
+			mux := http.NewServeMux()
+			mux.Handle("POST /publish/{topic}", PublishRaw(map[string]pubsub.Publisher{"emails": publisher}))
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish/"+tt.topic, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := publisher.count(); got != tt.wantPublish {
+				t.Errorf("published %d messages, want %d", got, tt.wantPublish)
+			}
+			// The body is forwarded unchanged
+			if tt.wantPublish == 1 && string(publisher.data[0]) != tt.body {
+				t.Errorf("published %s, want %s", publisher.data[0], tt.body)
+			}
+		})
+	}
+}
@@ -4,148 +4,464 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
+	"go_integration/internal/alert"
+	"go_integration/internal/dedup"
 	"go_integration/internal/email"
+	"go_integration/internal/logging"
 	"go_integration/internal/models"
 )
 
+// WelcomePublisher publishes welcome email messages for asynchronous delivery
+type WelcomePublisher interface {
+	PublishWelcome(ctx context.Context, payload *models.WelcomeEmailPayload) error
+}
+
+// VerificationPublisher publishes verification email messages for asynchronous delivery
+type VerificationPublisher interface {
+	PublishVerificationEmail(ctx context.Context, payload *models.VerificationEmailPayload) error
+}
+
+// RetryConfigs holds the retry settings of each message type; a config without attempts uses email.DefaultRetryConfig
+type RetryConfigs struct {
+	Email        email.RetryConfig
+	Verification email.RetryConfig
+	Welcome      email.RetryConfig
+	User         email.RetryConfig // Retries publishing the welcome and verification emails of a new user
+}
+
+// withDefaults fills the configs without attempts with email.DefaultRetryConfig
+func (c RetryConfigs) withDefaults() RetryConfigs {
+	for _, cfg := range []*email.RetryConfig{&c.Email, &c.Verification, &c.Welcome, &c.User} {
+		if cfg.MaxAttempts <= 0 {
+			maxElapsed := cfg.MaxElapsed
+			*cfg = email.DefaultRetryConfig()
+			cfg.MaxElapsed = maxElapsed
+		}
+	}
+	return c
+}
+
+// QueueHandlerOptions holds optional dependencies for the EmailQueueHandler
+type QueueHandlerOptions struct {
+	// SeenUsers skips the steps of user creation messages (welcome and verification publishes) already processed for
+	// the user ID, keyed "<id>:<step>" (nil disables deduplication)
+	SeenUsers dedup.SeenStore
+
+	// Brand is rendered in the email templates (zero value uses the NorthFi branding)
+	Brand email.BrandConfig
+
+	// Renderer renders the email templates (nil uses the shared renderer)
+	Renderer *email.TemplateRenderer
+
+	// EmailRenderer renders regular emails (nil uses an email.DefaultRenderer over Renderer and Brand)
+	EmailRenderer email.Renderer
+
+	// BodyMode is email.BodyModeRich to keep allowlisted HTML in email bodies (empty escapes every tag)
+	BodyMode string
+
+	// Clock paces the delays between retries (nil uses the system clock)
+	Clock email.Clock
+
+	// Sender addresses for verification and welcome emails (empty uses the default sender)
+	FromVerification string
+	FromWelcome      string
+
+	// FromByLocale maps a language ("en", "pt") to the sender address of emails in that locale,
+	// taking precedence over the per-type senders above
+	FromByLocale map[string]string
+
+	// FromByRecipientDomain maps a recipient domain ("gmail.com") to the sender address used for it,
+	// taking precedence over every other sender
+	FromByRecipientDomain map[string]string
+
+	// MaxBodyLength truncates longer email bodies before rendering (0 disables the limit)
+	MaxBodyLength int
+
+	// RetryJitter randomly shifts each retry delay by up to ±RetryJitter (0 keeps the fixed delay)
+	RetryJitter time.Duration
+
+	// Retries sets the attempts and delay per message type
+	Retries RetryConfigs
+
+	// VerificationPublisher queues the verification email of new users that carry a code or verify URL (nil skips it)
+	VerificationPublisher VerificationPublisher
+
+	// FailureNotifier alerts ops when a message is dropped after every retry failed (nil disables alerts)
+	FailureNotifier alert.FailureNotifier
+}
+
 // EmailQueueHandler handles email queue message processing
 type EmailQueueHandler struct {
-	emailService *email.ResendService
+	emailService     email.Sender
+	welcomePublisher WelcomePublisher
+	seenUsers        dedup.SeenStore
+	brand            email.BrandConfig
+	renderer         *email.TemplateRenderer
+	emailRenderer    email.Renderer
+	markdownRenderer email.Renderer
+	clock            email.Clock
+	fromVerification string
+	fromWelcome      string
+	fromByLocale     map[string]string
+	fromByDomain     map[string]string
+	maxBodyLength    int
+	retryJitter      time.Duration
+	retries          RetryConfigs
+	failureNotifier  alert.FailureNotifier
+
+	verificationPublisher VerificationPublisher
 }
 
 // NewEmailQueueHandler creates a new email queue handler
-func NewEmailQueueHandler(emailService *email.ResendService) *EmailQueueHandler {
-	return &EmailQueueHandler{
-		emailService: emailService,
+func NewEmailQueueHandler(emailService email.Sender, welcomePublisher WelcomePublisher, opts QueueHandlerOptions) *EmailQueueHandler {
+	brand := opts.Brand
+	if brand.CompanyName == "" {
+		brand = email.DefaultBrandConfig()
 	}
-}
 
-// retry executes a function with retry logic using structured logging
-func (h *EmailQueueHandler) retry(ctx context.Context, maxRetries int, delay time.Duration, fn func() error, logger *slog.Logger, operation string) error {
-	var lastErr error
+	renderer := opts.Renderer
+	if renderer == nil {
+		var err error
+		if renderer, err = email.SharedTemplateRenderer(); err != nil {
+			slog.Error("Failed to load email templates", "error", err)
+		}
+	}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		attemptLogger := logger.With(
-			"attempt", attempt,
-			"max_retries", maxRetries,
-			"operation", operation,
-		)
+	emailRenderer := opts.EmailRenderer
+	if emailRenderer == nil {
+		emailRenderer = email.NewDefaultRenderer(renderer, brand, opts.BodyMode)
+	}
 
-		attemptLogger.Info("Starting attempt")
+	clock := opts.Clock
+	if clock == nil {
+		clock = email.RealClock{}
+	}
 
-		err := fn()
-		if err == nil {
-			attemptLogger.Info("Operation completed successfully")
-			return nil
-		}
+	failureNotifier := opts.FailureNotifier
+	if failureNotifier == nil {
+		failureNotifier = alert.NopNotifier{}
+	}
 
-		lastErr = err
-		attemptLogger.Error("Operation failed", "error", err)
+	return &EmailQueueHandler{
+		emailService:     emailService,
+		welcomePublisher: welcomePublisher,
+		seenUsers:        opts.SeenUsers,
+		brand:            brand,
+		renderer:         renderer,
+		emailRenderer:    emailRenderer,
+		markdownRenderer: email.NewMarkdownRenderer(renderer, brand),
+		clock:            clock,
+		fromVerification: opts.FromVerification,
+		fromWelcome:      opts.FromWelcome,
+		fromByLocale:     opts.FromByLocale,
+		fromByDomain:     normalizeKeys(opts.FromByRecipientDomain),
+		maxBodyLength:    opts.MaxBodyLength,
+		retryJitter:      opts.RetryJitter,
+		retries:          opts.Retries.withDefaults(),
+		failureNotifier:  failureNotifier,
 
-		// If this is not the last attempt, wait before retrying
-		if attempt < maxRetries {
-			attemptLogger.Info("Waiting before retry", "delay", delay)
-			time.Sleep(delay)
-		}
+		verificationPublisher: opts.VerificationPublisher,
+	}
+}
+
+// retry executes a function with retry logic using structured logging, acknowledging the message once every attempt failed
+func (h *EmailQueueHandler) retry(ctx context.Context, config email.RetryConfig, fn func() error, logger *slog.Logger, operation string) error {
+	outcome := h.attempt(ctx, config, fn, logger, operation)
+	if outcome.Succeeded || outcome.Aborted {
+		return outcome.Err()
 	}
 
 	logger.Error("All retry attempts failed",
 		"operation", operation,
-		"max_retries", maxRetries,
-		"last_error", lastErr,
+		"attempts", outcome.Attempts,
+		"max_attempts", config.MaxAttempts,
+		"last_error", outcome.LastErr,
 	)
 
+	failure := alert.Failure{
+		Timestamp:   time.Now().UTC(),
+		Operation:   operation,
+		MessageID:   logging.MessageID(ctx),
+		MaxAttempts: config.MaxAttempts,
+		Error:       outcome.LastErr.Error(),
+	}
+	if notifyErr := h.failureNotifier.NotifyFailure(ctx, failure); notifyErr != nil {
+		logger.Error("Failed to send failure alert", "operation", operation, "error", notifyErr)
+	}
+
 	// Return nil to acknowledge the message and remove it from queue
 	// Even though sending failed, we don't want to keep retrying indefinitely
 	return nil
 }
 
+// attempt runs fn through email.ExecuteWithRetryResult with the handler clock and jitter
+func (h *EmailQueueHandler) attempt(ctx context.Context, config email.RetryConfig, fn func() error, logger *slog.Logger, operation string) email.RetryOutcome {
+	if config.Clock == nil {
+		config.Clock = h.clock
+	}
+	if config.Jitter == 0 {
+		config.Jitter = h.retryJitter
+	}
+	return email.ExecuteWithRetryResult(ctx, config, fn, logger.With("operation", operation))
+}
+
 // HandleEmailMessage processes and sends a regular email message with retry logic
 func (h *EmailQueueHandler) HandleEmailMessage(ctx context.Context, payload *models.EmailPayload) error {
-	logger := slog.With(
-		"recipient", payload.To,
+	logger := logging.FromContext(ctx).With(
+		"recipient", logging.Recipient(payload.To),
 		"subject", payload.Subject,
 		"type", "regular_email",
 	)
 
-	logger.Info("Processing regular email message")
+	if body, truncated := email.TruncateBody(payload.Body, h.maxBodyLength); truncated {
+		logger.Warn("Email body exceeds the maximum length, truncating",
+			"body_length", len(payload.Body),
+			"max_body_length", h.maxBodyLength,
+		)
+		payload.Body = body
+	}
+
+	templateName := email.TemplateFor(payload)
+	logger.Info("Processing regular email message",
+		"content_type", payload.ContentType,
+		"template", templateName,
+	)
+
+	return h.retry(ctx, h.retries.Email, func() error {
+		opts := email.SendOptions{
+			TrackOpens:  payload.TrackOpens,
+			TrackClicks: payload.TrackClicks,
+			Tags:        []email.Tag{email.TypeTag(templateName)},
+			From:        h.fromForRecipient(payload.To, h.fromForLocale(payload.Locale, h.fromFor(templateName))),
+			ScheduledAt: payload.ScheduledAt,
+			Headers:     payload.Headers,
+		}
 
-	return h.retry(ctx, 3, 2*time.Second, func() error {
-		htmlContent := email.GetDefaultEmailHTML(payload.Subject, payload.Body, "NorthFi")
-		return h.emailService.SendEmailWithHTML(payload.To, payload.Subject, htmlContent)
+		// Plain text emails skip the templates but keep every per-message option
+		if payload.IsText() {
+			opts.Text = payload.Body
+			return h.emailService.SendEmailWithOptions(ctx, payload.To, payload.Subject, "", opts)
+		}
+
+		subject, htmlContent, err := h.rendererFor(payload).Render(payload)
+		if err != nil {
+			return err
+		}
+
+		opts.Text = h.plainTextFor(templateName, payload)
+		opts.Attachments = h.brand.Attachments()
+		return h.emailService.SendEmailWithOptions(ctx, payload.To, subject, htmlContent, opts)
 	}, logger, "send_regular_email")
 }
 
+// rendererFor returns the renderer for the payload body format
+func (h *EmailQueueHandler) rendererFor(payload *models.EmailPayload) email.Renderer {
+	if payload.IsMarkdown() {
+		return h.markdownRenderer
+	}
+	return h.emailRenderer
+}
+
+// plainTextFor returns the plain text alternative for the default template (other templates send HTML only)
+func (h *EmailQueueHandler) plainTextFor(templateName string, payload *models.EmailPayload) string {
+	if templateName != email.TemplateDefault {
+		return ""
+	}
+	return payload.GeneratePlainText()
+}
+
+// fromFor returns the sender address configured for templateName (empty uses the default sender)
+func (h *EmailQueueHandler) fromFor(templateName string) string {
+	switch templateName {
+	case email.TemplateWelcome:
+		return h.fromWelcome
+	case email.TemplateVerification, email.TemplateVerificationLink:
+		return h.fromVerification
+	}
+	return ""
+}
+
+// fromForLocale returns the sender address configured for the language of locale (e.g. "en" for "en-US"),
+// falling back to fallback when the locale is empty or has no sender of its own
+func (h *EmailQueueHandler) fromForLocale(locale, fallback string) string {
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	if from, ok := h.fromByLocale[language]; ok && language != "" {
+		return from
+	}
+	return fallback
+}
+
+// fromForRecipient returns the sender address configured for the domain of the recipient address to,
+// falling back to fallback when its domain has no sender of its own
+func (h *EmailQueueHandler) fromForRecipient(to, fallback string) string {
+	at := strings.LastIndex(to, "@")
+	if at < 0 {
+		return fallback
+	}
+	if from, ok := h.fromByDomain[strings.ToLower(strings.TrimSpace(to[at+1:]))]; ok {
+		return from
+	}
+	return fallback
+}
+
+// normalizeKeys returns a copy of m with trimmed, lowercased keys
+func normalizeKeys(m map[string]string) map[string]string {
+	normalized := make(map[string]string, len(m))
+	for key, value := range m {
+		normalized[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return normalized
+}
+
 // HandleWelcomeMessage processes and sends a welcome email with retry logic
-func (h *EmailQueueHandler) HandleWelcomeMessage(ctx context.Context, payload *models.EmailPayload, userName string) error {
-	logger := slog.With(
-		"recipient", payload.To,
-		"subject", payload.Subject,
-		"user_name", userName,
+func (h *EmailQueueHandler) HandleWelcomeMessage(ctx context.Context, payload *models.WelcomeEmailPayload) error {
+	logger := logging.FromContext(ctx).With(
+		"recipient", logging.Recipient(payload.Email),
+		"user_name", payload.Name,
+		"locale", payload.GetLocale(),
+		"user_id", payload.UserID,
+		"segment", payload.Segment,
 		"type", "welcome_email",
 	)
 
 	logger.Info("Processing welcome email message")
 
-	return h.retry(ctx, 3, 2*time.Second, func() error {
-		htmlContent := email.GetWelcomeEmailHTML(userName, "NorthFi")
-		return h.emailService.SendEmailWithHTML(payload.To, payload.Subject, htmlContent)
+	tags := []email.Tag{email.TypeTag("welcome")}
+	if payload.UserID != "" {
+		tags = append(tags, email.UserTag(payload.UserID))
+	}
+
+	return h.retry(ctx, h.retries.Welcome, func() error {
+		htmlContent, err := h.renderer.Render(email.WelcomeTemplateFor(payload.Segment), email.WelcomeEmailData{
+			Username: payload.Name,
+			CTA:      email.CTA{Text: payload.CTAText, URL: payload.CTAURL},
+			Brand:    h.brand,
+		})
+		if err != nil {
+			return err
+		}
+
+		return h.emailService.SendEmailWithOptions(ctx, payload.Email, payload.GenerateSubject(), htmlContent, email.SendOptions{
+			Tags:        tags,
+			From:        h.fromForRecipient(payload.Email, h.fromForLocale(payload.Locale, h.fromWelcome)),
+			Attachments: h.brand.Attachments(),
+		})
 	}, logger, "send_welcome_email")
 }
 
 // HandleVerificationMessage processes and sends a verification email message with retry logic
 func (h *EmailQueueHandler) HandleVerificationMessage(ctx context.Context, payload *models.VerificationEmailPayload) error {
-	logger := slog.With(
-		"recipient", payload.To,
+	logger := logging.FromContext(ctx).With(
+		"recipient", logging.Recipient(payload.To),
 		"username", payload.Username,
-		"has_code", payload.Code != "",
+		"has_code", payload.VerificationCode() != "",
 		"has_url", payload.VerifyURL != "",
 		"type", "verification_email",
 	)
 
 	logger.Info("Processing verification email message")
 
-	return h.retry(ctx, 3, 2*time.Second, func() error {
-		// Use verification code if available, otherwise fall back to URL
-		verificationData := payload.Code
-		if verificationData == "" {
-			verificationData = payload.VerifyURL
+	return h.retry(ctx, h.retries.Verification, func() error {
+		// Show the code when there is one, otherwise the verification link
+		templateName := email.TemplateVerification
+		if payload.UsesLink() {
+			templateName = email.TemplateVerificationLink
+		}
+
+		htmlContent, err := h.renderer.Render(templateName, email.VerificationEmailData{
+			Username:  payload.Username,
+			Code:      payload.VerificationCode(),
+			VerifyURL: payload.VerifyURL,
+			ExpiresIn: payload.ExpiresIn(),
+			Brand:     h.brand,
+		})
+		if err != nil {
+			return err
 		}
 
-		htmlContent := email.GetVerificationEmailHTML(payload.Username, "NorthFi", verificationData)
-		return h.emailService.SendEmailWithHTML(payload.To, payload.GenerateSubject(), htmlContent)
+		return h.emailService.SendEmailWithOptions(ctx, payload.To, payload.GenerateSubject(), htmlContent, email.SendOptions{
+			Tags:        []email.Tag{email.TypeTag("verification")},
+			From:        h.fromForRecipient(payload.To, h.fromVerification),
+			Attachments: h.brand.Attachments(),
+		})
 	}, logger, "send_verification_email")
 }
 
-// HandleUserMessage processes a user creation message and sends a welcome email
+// HandleUserMessage processes a user creation message and publishes a welcome email.
+// Each publish is deduplicated on its own, so a redelivery after a failed step only redoes that step.
 func (h *EmailQueueHandler) HandleUserMessage(ctx context.Context, payload *models.UserPayload) error {
-	logger := slog.With(
+	logger := logging.FromContext(ctx).With(
 		"user_id", payload.ID,
-		"user_email", payload.Email,
+		"user_email", logging.Recipient(payload.Email),
 		"user_name", payload.Name,
+		"segment", payload.Segment,
 		"type", "user_creation",
 	)
 
 	logger.Info("Processing user creation message")
 
-	// Create welcome email payload
-	welcomeEmail := &models.EmailPayload{
-		To:      payload.Email,
-		Subject: "Bem-vindo(a) à NorthFi!",
-		Body:    fmt.Sprintf("Olá %s, seja bem-vindo(a) à NorthFi! Sua conta foi criada com sucesso.", payload.Name),
+	// Queue the welcome email so it is delivered and retried independently
+	welcome := models.NewWelcomeEmailPayload(payload)
+	if err := h.userStep(ctx, logger, payload.ID, "welcome", func() error {
+		logger.Info("Publishing welcome email for new user", "recipient", logging.Recipient(payload.Email))
+		return h.attempt(ctx, h.retries.User, func() error {
+			return h.welcomePublisher.PublishWelcome(ctx, welcome)
+		}, logger, "publish_welcome_email").Err()
+	}); err != nil {
+		return fmt.Errorf("failed to publish welcome email for user %s: %w", payload.ID, err)
 	}
 
-	logger.Info("Sending welcome email for new user", "recipient", payload.Email)
-
-	// Send welcome email using the welcome email handler
-	err := h.HandleWelcomeMessage(ctx, welcomeEmail, payload.Name)
-	if err != nil {
-		logger.Error("Failed to send welcome email", "error", err)
-		return fmt.Errorf("failed to send welcome email for user %s: %w", payload.ID, err)
+	if payload.NeedsVerification() {
+		if h.verificationPublisher == nil {
+			logger.Warn("Verification publisher not configured, skipping verification email")
+		} else if err := h.userStep(ctx, logger, payload.ID, "verification", func() error {
+			logger.Info("Publishing verification email for new user", "recipient", logging.Recipient(payload.Email))
+			verification := models.NewVerificationEmailPayload(payload, time.Now())
+			return h.attempt(ctx, h.retries.User, func() error {
+				return h.verificationPublisher.PublishVerificationEmail(ctx, verification)
+			}, logger, "publish_verification_email").Err()
+		}); err != nil {
+			return fmt.Errorf("failed to publish verification email for user %s: %w", payload.ID, err)
+		}
 	}
 
 	logger.Info("User creation processed successfully")
 	return nil
 }
+
+// userStep runs the step of a user creation message unless it already succeeded for userID. A failed step
+// is forgotten so the redelivered message retries it, while the steps that succeeded are skipped.
+func (h *EmailQueueHandler) userStep(ctx context.Context, logger *slog.Logger, userID, step string, run func() error) error {
+	key := userID + ":" + step
+	if h.seenUsers != nil {
+		seen, err := h.seenUsers.MarkSeen(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check user %s in dedup store: %w", userID, err)
+		}
+		if seen {
+			logger.Info("User step already processed, skipping", "step", step)
+			return nil
+		}
+	}
+
+	if err := run(); err != nil {
+		logger.Error("User step failed", "step", step, "error", err)
+		h.forgetUser(ctx, logger, key)
+		return err
+	}
+	return nil
+}
+
+// forgetUser removes the dedup key of a user step so the redelivered message processes it again
+func (h *EmailQueueHandler) forgetUser(ctx context.Context, logger *slog.Logger, key string) {
+	if h.seenUsers == nil {
+		return
+	}
+	if err := h.seenUsers.Forget(ctx, key); err != nil {
+		logger.Error("Failed to forget user in dedup store", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,928 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/alert"
+	"go_integration/internal/dedup"
+	"go_integration/internal/email"
+	"go_integration/internal/models"
+)
+
+// fakeClock advances its time on every Sleep instead of waiting
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+	return nil
+}
+
+// fakeSender fails the first len(errs) sends with those errors and records every send
+type fakeSender struct {
+	mu    sync.Mutex
+	errs  []error
+	calls []sentMessage
+}
+
+// sentMessage is an email captured by fakeSender
+type sentMessage struct {
+	To, Subject, HTML string
+	Opts              email.SendOptions
+}
+
+func (s *fakeSender) send(to, subject, htmlBody string, opts email.SendOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, sentMessage{To: to, Subject: subject, HTML: htmlBody, Opts: opts})
+	if i := len(s.calls) - 1; i < len(s.errs) {
+		return s.errs[i]
+	}
+	return nil
+}
+
+func (s *fakeSender) SendText(to, subject, body string) error {
+	return s.send(to, subject, "", email.SendOptions{Text: body})
+}
+
+func (s *fakeSender) SendEmailContext(_ context.Context, to, subject, body string) error {
+	return s.send(to, subject, "", email.SendOptions{Text: body})
+}
+
+func (s *fakeSender) SendEmailWithHTMLContext(_ context.Context, to, subject, htmlBody string) error {
+	return s.send(to, subject, htmlBody, email.SendOptions{})
+}
+
+func (s *fakeSender) SendEmailWithOptions(_ context.Context, to, subject, htmlBody string, opts email.SendOptions) error {
+	return s.send(to, subject, htmlBody, opts)
+}
+
+func (s *fakeSender) sent() []sentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentMessage(nil), s.calls...)
+}
+
+// recordingNotifier keeps the failures it is notified of
+type recordingNotifier struct {
+	mu       sync.Mutex
+	failures []alert.Failure
+}
+
+func (n *recordingNotifier) NotifyFailure(_ context.Context, failure alert.Failure) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.failures = append(n.failures, failure)
+	return nil
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.failures)
+}
+
+func TestHandleEmailMessageRetries(t *testing.T) {
+	transient := errors.New("connection reset")
+	permanent := &email.ResendAPIError{StatusCode: http.StatusUnprocessableEntity, Name: "validation_error"}
+	rateLimited := &email.ResendAPIError{StatusCode: http.StatusTooManyRequests, RetryAfterDelay: 5 * time.Second}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		config       email.RetryConfig
+		wantAttempts int
+		wantSleeps   []time.Duration
+		wantAlert    bool
+	}{
+		{
+			name:         "succeeds first time",
+			config:       email.RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after a transient error",
+			errs:         []error{transient},
+			config:       email.RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{time.Second},
+		},
+		{
+			name:         "exhausts every attempt",
+			errs:         []error{transient, transient, transient},
+			config:       email.RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 3,
+			wantSleeps:   []time.Duration{time.Second, time.Second},
+			wantAlert:    true,
+		},
+		{
+			name:         "permanent error stops retrying",
+			errs:         []error{permanent},
+			config:       email.RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 1,
+			wantAlert:    true,
+		},
+		{
+			name:         "honors Retry-After",
+			errs:         []error{rateLimited},
+			config:       email.RetryConfig{MaxAttempts: 3, Delay: time.Second},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{5 * time.Second},
+		},
+		{
+			name:         "retry budget stops before waiting past it",
+			errs:         []error{transient, transient, transient},
+			config:       email.RetryConfig{MaxAttempts: 5, Delay: 2 * time.Second, MaxElapsed: 3 * time.Second},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{2 * time.Second},
+			wantAlert:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{errs: tt.errs}
+			clock := newFakeClock()
+			notifier := &recordingNotifier{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+				Clock:           clock,
+				FailureNotifier: notifier,
+				Retries:         RetryConfigs{Email: tt.config},
+			})
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"}
+			if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleEmailMessage returned %v, want nil so the message is acknowledged", err)
+			}
+
+			if got := len(sender.sent()); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if len(clock.sleeps) != len(tt.wantSleeps) {
+				t.Fatalf("sleeps = %v, want %v", clock.sleeps, tt.wantSleeps)
+			}
+			for i, want := range tt.wantSleeps {
+				if clock.sleeps[i] != want {
+					t.Errorf("sleep[%d] = %v, want %v", i, clock.sleeps[i], want)
+				}
+			}
+			if got := notifier.count() == 1; got != tt.wantAlert {
+				t.Errorf("alerted = %v, want %v", got, tt.wantAlert)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageCanceled(t *testing.T) {
+	sender := &fakeSender{errs: []error{errors.New("connection reset")}}
+	handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+		Clock:   newFakeClock(),
+		Retries: RetryConfigs{Email: email.RetryConfig{MaxAttempts: 3, Delay: time.Second}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A canceled message must be nacked so Pub/Sub redelivers it instead of dropping it
+	payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"}
+	if err := handler.HandleEmailMessage(ctx, payload); !errors.Is(err, context.Canceled) {
+		t.Errorf("HandleEmailMessage returned %v, want context.Canceled", err)
+	}
+}
+
+// countingPublisher counts the welcome and verification publishes, failing the first failFirst of each
+type countingPublisher struct {
+	mu            sync.Mutex
+	failFirst     map[string]int
+	welcomes      int
+	verifications int
+}
+
+func (p *countingPublisher) PublishWelcome(context.Context, *models.WelcomeEmailPayload) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.welcomes++
+	if p.welcomes <= p.failFirst["welcome"] {
+		return errors.New("publish failed")
+	}
+	return nil
+}
+
+func (p *countingPublisher) PublishVerificationEmail(context.Context, *models.VerificationEmailPayload) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.verifications++
+	if p.verifications <= p.failFirst["verification"] {
+		return errors.New("publish failed")
+	}
+	return nil
+}
+
+func TestHandleUserMessageRedeliveryRedoesOnlyTheFailedStep(t *testing.T) {
+	tests := []struct {
+		name              string
+		failFirst         map[string]int
+		wantWelcomes      int
+		wantVerifications int
+	}{
+		{name: "verification fails once", failFirst: map[string]int{"verification": 1}, wantWelcomes: 1, wantVerifications: 2},
+		{name: "welcome fails once", failFirst: map[string]int{"welcome": 1}, wantWelcomes: 2, wantVerifications: 1},
+		{name: "nothing fails", wantWelcomes: 1, wantVerifications: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &countingPublisher{failFirst: tt.failFirst}
+			handler := NewEmailQueueHandler(&fakeSender{}, publisher, QueueHandlerOptions{
+				SeenUsers:             dedup.NewMemoryStore(time.Hour),
+				Clock:                 newFakeClock(),
+				Retries:               RetryConfigs{User: email.RetryConfig{MaxAttempts: 1}},
+				VerificationPublisher: publisher,
+			})
+			user := &models.UserPayload{ID: "u1", Email: "ana@example.com", Name: "Ana", Code: "123456"}
+
+			// Deliver until the message is acknowledged, then once more as a duplicate
+			for range 3 {
+				handler.HandleUserMessage(context.Background(), user)
+			}
+
+			if publisher.welcomes != tt.wantWelcomes || publisher.verifications != tt.wantVerifications {
+				t.Errorf("welcomes = %d, verifications = %d, want %d and %d",
+					publisher.welcomes, publisher.verifications, tt.wantWelcomes, tt.wantVerifications)
+			}
+		})
+	}
+}
+
+func TestRetriesPerMessageType(t *testing.T) {
+	retries := RetryConfigs{
+		Email:        email.RetryConfig{MaxAttempts: 5, Delay: time.Second},
+		Verification: email.RetryConfig{MaxAttempts: 2, Delay: 100 * time.Millisecond},
+		Welcome:      email.RetryConfig{MaxAttempts: 3, Delay: 3 * time.Second},
+	}
+
+	tests := []struct {
+		name         string
+		handle       func(*EmailQueueHandler) error
+		wantAttempts int
+		wantDelay    time.Duration
+	}{
+		{
+			name: "email",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleEmailMessage(context.Background(), &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"})
+			},
+			wantAttempts: 5,
+			wantDelay:    time.Second,
+		},
+		{
+			name: "verification",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleVerificationMessage(context.Background(), &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"})
+			},
+			wantAttempts: 2,
+			wantDelay:    100 * time.Millisecond,
+		},
+		{
+			name: "welcome",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleWelcomeMessage(context.Background(), &models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com"})
+			},
+			wantAttempts: 3,
+			wantDelay:    3 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transient := errors.New("connection reset")
+			sender := &fakeSender{errs: slices.Repeat([]error{transient}, 10)}
+			clock := newFakeClock()
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: clock, Retries: retries})
+
+			if err := tt.handle(handler); err != nil {
+				t.Fatalf("handler returned %v, want nil so the message is acknowledged", err)
+			}
+
+			if got := len(sender.sent()); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if len(clock.sleeps) != tt.wantAttempts-1 || clock.sleeps[0] != tt.wantDelay {
+				t.Errorf("sleeps = %v, want %d sleeps of %v", clock.sleeps, tt.wantAttempts-1, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestSenderPerType(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(*EmailQueueHandler) error
+		wantFrom string
+	}{
+		{
+			name: "welcome",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleWelcomeMessage(context.Background(), &models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com"})
+			},
+			wantFrom: "hello@northfi.com.br",
+		},
+		{
+			name: "verification",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleVerificationMessage(context.Background(), &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"})
+			},
+			wantFrom: "verify@northfi.com.br",
+		},
+		{
+			name: "regular email keeps the default sender",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleEmailMessage(context.Background(), &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+				Clock:            newFakeClock(),
+				FromWelcome:      "hello@northfi.com.br",
+				FromVerification: "verify@northfi.com.br",
+			})
+			if err := tt.handle(handler); err != nil {
+				t.Fatalf("handler failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || sent[0].Opts.From != tt.wantFrom {
+				t.Errorf("sent %+v, want one email from %q", sent, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestSenderForLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		wantFrom string
+	}{
+		{name: "no locale keeps the welcome sender", wantFrom: "welcome@northfi.com.br"},
+		{name: "explicit default locale", locale: "pt-BR", wantFrom: "ola@northfi.com.br"},
+		{name: "explicit english locale", locale: "en-US", wantFrom: "hello@northfi.com.br"},
+		{name: "locale without a sender", locale: "es", wantFrom: "welcome@northfi.com.br"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+				Clock:        newFakeClock(),
+				FromWelcome:  "welcome@northfi.com.br",
+				FromByLocale: map[string]string{"pt": "ola@northfi.com.br", "en": "hello@northfi.com.br"},
+			})
+
+			payload := &models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com", Locale: tt.locale}
+			if err := handler.HandleWelcomeMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleWelcomeMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || sent[0].Opts.From != tt.wantFrom {
+				t.Errorf("sent %+v, want one email from %s", sent, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestSenderForRecipientDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		to       string
+		locale   string
+		wantFrom string
+	}{
+		{name: "mapped domain", to: "ana@gmail.com", wantFrom: "gmail@northfi.com.br"},
+		{name: "domain is matched case-insensitively", to: "ana@Gmail.COM", wantFrom: "gmail@northfi.com.br"},
+		{name: "domain wins over the locale", to: "ana@gmail.com", locale: "en", wantFrom: "gmail@northfi.com.br"},
+		{name: "unmapped domain falls back to the locale", to: "ana@example.com", locale: "en", wantFrom: "hello@northfi.com.br"},
+		{name: "unmapped domain falls back to the welcome sender", to: "ana@example.com", wantFrom: "welcome@northfi.com.br"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+				Clock:                 newFakeClock(),
+				FromWelcome:           "welcome@northfi.com.br",
+				FromByLocale:          map[string]string{"en": "hello@northfi.com.br"},
+				FromByRecipientDomain: map[string]string{" GMAIL.com ": "gmail@northfi.com.br"},
+			})
+
+			payload := &models.WelcomeEmailPayload{Name: "Ana", Email: tt.to, Locale: tt.locale}
+			if err := handler.HandleWelcomeMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleWelcomeMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || sent[0].Opts.From != tt.wantFrom {
+				t.Errorf("sent %+v, want one email from %s", sent, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestFromForRecipient(t *testing.T) {
+	handler := NewEmailQueueHandler(&fakeSender{}, nil, QueueHandlerOptions{
+		FromByRecipientDomain: map[string]string{"gmail.com": "gmail@northfi.com.br"},
+	})
+
+	tests := []struct {
+		name string
+		to   string
+		want string
+	}{
+		{name: "mapped domain", to: "ana@gmail.com", want: "gmail@northfi.com.br"},
+		{name: "trailing whitespace", to: "ana@gmail.com ", want: "gmail@northfi.com.br"},
+		{name: "subdomain is not mapped", to: "ana@mail.gmail.com", want: "default@northfi.com.br"},
+		{name: "no domain", to: "ana", want: "default@northfi.com.br"},
+		{name: "empty address", to: "", want: "default@northfi.com.br"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handler.fromForRecipient(tt.to, "default@northfi.com.br"); got != tt.want {
+				t.Errorf("fromForRecipient(%q) = %q, want %q", tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageTemplateBySubject(t *testing.T) {
+	tests := []struct {
+		name         string
+		subject      string
+		wantTemplate string
+		wantHTML     string
+	}{
+		{name: "portuguese welcome subject", subject: "Bem-vinda à NorthFi", wantTemplate: email.TemplateWelcome, wantHTML: "Bem-vindo(a) à"},
+		{name: "english welcome subject", subject: "Welcome aboard", wantTemplate: email.TemplateWelcome, wantHTML: "Bem-vindo(a) à"},
+		{name: "regular subject", subject: "Sua fatura chegou", wantTemplate: email.TemplateDefault, wantHTML: "Olá, Ana"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: tt.subject, Body: "Olá, Ana"}
+			if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sent))
+			}
+			if !slices.Contains(sent[0].Opts.Tags, email.TypeTag(tt.wantTemplate)) {
+				t.Errorf("tags = %v, want the %s template", sent[0].Opts.Tags, tt.wantTemplate)
+			}
+			if !strings.Contains(sent[0].HTML, tt.wantHTML) {
+				t.Errorf("rendered HTML does not contain %q", tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantHTML    bool
+	}{
+		{name: "defaults to html", wantHTML: true},
+		{name: "explicit html", contentType: models.ContentTypeHTML, wantHTML: true},
+		{name: "plain text", contentType: models.ContentTypeText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá", ContentType: tt.contentType}
+			if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sent))
+			}
+			if got := sent[0].HTML != ""; got != tt.wantHTML {
+				t.Errorf("html = %q, want html %v", sent[0].HTML, tt.wantHTML)
+			}
+			if !tt.wantHTML && sent[0].Opts.Text != "Olá" {
+				t.Errorf("text = %q, want the payload body", sent[0].Opts.Text)
+			}
+		})
+	}
+}
+
+func TestHandlersTagEmailType(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(*EmailQueueHandler) error
+		want   email.Tag
+	}{
+		{
+			name: "welcome",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleWelcomeMessage(context.Background(), &models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com"})
+			},
+			want: email.TypeTag(email.TemplateWelcome),
+		},
+		{
+			name: "verification",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleVerificationMessage(context.Background(), &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"})
+			},
+			want: email.TypeTag(email.TemplateVerification),
+		},
+		{
+			name: "regular",
+			handle: func(h *EmailQueueHandler) error {
+				return h.HandleEmailMessage(context.Background(), &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"})
+			},
+			want: email.TypeTag(email.TemplateDefault),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			if err := tt.handle(NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})); err != nil {
+				t.Fatalf("handler failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || !slices.Contains(sent[0].Opts.Tags, tt.want) {
+				t.Errorf("sent %+v, want one email tagged %v", sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleVerificationMessageRendersExpiry(t *testing.T) {
+	tests := []struct {
+		ttl  int
+		want string
+	}{
+		{want: "10 minutos"},
+		{ttl: 60, want: "1 minuto"},
+		{ttl: 300, want: "5 minutos"},
+		{ttl: 3600, want: "1 hora"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456", TTLSeconds: tt.ttl}
+			if err := handler.HandleVerificationMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleVerificationMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || !strings.Contains(sent[0].HTML, tt.want) {
+				t.Errorf("rendered HTML does not mention the expiry %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageTemplateDispatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      models.EmailPayload
+		wantTemplate string
+		wantHTML     string
+	}{
+		{
+			name:         "welcome template overrides the subject",
+			payload:      models.EmailPayload{Subject: "Sua conta", Template: models.TemplateWelcome, Data: map[string]string{"name": "Ana"}},
+			wantTemplate: email.TemplateWelcome,
+			wantHTML:     "Bem-vindo(a) à",
+		},
+		{
+			name:         "verification code",
+			payload:      models.EmailPayload{Subject: "Código", Template: models.TemplateVerification, Data: map[string]string{"code": "123456"}},
+			wantTemplate: email.TemplateVerification,
+			wantHTML:     "123 456",
+		},
+		{
+			name:         "verification link",
+			payload:      models.EmailPayload{Subject: "Confirme", Template: models.TemplateVerification, Data: map[string]string{"verify_url": "https://northfi.com.br/v/1"}},
+			wantTemplate: email.TemplateVerificationLink,
+			wantHTML:     "https://northfi.com.br/v/1",
+		},
+		{
+			name:         "explicit default ignores a welcome subject",
+			payload:      models.EmailPayload{Subject: "Bem-vindo", Body: "Olá, Ana", Template: models.TemplateDefault},
+			wantTemplate: email.TemplateDefault,
+			wantHTML:     "Olá, Ana",
+		},
+		{
+			name:         "no template falls back to the default",
+			payload:      models.EmailPayload{Subject: "Fatura", Body: "Olá, Ana"},
+			wantTemplate: email.TemplateDefault,
+			wantHTML:     "Olá, Ana",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := tt.payload
+			payload.To = "ana@example.com"
+			if err := handler.HandleEmailMessage(context.Background(), &payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sent))
+			}
+			if !slices.Contains(sent[0].Opts.Tags, email.TypeTag(tt.wantTemplate)) {
+				t.Errorf("tags = %v, want the %s template", sent[0].Opts.Tags, tt.wantTemplate)
+			}
+			if !strings.Contains(sent[0].HTML, tt.wantHTML) {
+				t.Errorf("rendered HTML does not contain %q", tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestHandleWelcomeMessageCarriesUserID(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	tests := []struct {
+		name    string
+		userID  string
+		wantTag bool
+	}{
+		{name: "created user", userID: "u-42", wantTag: true},
+		{name: "no user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := &models.WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com", UserID: tt.userID}
+			if err := handler.HandleWelcomeMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleWelcomeMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sent))
+			}
+			if got := slices.Contains(sent[0].Opts.Tags, email.UserTag("u-42")); got != tt.wantTag {
+				t.Errorf("tags = %v, want user tag %v", sent[0].Opts.Tags, tt.wantTag)
+			}
+			if tt.wantTag && !strings.Contains(logs.String(), `"user_id":"u-42"`) {
+				t.Errorf("logs are missing the user ID: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageTruncatesBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantText string
+	}{
+		{name: "under the limit", body: "Olá, Ana", wantText: "Olá, Ana"},
+		{name: "over the limit", body: "Olá, Ana Souza", wantText: "Olá, An…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock(), MaxBodyLength: 8})
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: tt.body, ContentType: models.ContentTypeText}
+			if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || sent[0].Opts.Text != tt.wantText {
+				t.Errorf("sent %+v, want the body %q", sent, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessagePlainTextAlternative(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  models.EmailPayload
+		wantText string
+	}{
+		{name: "default template", payload: models.EmailPayload{Subject: "Oi", Body: "<p>Olá, Ana</p>"}, wantText: "Oi\n\nOlá, Ana"},
+		{name: "welcome template sends html only", payload: models.EmailPayload{Subject: "Bem-vinda", Body: "Olá"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := tt.payload
+			payload.To = "ana@example.com"
+			if err := handler.HandleEmailMessage(context.Background(), &payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 || sent[0].HTML == "" || sent[0].Opts.Text != tt.wantText {
+				t.Errorf("sent %+v, want html with the text alternative %q", sent, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestHandleVerificationMessageCodeOrLink(t *testing.T) {
+	const verifyURL = "https://northfi.com.br/verify/abc"
+
+	tests := []struct {
+		name     string
+		payload  models.VerificationEmailPayload
+		wantCode string
+		wantLink bool
+	}{
+		{name: "code only", payload: models.VerificationEmailPayload{Code: "123456"}, wantCode: "123 456"},
+		{name: "legacy token", payload: models.VerificationEmailPayload{Token: "654321"}, wantCode: "654 321"},
+		{name: "url only", payload: models.VerificationEmailPayload{VerifyURL: verifyURL}, wantLink: true},
+		{name: "code wins over the url", payload: models.VerificationEmailPayload{Code: "123456", VerifyURL: verifyURL}, wantCode: "123 456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{Clock: newFakeClock()})
+
+			payload := tt.payload
+			payload.To, payload.Username = "ana@example.com", "Ana"
+			if err := handler.HandleVerificationMessage(context.Background(), &payload); err != nil {
+				t.Fatalf("HandleVerificationMessage failed: %v", err)
+			}
+
+			sent := sender.sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d emails, want 1", len(sent))
+			}
+			html := sent[0].HTML
+			if got := strings.Contains(html, `class="verification-code"`); got != (tt.wantCode != "") {
+				t.Errorf("renders a code box = %v, want %v", got, tt.wantCode != "")
+			}
+			if tt.wantCode != "" && !strings.Contains(html, tt.wantCode) {
+				t.Errorf("rendered HTML does not show the code %q", tt.wantCode)
+			}
+			if got := strings.Contains(html, `href="`+verifyURL+`"`); got != tt.wantLink {
+				t.Errorf("renders the verification link = %v, want %v", got, tt.wantLink)
+			}
+		})
+	}
+}
+
+// stubRenderer renders every payload as a fixed subject and body, or fails with err
+type stubRenderer struct {
+	err   error
+	calls int
+}
+
+func (r *stubRenderer) Render(payload *models.EmailPayload) (string, string, error) {
+	r.calls++
+	if r.err != nil {
+		return "", "", r.err
+	}
+	return "[custom] " + payload.Subject, "<p>custom</p>", nil
+}
+
+func TestHandleEmailMessageCustomRenderer(t *testing.T) {
+	tests := []struct {
+		name         string
+		renderErr    error
+		wantAttempts int
+		wantSent     int
+	}{
+		{name: "custom rendering", wantAttempts: 1, wantSent: 1},
+		{name: "render failure is retried", renderErr: errors.New("template missing"), wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			renderer := &stubRenderer{err: tt.renderErr}
+			handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+				Clock:         newFakeClock(),
+				EmailRenderer: renderer,
+				Retries:       RetryConfigs{Email: email.RetryConfig{MaxAttempts: 3, Delay: time.Second}},
+			})
+
+			payload := &models.EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"}
+			if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+				t.Fatalf("HandleEmailMessage failed: %v", err)
+			}
+
+			if renderer.calls != tt.wantAttempts {
+				t.Errorf("renderer called %d times, want %d", renderer.calls, tt.wantAttempts)
+			}
+			sent := sender.sent()
+			if len(sent) != tt.wantSent {
+				t.Fatalf("sent %d emails, want %d", len(sent), tt.wantSent)
+			}
+			if tt.wantSent > 0 && (sent[0].Subject != "[custom] Oi" || sent[0].HTML != "<p>custom</p>") {
+				t.Errorf("sent %q with %q, want the custom rendering", sent[0].Subject, sent[0].HTML)
+			}
+		})
+	}
+}
+
+func TestHandleEmailMessageTextKeepsOptions(t *testing.T) {
+	sender := &fakeSender{}
+	handler := NewEmailQueueHandler(sender, nil, QueueHandlerOptions{
+		Clock:        newFakeClock(),
+		FromByLocale: map[string]string{"en": "hello@northfi.com.br"},
+	})
+
+	scheduledAt := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	payload := &models.EmailPayload{
+		To:          "ana@example.com",
+		Subject:     "Oi",
+		Body:        "Olá",
+		ContentType: models.ContentTypeText,
+		Locale:      "en",
+		Headers:     map[string]string{"X-Entity-Ref-ID": "42"},
+		ScheduledAt: scheduledAt,
+	}
+	if err := handler.HandleEmailMessage(context.Background(), payload); err != nil {
+		t.Fatalf("HandleEmailMessage failed: %v", err)
+	}
+
+	sent := sender.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sent))
+	}
+	got := sent[0]
+	if got.HTML != "" || got.Opts.Text != "Olá" {
+		t.Errorf("html = %q, text = %q, want a text-only email", got.HTML, got.Opts.Text)
+	}
+	if got.Opts.From != "hello@northfi.com.br" || !got.Opts.ScheduledAt.Equal(scheduledAt) {
+		t.Errorf("from = %q, scheduled_at = %v, want the locale sender and the schedule", got.Opts.From, got.Opts.ScheduledAt)
+	}
+	if got.Opts.Headers["X-Entity-Ref-ID"] != "42" || len(got.Opts.Tags) == 0 {
+		t.Errorf("headers = %v, tags = %v, want the payload headers and a type tag", got.Opts.Headers, got.Opts.Tags)
+	}
+}
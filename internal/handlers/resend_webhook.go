@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_integration/internal/email"
+	"go_integration/internal/logging"
+)
+
+// Resend webhook event types that suppress the recipient
+const (
+	ResendEventBounced    = "email.bounced"
+	ResendEventComplained = "email.complained"
+)
+
+// resendWebhookTolerance bounds the age of a webhook timestamp, rejecting replayed deliveries
+const resendWebhookTolerance = 5 * time.Minute
+
+// maxWebhookBodySize bounds the webhook body read before its signature is checked
+const maxWebhookBodySize = 1 << 20
+
+// resendEvent is the part of a Resend webhook event used to maintain the suppression list
+type resendEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		EmailID string   `json:"email_id"`
+		To      []string `json:"to"`
+		Bounce  struct {
+			Type string `json:"type"` // "Permanent", "Transient" or "Undetermined"
+		} `json:"bounce"`
+	} `json:"data"`
+}
+
+// ResendWebhook handles POST /webhooks/resend, adding the recipients of hard bounces and spam complaints to
+// suppressed. Deliveries are authenticated with the svix-* signature headers under secret ("whsec_..."), and
+// other event types are acknowledged and ignored.
+func ResendWebhook(secret string, suppressed email.SuppressionList) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+		if err != nil {
+			writeError(w, r, fmt.Sprintf("Failed to read body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := verifyResendSignature(secret, r.Header, body, time.Now()); err != nil {
+			slog.Warn("Rejected Resend webhook", "error", err)
+			writeError(w, r, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var event resendEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if !suppresses(event) {
+			writeJSONSuccess(w, r, http.StatusOK, map[string]any{"suppressed": 0})
+			return
+		}
+
+		for _, to := range event.Data.To {
+			if err := suppressed.Add(r.Context(), to); err != nil {
+				// Resend retries failed deliveries, so the whole event is applied again later
+				writeError(w, r, fmt.Sprintf("Failed to suppress recipient: %v", err), http.StatusInternalServerError)
+				return
+			}
+			slog.Info("Recipient suppressed", "recipient", logging.Recipient(to), "event", event.Type, "email_id", event.Data.EmailID)
+		}
+		writeJSONSuccess(w, r, http.StatusOK, map[string]any{"suppressed": len(event.Data.To)})
+	}
+}
+
+// suppresses reports whether event must suppress its recipients: every complaint, and bounces that are not transient
+func suppresses(event resendEvent) bool {
+	switch event.Type {
+	case ResendEventComplained:
+		return true
+	case ResendEventBounced:
+		return !strings.EqualFold(event.Data.Bounce.Type, "Transient")
+	default:
+		return false
+	}
+}
+
+// verifyResendSignature checks the Svix signature Resend sends with every webhook: a base64 HMAC-SHA256 of
+// "<svix-id>.<svix-timestamp>.<body>" under the decoded secret, with a timestamp close to now
+func verifyResendSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	id, timestamp, signatures := header.Get("svix-id"), header.Get("svix-timestamp"), header.Get("svix-signature")
+	if id == "" || timestamp == "" || signatures == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > resendWebhookTolerance || age < -resendWebhookTolerance {
+		return fmt.Errorf("timestamp outside the %s tolerance", resendWebhookTolerance)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		return fmt.Errorf("invalid webhook secret: %w", err)
+	}
+	expected := signSvix(key, id, timestamp, body)
+
+	// The header lists space separated "v1,<signature>" entries, one per active secret
+	for _, entry := range strings.Fields(signatures) {
+		version, signature, ok := strings.Cut(entry, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		if provided, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(provided, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching signature")
+}
+
+// signSvix returns the HMAC-SHA256 of a Svix webhook delivery under key
+func signSvix(key []byte, id, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
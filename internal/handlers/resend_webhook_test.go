@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_integration/internal/email"
+)
+
+var testWebhookKey = []byte("resend-webhook-test-key")
+
+// signedWebhookRequest builds a Resend webhook delivery of body signed with key at timestamp
+func signedWebhookRequest(key []byte, body string, timestamp time.Time) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/resend", strings.NewReader(body))
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req.Header.Set("svix-id", "msg_1")
+	req.Header.Set("svix-timestamp", ts)
+	req.Header.Set("svix-signature", "v1,"+base64.StdEncoding.EncodeToString(signSvix(key, "msg_1", ts, []byte(body))))
+	return req
+}
+
+func TestResendWebhook(t *testing.T) {
+	secret := "whsec_" + base64.StdEncoding.EncodeToString(testWebhookKey)
+	const recipient = "ana@example.com"
+
+	tests := []struct {
+		name           string
+		body           string
+		key            []byte
+		age            time.Duration
+		wantStatus     int
+		wantSuppressed bool
+	}{
+		{
+			name:           "hard bounce",
+			body:           `{"type":"email.bounced","data":{"email_id":"e1","to":["ana@example.com"],"bounce":{"type":"Permanent"}}}`,
+			wantStatus:     http.StatusOK,
+			wantSuppressed: true,
+		},
+		{
+			name:           "complaint",
+			body:           `{"type":"email.complained","data":{"email_id":"e1","to":["ana@example.com"]}}`,
+			wantStatus:     http.StatusOK,
+			wantSuppressed: true,
+		},
+		{
+			name:       "transient bounce",
+			body:       `{"type":"email.bounced","data":{"email_id":"e1","to":["ana@example.com"],"bounce":{"type":"Transient"}}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "delivered",
+			body:       `{"type":"email.delivered","data":{"email_id":"e1","to":["ana@example.com"]}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong secret",
+			body:       `{"type":"email.complained","data":{"email_id":"e1","to":["ana@example.com"]}}`,
+			key:        []byte("another-key"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "stale timestamp",
+			body:       `{"type":"email.complained","data":{"email_id":"e1","to":["ana@example.com"]}}`,
+			age:        time.Hour,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == nil {
+				key = testWebhookKey
+			}
+			suppressed := email.NewMemorySuppressionList()
+			rec := httptest.NewRecorder()
+
+			ResendWebhook(secret, suppressed).ServeHTTP(rec, signedWebhookRequest(key, tt.body, time.Now().Add(-tt.age)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got, _ := suppressed.Contains(context.Background(), recipient); got != tt.wantSuppressed {
+				t.Errorf("suppressed = %v, want %v", got, tt.wantSuppressed)
+			}
+		})
+	}
+}
+
+func TestResendWebhookMissingSignature(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/resend", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+
+	ResendWebhook("whsec_"+base64.StdEncoding.EncodeToString(testWebhookKey), email.NewMemorySuppressionList()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go_integration/internal/models"
+)
+
+// RequestIDHeader carries the request ID, echoed from the client or generated by RequestID
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// RequestID assigns every request an ID, taken from the X-Request-ID header when present, and echoes it in the response
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFrom returns the request ID assigned by RequestID, or an empty string outside of it
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random identifier for a request
+func newRequestID() string {
+	buf := make([]byte, 16)
+	// crypto/rand.Read never returns an error on supported platforms
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// successResponse is the envelope of every successful JSON response
+type successResponse struct {
+	Data any          `json:"data"`
+	Meta responseMeta `json:"meta"`
+}
+
+// responseMeta holds the metadata returned alongside the response data
+type responseMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeJSONSuccess writes data wrapped in the success envelope with the given status code
+func writeJSONSuccess(w http.ResponseWriter, r *http.Request, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(successResponse{
+		Data: data,
+		Meta: responseMeta{RequestID: requestIDFrom(r.Context())},
+	})
+}
+
+// Error body formats selected by ErrorFormat
+const (
+	// ErrorFormatPlain writes errors as plain text (default)
+	ErrorFormatPlain = "plain"
+
+	// ErrorFormatProblem writes errors as RFC 7807 application/problem+json documents
+	ErrorFormatProblem = "problem"
+)
+
+// errorFormatKey is the context key of the error format
+type errorFormatKey struct{}
+
+// ErrorFormat makes the handlers behind it write their errors in format (ErrorFormatPlain or ErrorFormatProblem)
+func ErrorFormat(format string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorFormatKey{}, format)))
+	})
+}
+
+// problemFormat reports whether ErrorFormat asked for RFC 7807 problem documents
+func problemFormat(r *http.Request) bool {
+	format, _ := r.Context().Value(errorFormatKey{}).(string)
+	return format == ErrorFormatProblem
+}
+
+// problemDetails is an RFC 7807 error document, extended with the request ID
+type problemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	Errors []fieldError `json:"errors,omitempty"` // Individual violations of a validation failure
+}
+
+// fieldError is a single payload violation reported in a validation problem
+type fieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// writeError writes detail with the status code in the error format set by ErrorFormat, plain text by default
+func writeError(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	if !problemFormat(r) {
+		http.Error(w, detail, status)
+		return
+	}
+	writeProblem(w, r, detail, status)
+}
+
+// writeProblem writes detail with the status code as an RFC 7807 application/problem+json document
+func writeProblem(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	writeProblemDetails(w, r, problemDetails{Detail: detail, Status: status})
+}
+
+// isValidationError reports whether err is a payload validation failure, which the client must fix rather than retry
+func isValidationError(err error) bool {
+	var violations models.ValidationErrors
+	var fieldErr *models.ValidationError
+	return errors.As(err, &violations) || errors.As(err, &fieldErr)
+}
+
+// writeValidationError writes a payload validation error with status 400 through writeError, or as a problem
+// document listing every violation when the error format is ErrorFormatProblem
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	if !problemFormat(r) {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	problem := problemDetails{Detail: err.Error(), Status: http.StatusBadRequest}
+
+	var violations models.ValidationErrors
+	if errors.As(err, &violations) {
+		problem.Detail = "Payload inválido"
+		for _, violation := range violations {
+			var fieldErr *models.ValidationError
+			if errors.As(violation, &fieldErr) {
+				problem.Errors = append(problem.Errors, fieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+				continue
+			}
+			problem.Errors = append(problem.Errors, fieldError{Message: violation.Error()})
+		}
+	}
+	writeProblemDetails(w, r, problem)
+}
+
+// writeProblemDetails fills the common members of problem and writes it as application/problem+json
+func writeProblemDetails(w http.ResponseWriter, r *http.Request, problem problemDetails) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = r.URL.Path
+	problem.RequestID = requestIDFrom(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
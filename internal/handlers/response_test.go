@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go_integration/internal/email"
+	"go_integration/internal/user"
+)
+
+func TestSuccessEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		path     string
+		body     string
+		wantData []string
+	}{
+		{
+			name:     "send email",
+			handler:  http.HandlerFunc(NewEmailHandler(email.NewService(&fakePublisher{})).SendEmail),
+			path:     "/send-email",
+			body:     `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`,
+			wantData: []string{"id", "message"},
+		},
+		{
+			name:     "create user",
+			handler:  http.HandlerFunc(NewUserHandler(user.NewService(&fakePublisher{}), 0).CreateUser),
+			path:     "/create-user",
+			body:     `{"id":"u1","email":"ana@example.com","name":"Ana"}`,
+			wantData: []string{"id", "message", "user"},
+		},
+		{
+			name:     "send verification email",
+			handler:  SendVerificationEmail(email.NewServiceWithVerification(&fakePublisher{}, &fakePublisher{})),
+			path:     "/send-verification-email",
+			body:     `{"to":"ana@example.com","username":"Ana","code":"123456"}`,
+			wantData: []string{"expires_at", "message"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			RequestID(tt.handler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+
+			var envelope map[string]json.RawMessage
+			if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if keys := slices.Sorted(maps.Keys(envelope)); !slices.Equal(keys, []string{"data", "meta"}) {
+				t.Fatalf("envelope keys = %v, want [data meta]", keys)
+			}
+
+			var meta responseMeta
+			if err := json.Unmarshal(envelope["meta"], &meta); err != nil {
+				t.Fatalf("failed to decode meta: %v", err)
+			}
+			if meta.RequestID != "req-1" {
+				t.Errorf("meta.request_id = %q, want %q", meta.RequestID, "req-1")
+			}
+
+			var data map[string]json.RawMessage
+			if err := json.Unmarshal(envelope["data"], &data); err != nil {
+				t.Fatalf("failed to decode data: %v", err)
+			}
+			if keys := slices.Sorted(maps.Keys(data)); !slices.Equal(keys, tt.wantData) {
+				t.Errorf("data keys = %v, want %v", keys, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		body       string
+		publishErr error
+		wantStatus int
+		wantType   string
+	}{
+		{
+			name:       "validation error as problem",
+			format:     ErrorFormatProblem,
+			body:       `{"to":"ana@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+			wantType:   "application/problem+json",
+		},
+		{
+			name:       "server error as problem",
+			format:     ErrorFormatProblem,
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`,
+			publishErr: errors.New("unavailable"),
+			wantStatus: http.StatusInternalServerError,
+			wantType:   "application/problem+json",
+		},
+		{
+			name:       "validation error as plain text by default",
+			body:       `{"to":"ana@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+			wantType:   "text/plain",
+		},
+		{
+			name:       "server error as plain text",
+			format:     ErrorFormatPlain,
+			body:       `{"to":"ana@example.com","subject":"Oi","body":"Olá"}`,
+			publishErr: errors.New("unavailable"),
+			wantStatus: http.StatusInternalServerError,
+			wantType:   "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEmailHandler(email.NewService(&fakePublisher{err: tt.publishErr}))
+			req := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(tt.body))
+			req.Header.Set(RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			RequestID(ErrorFormat(tt.format, http.HandlerFunc(handler.SendEmail))).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.wantType) {
+				t.Fatalf("Content-Type = %q, want %s", ct, tt.wantType)
+			}
+			if tt.format != ErrorFormatProblem {
+				return
+			}
+
+			var problem problemDetails
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			want := problemDetails{
+				Type:      "about:blank",
+				Title:     http.StatusText(tt.wantStatus),
+				Status:    tt.wantStatus,
+				Instance:  "/send-email",
+				RequestID: "req-1",
+			}
+			if problem.Detail == "" {
+				t.Error("problem has no detail")
+			}
+			problem.Detail, problem.Errors = "", nil
+			if !reflect.DeepEqual(problem, want) {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+		})
+	}
+}
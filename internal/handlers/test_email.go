@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go_integration/internal/email"
+	"go_integration/internal/logging"
+)
+
+// TestEmailSender sends an email straight through the provider, returning its message ID
+type TestEmailSender interface {
+	SendEmailWithResult(ctx context.Context, to, subject, htmlBody string, opts email.SendOptions) (email.SendResult, error)
+}
+
+// SendTestEmail handles POST /send-test-email requests, sending a fixed email to recipient without going through the queue
+func SendTestEmail(sender TestEmailSender, recipient, companyName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subject := fmt.Sprintf("Email de teste - %s", companyName)
+		body := fmt.Sprintf("Este é um email de teste enviado em %s para verificar a entrega de emails.", time.Now().UTC().Format(time.RFC3339))
+
+		result, err := sender.SendEmailWithResult(r.Context(), recipient, subject, email.GetDefaultEmailHTML(subject, body, companyName, email.CTA{}), email.SendOptions{
+			Tags: []email.Tag{email.TypeTag("test")},
+		})
+		if err != nil {
+			log.Printf("Failed to send test email to %s: %v", logging.Recipient(recipient), err)
+			writeError(w, r, fmt.Sprintf("Failed to send test email: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		log.Printf("Test email %s sent to %s", result.ID, logging.Recipient(recipient))
+
+		writeJSONSuccess(w, r, http.StatusOK, map[string]string{
+			"message":   fmt.Sprintf("Email de teste enviado para %s", recipient),
+			"id":        result.ID,
+			"recipient": recipient,
+		})
+	}
+}
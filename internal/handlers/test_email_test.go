@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go_integration/internal/email"
+)
+
+// fakeTestEmailSender records the recipient of the test email, failing with err when set
+type fakeTestEmailSender struct {
+	err  error
+	to   string
+	opts email.SendOptions
+}
+
+func (s *fakeTestEmailSender) SendEmailWithResult(_ context.Context, to, _, _ string, opts email.SendOptions) (email.SendResult, error) {
+	s.to, s.opts = to, opts
+	if s.err != nil {
+		return email.SendResult{}, s.err
+	}
+	return email.SendResult{ID: "re_123"}, nil
+}
+
+func TestSendTestEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		sendErr    error
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "success returns the message ID", wantStatus: http.StatusOK, wantBody: `"id":"re_123"`},
+		{name: "failure returns the error", sendErr: errors.New("invalid API key"), wantStatus: http.StatusBadGateway, wantBody: "invalid API key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeTestEmailSender{err: tt.sendErr}
+			handler := SendTestEmail(sender, "owner@northfi.com.br", "NorthFi")
+
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodPost, "/send-test-email", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+			if sender.to != "owner@northfi.com.br" {
+				t.Errorf("sent to %q, want the configured owner", sender.to)
+			}
+			if len(sender.opts.Tags) != 1 || sender.opts.Tags[0] != email.TypeTag("test") {
+				t.Errorf("tags = %v, want the test type tag", sender.opts.Tags)
+			}
+			if tt.sendErr != nil {
+				return
+			}
+
+			var resp struct {
+				Data map[string]string `json:"data"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Data["recipient"] != "owner@northfi.com.br" {
+				t.Errorf("recipient = %q, want the configured owner", resp.Data["recipient"])
+			}
+		})
+	}
+}
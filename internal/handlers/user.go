@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"go_integration/internal/models"
 	"go_integration/internal/user"
@@ -12,41 +13,101 @@ import (
 
 // UserHandler handles HTTP requests for user operations
 type UserHandler struct {
-	userService *user.Service
+	userService    *user.Service
+	requestTimeout time.Duration
 }
 
-// NewUserHandler creates a new user handler
-func NewUserHandler(userService *user.Service) *UserHandler {
+// NewUserHandler creates a new user handler whose publishes give up after requestTimeout (0 disables the timeout)
+func NewUserHandler(userService *user.Service, requestTimeout time.Duration) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:    userService,
+		requestTimeout: requestTimeout,
 	}
 }
 
 // CreateUser handles POST /create-user requests
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var payload models.UserPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if err := decodeJSON(r.Body, &payload); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	id, err := h.userService.CreateUser(context.Background(), &payload)
+	ctx := r.Context()
+	if h.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.requestTimeout)
+		defer cancel()
+	}
+
+	id, err := h.userService.CreateUser(ctx, &payload)
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, r, "Timed out creating user", http.StatusGatewayTimeout)
+		return
+	}
+	if isValidationError(err) {
+		writeValidationError(w, r, err)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
+		writeError(w, r, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
+	writeJSONSuccess(w, r, http.StatusOK, map[string]interface{}{
 		"message": fmt.Sprintf("User creation message published with ID: %s", id),
 		"id":      id,
 		"user":    payload,
+	})
+}
+
+// CreateUsers handles POST /create-users requests with a JSON array of users
+func (h *UserHandler) CreateUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payloads []*models.UserPayload
+	if err := decodeJSON(r.Body, &payloads); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(payloads) == 0 {
+		writeError(w, r, "At least one user is required", http.StatusBadRequest)
+		return
+	}
+	if len(payloads) > maxBatchSize {
+		writeError(w, r, fmt.Sprintf("Too many users: maximum is %d per request", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	ids, errs := h.userService.CreateUsers(r.Context(), payloads)
+
+	results := make([]batchItemResult, len(payloads))
+	succeeded := 0
+	for i := range payloads {
+		results[i] = batchItemResult{Index: i}
+		if errs[i] != nil {
+			results[i].Status = "error"
+			results[i].Error = errs[i].Error()
+			continue
+		}
+		results[i].Status = "published"
+		results[i].ID = ids[i]
+		succeeded++
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSONSuccess(w, r, http.StatusOK, map[string]interface{}{
+		"message":   fmt.Sprintf("%d of %d user creation messages published", succeeded, len(payloads)),
+		"succeeded": succeeded,
+		"failed":    len(payloads) - succeeded,
+		"results":   results,
+	})
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_integration/internal/user"
+)
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		body        string
+		publishErr  error
+		wantStatus  int
+		wantFields  []string
+		wantPublish int
+	}{
+		{
+			name:        "published",
+			body:        `{"id":"u1","email":"ana@example.com","name":"Ana"}`,
+			wantStatus:  http.StatusOK,
+			wantPublish: 1,
+		},
+		{
+			name:       "missing fields",
+			format:     ErrorFormatProblem,
+			body:       `{"id":"u1"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []string{"email", "name"},
+		},
+		{
+			name:       "missing fields in plain format",
+			body:       `{"id":"u1"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "publish failure",
+			body:       `{"id":"u1","email":"ana@example.com","name":"Ana"}`,
+			publishErr: errors.New("unavailable"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{err: tt.publishErr}
+			handler := NewUserHandler(user.NewService(publisher), 0)
+			req := httptest.NewRequest(http.MethodPost, "/create-user", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			ErrorFormat(tt.format, http.HandlerFunc(handler.CreateUser)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := publisher.count(); got != tt.wantPublish {
+				t.Errorf("published %d messages, want %d", got, tt.wantPublish)
+			}
+			if tt.wantFields == nil {
+				return
+			}
+
+			var problem problemDetails
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			if len(problem.Errors) != len(tt.wantFields) {
+				t.Fatalf("problem errors = %+v, want fields %v", problem.Errors, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if problem.Errors[i].Field != field {
+					t.Errorf("errors[%d].field = %q, want %q", i, problem.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+// blockingPublisher blocks every publish until its context is done, recording the context error
+type blockingPublisher struct {
+	err chan error
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, _ []byte, _ map[string]string) (string, error) {
+	<-ctx.Done()
+	p.err <- ctx.Err()
+	return "", ctx.Err()
+}
+
+func TestCreateUserTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		cancel     bool
+		wantErr    error
+		wantStatus int
+	}{
+		{name: "publish times out", timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded, wantStatus: http.StatusGatewayTimeout},
+		{name: "client disconnects", cancel: true, wantErr: context.Canceled, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &blockingPublisher{err: make(chan error, 1)}
+			handler := NewUserHandler(user.NewService(publisher), tt.timeout)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			body := `{"id":"u1","email":"ana@example.com","name":"Ana"}`
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/create-user", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.CreateUser(rec, req)
+
+			if err := <-publisher.err; !errors.Is(err, tt.wantErr) {
+				t.Errorf("publish context error = %v, want %v", err, tt.wantErr)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"go_integration/internal/email"
+	"go_integration/internal/logging"
 	"go_integration/internal/models"
 )
 
@@ -13,33 +16,78 @@ import (
 func SendVerificationEmail(emailService *email.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		var payload models.VerificationEmailPayload
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		if err := decodeJSON(r.Body, &payload); err != nil {
+			writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 			return
 		}
 
+		payload.Normalize()
 		if err := payload.Validate(); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeValidationError(w, r, err)
 			return
 		}
+		payload.SetExpiry(time.Now())
 
 		// Publish verification email to pub/sub
-		if err := emailService.PublishVerificationEmail(r.Context(), &payload); err != nil {
+		err := emailService.PublishVerificationEmail(r.Context(), &payload)
+		if isValidationError(err) {
+			writeValidationError(w, r, err)
+			return
+		}
+		if err != nil {
 			log.Printf("Failed to publish verification email: %v", err)
-			http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+			writeError(w, r, "Failed to send verification email", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Verification email published successfully to: %s", logging.Recipient(payload.To))
+
+		writeJSONSuccess(w, r, http.StatusOK, map[string]string{
+			"message":    "Verification email sent successfully",
+			"expires_at": payload.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+}
+
+// resendVerificationRequest is the body of POST /resend-verification
+type resendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// ResendVerificationEmail handles POST /resend-verification, republishing the latest verification email for an address
+func ResendVerificationEmail(emailService *email.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req resendVerificationRequest
+		if err := decodeJSON(r.Body, &req); err != nil {
+			writeError(w, r, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Email == "" {
+			writeError(w, r, models.ErrMissingRecipient.Error(), http.StatusBadRequest)
+			return
+		}
+
+		payload, err := emailService.ResendVerificationEmail(r.Context(), req.Email, time.Now())
+		if errors.Is(err, email.ErrVerificationNotFound) {
+			writeError(w, r, "No verification email found for this address", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to resend verification email: %v", err)
+			writeError(w, r, "Failed to resend verification email", http.StatusInternalServerError)
 			return
 		}
 
-		log.Printf("Verification email published successfully to: %s", payload.To)
+		log.Printf("Verification email republished successfully to: %s", logging.Recipient(payload.To))
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "Verification email sent successfully",
+		writeJSONSuccess(w, r, http.StatusOK, map[string]string{
+			"message":    "Verification email resent successfully",
+			"expires_at": payload.ExpiresAt.Format(time.RFC3339),
 		})
 	}
 }
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_integration/internal/email"
+	"go_integration/internal/models"
+)
+
+func TestSendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		body        string
+		wantStatus  int
+		wantType    string
+		wantPublish int
+	}{
+		{
+			name:        "published",
+			body:        `{"to":"ana@example.com","username":"Ana","code":"123456"}`,
+			wantStatus:  http.StatusOK,
+			wantType:    "application/json",
+			wantPublish: 1,
+		},
+		{
+			name:       "invalid payload",
+			body:       `{"to":"ana@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+			wantType:   "text/plain",
+		},
+		{
+			name:       "invalid payload as problem",
+			format:     ErrorFormatProblem,
+			body:       `{"to":"ana@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+			wantType:   "application/problem+json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			service := email.NewServiceWithVerification(&fakePublisher{}, publisher)
+
+			req := httptest.NewRequest(http.MethodPost, "/send-verification-email", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			ErrorFormat(tt.format, SendVerificationEmail(service)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %s", ct, tt.wantType)
+			}
+			if got := publisher.count(); got != tt.wantPublish {
+				t.Errorf("published %d verification emails, want %d", got, tt.wantPublish)
+			}
+		})
+	}
+}
+
+func TestSendVerificationEmailTTL(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        int
+		wantStatus int
+		wantExpiry time.Duration
+	}{
+		{name: "default ttl", wantStatus: http.StatusOK, wantExpiry: models.DefaultVerificationTTL},
+		{name: "valid ttl", ttl: 300, wantStatus: http.StatusOK, wantExpiry: 5 * time.Minute},
+		{name: "too short", ttl: 30, wantStatus: http.StatusBadRequest},
+		{name: "too long", ttl: 7200, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			service := email.NewServiceWithVerification(&fakePublisher{}, publisher)
+
+			body := fmt.Sprintf(`{"to":"ana@example.com","username":"Ana","code":"123456","ttl_seconds":%d}`, tt.ttl)
+			req := httptest.NewRequest(http.MethodPost, "/send-verification-email", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			start := time.Now()
+			SendVerificationEmail(service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if publisher.count() != 0 {
+					t.Error("published a verification email with an invalid ttl")
+				}
+				return
+			}
+
+			var published models.VerificationEmailPayload
+			if err := json.Unmarshal(publisher.data[0], &published); err != nil {
+				t.Fatalf("failed to decode the published payload: %v", err)
+			}
+			if expiry := published.ExpiresAt.Sub(start); expiry < tt.wantExpiry-time.Second || expiry > tt.wantExpiry+time.Second {
+				t.Errorf("expires_at is %v from now, want %v", expiry, tt.wantExpiry)
+			}
+		})
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantPublish int
+	}{
+		{name: "resends the stored code", body: `{"email":"ana@example.com"}`, wantStatus: http.StatusOK, wantPublish: 1},
+		{name: "nothing issued", body: `{"email":"eva@example.com"}`, wantStatus: http.StatusNotFound},
+		{name: "missing email", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"email":"ana@example.com","code":"000000"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", body: `{"email":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := email.NewMemoryVerificationStore(time.Hour)
+			store.Save(context.Background(), &models.VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"})
+
+			publisher := &fakePublisher{}
+			service := email.NewServiceWithVerification(&fakePublisher{}, publisher)
+			service.SetVerificationStore(store)
+
+			req := httptest.NewRequest(http.MethodPost, "/resend-verification", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			ResendVerificationEmail(service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := publisher.count(); got != tt.wantPublish {
+				t.Errorf("published %d verification emails, want %d", got, tt.wantPublish)
+			}
+		})
+	}
+}
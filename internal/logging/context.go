@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// contextKey is the type for values stored by this package in a context
+type contextKey string
+
+const (
+	messageIDKey   contextKey = "message_id"
+	publishTimeKey contextKey = "publish_time"
+)
+
+// WithMessageID returns a copy of ctx carrying the Pub/Sub message ID
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey, id)
+}
+
+// MessageID returns the Pub/Sub message ID stored in ctx, if any
+func MessageID(ctx context.Context) string {
+	id, _ := ctx.Value(messageIDKey).(string)
+	return id
+}
+
+// WithPublishTime returns a copy of ctx carrying the time the Pub/Sub message was published
+func WithPublishTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, publishTimeKey, t)
+}
+
+// PublishTime returns the Pub/Sub message publish time stored in ctx, if any
+func PublishTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(publishTimeKey).(time.Time)
+	return t, ok && !t.IsZero()
+}
+
+// FromContext returns the default logger enriched with the values stored in ctx,
+// including the queue lag since the message was published
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := MessageID(ctx); id != "" {
+		logger = logger.With("message_id", id)
+	}
+	if published, ok := PublishTime(ctx); ok {
+		logger = logger.With("queue_lag", time.Since(published))
+	}
+	return logger
+}
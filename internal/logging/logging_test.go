@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		level     string
+		wantText  bool
+		wantLevel slog.Level
+	}{
+		{name: "json info", format: "json", level: "info", wantLevel: slog.LevelInfo},
+		{name: "text debug", format: "text", level: "debug", wantText: true, wantLevel: slog.LevelDebug},
+		{name: "case and spaces", format: " TEXT ", level: " WARN ", wantText: true, wantLevel: slog.LevelWarn},
+		{name: "error level", format: "json", level: "error", wantLevel: slog.LevelError},
+		{name: "unknown format falls back to json", format: "xml", level: "info", wantLevel: slog.LevelInfo},
+		{name: "unknown level falls back to info", format: "json", level: "verbose", wantLevel: slog.LevelInfo},
+		{name: "empty", wantLevel: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Setup(tt.format, tt.level).Handler()
+
+			if _, isText := handler.(*slog.TextHandler); isText != tt.wantText {
+				t.Errorf("handler = %T, want text %v", handler, tt.wantText)
+			}
+			if !handler.Enabled(context.Background(), tt.wantLevel) {
+				t.Errorf("level %v is disabled", tt.wantLevel)
+			}
+			if handler.Enabled(context.Background(), tt.wantLevel-1) {
+				t.Errorf("level below %v is enabled", tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"net/mail"
+	"strings"
+	"sync/atomic"
+)
+
+// redactEmails masks the recipient addresses logged through Recipient, set by SetRedactEmails
+var redactEmails atomic.Bool
+
+// SetRedactEmails enables or disables the masking of recipient addresses in logs
+func SetRedactEmails(enabled bool) {
+	redactEmails.Store(enabled)
+}
+
+// Recipient returns address as it should appear in logs, masked when redaction is enabled
+func Recipient(address string) string {
+	if !redactEmails.Load() {
+		return address
+	}
+	return redactEmail(address)
+}
+
+// redactEmail masks the local part of address, keeping its first character when the local part
+// is longer than one character: "joao@example.com" becomes "j***@example.com". A display name is dropped.
+func redactEmail(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return ""
+	}
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return "***"
+	}
+
+	// Count runes so a multi-byte first character is neither split nor revealed as the whole local part
+	local, domain := []rune(address[:at]), address[at+1:]
+	if len(local) <= 1 {
+		return "***@" + domain
+	}
+	return string(local[0]) + "***@" + domain
+}
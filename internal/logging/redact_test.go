@@ -0,0 +1,49 @@
+package logging
+
+import "testing"
+
+func TestRedactEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{name: "plain address", address: "joao@example.com", want: "j***@example.com"},
+		{name: "single character local part", address: "j@example.com", want: "***@example.com"},
+		{name: "multi-byte first character", address: "élia@example.com", want: "é***@example.com"},
+		{name: "display name is dropped", address: "João <joao@example.com>", want: "j***@example.com"},
+		{name: "surrounding spaces", address: "  joao@example.com ", want: "j***@example.com"},
+		{name: "not an address", address: "joao", want: "***"},
+		{name: "empty", address: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactEmail(tt.address); got != tt.want {
+				t.Errorf("redactEmail(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipient(t *testing.T) {
+	defer SetRedactEmails(false)
+
+	tests := []struct {
+		name   string
+		redact bool
+		want   string
+	}{
+		{name: "redaction disabled", want: "joao@example.com"},
+		{name: "redaction enabled", redact: true, want: "j***@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetRedactEmails(tt.redact)
+			if got := Recipient("joao@example.com"); got != tt.want {
+				t.Errorf("Recipient = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
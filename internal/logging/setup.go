@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup builds a logger writing to stdout in the given format ("json" or "text") at the given level.
+// Unknown formats fall back to JSON and unknown levels to info.
+func Setup(format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(strings.TrimSpace(format), "text") {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.New(handler)
+}
+
+// parseLevel parses a level name such as "debug" or "warn", defaulting to info
+func parseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
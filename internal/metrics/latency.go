@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// QueueLatency records how long messages waited on each Pub/Sub subscription before being handled,
+// published through expvar as "queue_latency"
+var QueueLatency = NewLatency("queue_latency")
+
+// LatencyStats summarizes the latencies observed for one kind of message, in milliseconds
+type LatencyStats struct {
+	Count   int64 `json:"count"`
+	TotalMs int64 `json:"total_ms"`
+	MaxMs   int64 `json:"max_ms"`
+	LastMs  int64 `json:"last_ms"`
+}
+
+// Latency aggregates latencies by message kind
+type Latency struct {
+	mu    sync.Mutex
+	stats map[string]LatencyStats
+}
+
+// NewLatency creates a latency metric published through expvar under name (empty skips publishing)
+func NewLatency(name string) *Latency {
+	l := &Latency{stats: make(map[string]LatencyStats)}
+	if name != "" {
+		expvar.Publish(name, expvar.Func(func() any { return l.Snapshot() }))
+	}
+	return l
+}
+
+// Observe records a latency for kind
+func (l *Latency) Observe(kind string, d time.Duration) {
+	ms := d.Milliseconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.stats[kind]
+	s.Count++
+	s.TotalMs += ms
+	s.MaxMs = max(s.MaxMs, ms)
+	s.LastMs = ms
+	l.stats[kind] = s
+}
+
+// Snapshot returns a copy of the stats of every kind observed so far
+func (l *Latency) Snapshot() map[string]LatencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := make(map[string]LatencyStats, len(l.stats))
+	for kind, s := range l.stats {
+		snapshot[kind] = s
+	}
+	return snapshot
+}
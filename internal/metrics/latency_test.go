@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestLatencyObserve(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []time.Duration
+		want         LatencyStats
+	}{
+		{name: "single", observations: []time.Duration{120 * time.Millisecond}, want: LatencyStats{Count: 1, TotalMs: 120, MaxMs: 120, LastMs: 120}},
+		{
+			name:         "max and last differ",
+			observations: []time.Duration{300 * time.Millisecond, 100 * time.Millisecond},
+			want:         LatencyStats{Count: 2, TotalMs: 400, MaxMs: 300, LastMs: 100},
+		},
+		{name: "sub-millisecond", observations: []time.Duration{500 * time.Microsecond}, want: LatencyStats{Count: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latency := NewLatency("")
+			for _, d := range tt.observations {
+				latency.Observe("email", d)
+			}
+
+			snapshot := latency.Snapshot()
+			if got := snapshot["email"]; got != tt.want {
+				t.Errorf("stats = %+v, want %+v", got, tt.want)
+			}
+			if len(snapshot) != 1 {
+				t.Errorf("snapshot has %d kinds, want 1", len(snapshot))
+			}
+		})
+	}
+}
+
+func TestLatencyPublishesExpvar(t *testing.T) {
+	latency := NewLatency("test_queue_latency")
+	latency.Observe("user", time.Second)
+
+	v := expvar.Get("test_queue_latency")
+	if v == nil {
+		t.Fatal("latency not published through expvar")
+	}
+	if got, want := v.String(), `{"user":{"count":1,"total_ms":1000,"max_ms":1000,"last_ms":1000}}`; got != want {
+		t.Errorf("expvar = %s, want %s", got, want)
+	}
+}
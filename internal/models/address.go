@@ -0,0 +1,25 @@
+package models
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// NormalizeAddress trims an email address and lowercases its domain, preserving the case of the local part.
+// A display-name form such as "Ana <ana@Example.com>" is split into its name and bare address.
+func NormalizeAddress(raw string) (name, address string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ""
+	}
+
+	address = raw
+	if parsed, err := mail.ParseAddress(raw); err == nil {
+		name, address = parsed.Name, parsed.Address
+	}
+
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		address = address[:at+1] + strings.ToLower(address[at+1:])
+	}
+	return name, address
+}
@@ -0,0 +1,28 @@
+package models
+
+import "testing"
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantName    string
+		wantAddress string
+	}{
+		{name: "empty", raw: "  "},
+		{name: "whitespace", raw: "  ana@example.com\n", wantAddress: "ana@example.com"},
+		{name: "mixed-case domain", raw: "ana@Example.COM", wantAddress: "ana@example.com"},
+		{name: "local part case is preserved", raw: "Ana.Souza@Example.com", wantAddress: "Ana.Souza@example.com"},
+		{name: "display name", raw: "Ana Souza <Ana@Example.com>", wantName: "Ana Souza", wantAddress: "Ana@example.com"},
+		{name: "not an address", raw: "ana", wantAddress: "ana"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, address := NormalizeAddress(tt.raw)
+			if name != tt.wantName || address != tt.wantAddress {
+				t.Errorf("NormalizeAddress(%q) = %q, %q, want %q, %q", tt.raw, name, address, tt.wantName, tt.wantAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// BatchValidationResult reports whether a single payload of a batch is valid
+type BatchValidationResult struct {
+	Index int    `json:"index"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// ValidateEmailBatch validates each payload as SendEmail would, without modifying them
+func ValidateEmailBatch(payloads []*EmailPayload) []BatchValidationResult {
+	results := make([]BatchValidationResult, len(payloads))
+	for i, payload := range payloads {
+		results[i] = BatchValidationResult{Index: i}
+		if payload == nil {
+			results[i].Error = "empty email"
+			continue
+		}
+
+		normalized := *payload
+		normalized.Normalize()
+		if err := normalized.Validate(); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Valid = true
+	}
+	return results
+}
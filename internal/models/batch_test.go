@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestValidateEmailBatch(t *testing.T) {
+	payloads := []*EmailPayload{
+		{To: "ana@example.com", Subject: "Oi", Body: "Olá"},
+		{To: "not-an-address", Subject: "Oi", Body: "Olá"},
+		nil,
+		{To: " Bia@Example.com ", Subject: "Oi", Body: "Olá"},
+		{To: "eva@example.com"},
+	}
+	want := []struct {
+		valid   bool
+		wantErr bool
+	}{
+		{valid: true},
+		{wantErr: true},
+		{wantErr: true},
+		{valid: true},
+		{wantErr: true},
+	}
+
+	results := ValidateEmailBatch(payloads)
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Index != i || result.Valid != want[i].valid || (result.Error != "") != want[i].wantErr {
+			t.Errorf("results[%d] = %+v, want valid %v", i, result, want[i].valid)
+		}
+	}
+
+	if payloads[3].To != " Bia@Example.com " {
+		t.Errorf("payload was normalized in place: %q", payloads[3].To)
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "net/url"
+
+// validateCTA checks that a call-to-action button has both a text and an absolute http(s) URL, or neither
+func validateCTA(text, rawURL string) ValidationErrors {
+	if text == "" && rawURL == "" {
+		return nil
+	}
+	if text == "" || rawURL == "" {
+		return ValidationErrors{&ValidationError{Field: "cta", Message: "cta_text and cta_url must be set together"}}
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return ValidationErrors{&ValidationError{Field: "cta_url", Message: "cta_url must be an absolute http or https URL"}}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateCTA(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		url  string
+		want []string
+	}{
+		{name: "no button"},
+		{name: "https button", text: "Abrir app", url: "https://northfi.com.br/app"},
+		{name: "http button", text: "Abrir app", url: "http://localhost:8080/app"},
+		{name: "text without url", text: "Abrir app", want: []string{"cta"}},
+		{name: "url without text", url: "https://northfi.com.br/app", want: []string{"cta"}},
+		{name: "relative url", text: "Abrir app", url: "/app", want: []string{"cta_url"}},
+		{name: "javascript url", text: "Abrir app", url: "javascript:alert(1)", want: []string{"cta_url"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(validateCTA(tt.text, tt.url).errOrNil()); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,27 +3,165 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"html"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+const (
+	// ContentTypeHTML renders the body inside the HTML email template (default)
+	ContentTypeHTML = "html"
+
+	// ContentTypeText sends the body as a plain text email
+	ContentTypeText = "text"
+)
+
+// FormatMarkdown marks an email body written in Markdown, converted to HTML before rendering
+const FormatMarkdown = "markdown"
+
+// Priority lanes of an email, routing it to the high or low priority worker subscription
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+// Template names a caller can request through the "template" field
+const (
+	TemplateDefault      = "default"
+	TemplateWelcome      = "welcome"
+	TemplateVerification = "verification"
 )
 
 // EmailPayload represents the structure of an email message
 type EmailPayload struct {
-	To      string `json:"to"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+	To          string `json:"to"`
+	Subject     string `json:"subject"`
+	Body        string `json:"body"`
+	Name        string `json:"name,omitempty"`         // Optional: recipient display name
+	ContentType string `json:"content_type,omitempty"` // Optional: "html" (default) or "text"
+	TrackOpens  *bool  `json:"track_opens,omitempty"`  // Optional: overrides the default open tracking
+	TrackClicks *bool  `json:"track_clicks,omitempty"` // Optional: overrides the default click tracking
+
+	// Optional: render a named template ("default", "welcome" or "verification") from Data instead of sniffing the subject
+	Template string            `json:"template,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+
+	// Optional: custom headers added to the email, e.g. X-Entity-Ref-ID
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Optional: "markdown" converts Body from Markdown into the default template
+	Format string `json:"format,omitempty"`
+
+	// Optional: call-to-action button rendered below the body, set together
+	CTAText string `json:"cta_text,omitempty"`
+	CTAURL  string `json:"cta_url,omitempty"`
+
+	// Optional: "high" or "low" priority lane (empty uses high for verification emails and low otherwise)
+	Priority string `json:"priority,omitempty"`
+
+	// Optional: locale of the email, e.g. "en-US", selecting the sender address configured for its language
+	Locale string `json:"locale,omitempty"`
+
+	// Optional: RFC 3339 time Resend should deliver the email at
+	ScheduledAt time.Time `json:"scheduled_at,omitzero"`
 }
 
-// Validate validates the email payload
+// Validate validates the email payload, reporting every missing or invalid field at once
 func (e *EmailPayload) Validate() error {
+	var errs ValidationErrors
 	if e.To == "" {
-		return ErrMissingRecipient
+		errs = append(errs, ErrMissingRecipient)
+	} else if _, err := mail.ParseAddress(e.To); err != nil {
+		errs = append(errs, ErrInvalidRecipient)
 	}
 	if e.Subject == "" {
-		return ErrMissingSubject
+		errs = append(errs, ErrMissingSubject)
+	}
+	// Named templates other than the default build their content from Data
+	if e.Body == "" && (e.Template == "" || e.Template == TemplateDefault) {
+		errs = append(errs, ErrMissingBody)
+	}
+	if e.ContentType != "" && e.ContentType != ContentTypeHTML && e.ContentType != ContentTypeText {
+		errs = append(errs, &ValidationError{Field: "content_type", Message: "content_type must be \"html\" or \"text\""})
+	}
+	switch e.Template {
+	case "", TemplateDefault, TemplateWelcome:
+	case TemplateVerification:
+		if e.DataValue("code") == "" && e.DataValue("verify_url") == "" {
+			errs = append(errs, &ValidationError{Field: "data", Message: "verification template requires data.code or data.verify_url"})
+		}
+	default:
+		errs = append(errs, &ValidationError{Field: "template", Message: "template must be \"default\", \"welcome\" or \"verification\""})
+	}
+	for name := range e.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			errs = append(errs, &ValidationError{Field: "headers", Message: fmt.Sprintf("invalid header name %q", name)})
+		}
+	}
+	if e.Format != "" && e.Format != FormatMarkdown {
+		errs = append(errs, &ValidationError{Field: "format", Message: "format must be \"markdown\""})
+	}
+	if e.Format != "" && (e.IsText() || (e.Template != "" && e.Template != TemplateDefault)) {
+		errs = append(errs, &ValidationError{Field: "format", Message: "format can only be used with the default HTML template"})
+	}
+	errs = append(errs, validateCTA(e.CTAText, e.CTAURL)...)
+	if e.Priority != "" && e.Priority != PriorityHigh && e.Priority != PriorityLow {
+		errs = append(errs, &ValidationError{Field: "priority", Message: "priority must be \"high\" or \"low\""})
+	}
+	if e.Template != "" && e.IsText() {
+		errs = append(errs, &ValidationError{Field: "template", Message: "template cannot be combined with content_type \"text\""})
+	}
+	return errs.errOrNil()
+}
+
+// DataValue returns the template data value for key, or an empty string when unset
+func (e *EmailPayload) DataValue(key string) string {
+	return e.Data[key]
+}
+
+// Normalize trims the recipient address and lowercases its domain, moving a display name into Name when unset
+func (e *EmailPayload) Normalize() {
+	name, address := NormalizeAddress(e.To)
+	e.To = address
+	if e.Name == "" {
+		e.Name = name
+	}
+}
+
+// GetPriority returns the priority lane of the email: verification emails jump ahead of bulk sends by default
+func (e *EmailPayload) GetPriority() string {
+	if e.Priority != "" {
+		return e.Priority
+	}
+	if e.Template == TemplateVerification {
+		return PriorityHigh
+	}
+	return PriorityLow
+}
+
+// IsMarkdown reports whether the body is written in Markdown
+func (e *EmailPayload) IsMarkdown() bool {
+	return e.Format == FormatMarkdown
+}
+
+// IsText reports whether the payload should be sent as plain text
+func (e *EmailPayload) IsText() bool {
+	return e.ContentType == ContentTypeText
+}
+
+// RecipientName returns the recipient name, falling back to the local part of the address
+func (e *EmailPayload) RecipientName() string {
+	if e.Name != "" {
+		return e.Name
 	}
-	if e.Body == "" {
-		return ErrMissingBody
+	if local, _, found := strings.Cut(e.To, "@"); found {
+		return local
 	}
-	return nil
+	return e.To
 }
 
 // ToJSON converts the payload to JSON bytes
@@ -73,28 +211,107 @@ func (e *EmailPayload) GenerateBody() string {
 	`, e.Subject, e.Subject, e.Body)
 }
 
+const (
+	// DefaultVerificationTTL is how long a verification code is valid when no TTL is given
+	DefaultVerificationTTL = 10 * time.Minute
+
+	// MinVerificationTTLSeconds and MaxVerificationTTLSeconds bound the accepted ttl_seconds
+	MinVerificationTTLSeconds = 60
+	MaxVerificationTTLSeconds = 3600
+)
+
 // VerificationEmailPayload represents the structure of a verification email message
 type VerificationEmailPayload struct {
-	To        string `json:"to"`
-	Username  string `json:"username"`
-	Token     string `json:"token,omitempty"`      // Optional: for backward compatibility
-	Code      string `json:"code,omitempty"`       // Verification code
-	VerifyURL string `json:"verify_url,omitempty"` // Optional: for backward compatibility
+	To         string    `json:"to"`
+	Username   string    `json:"username"`
+	Token      string    `json:"token,omitempty"`       // Deprecated: legacy name for Code, used when Code is empty
+	Code       string    `json:"code,omitempty"`        // Verification code
+	VerifyURL  string    `json:"verify_url,omitempty"`  // Optional: for backward compatibility
+	TTLSeconds int       `json:"ttl_seconds,omitempty"` // Optional: code lifetime, between 60 and 3600 seconds
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`   // Set from TTLSeconds when the request is accepted
 }
 
-// Validate validates the verification email payload
+// Validate validates the verification email payload, reporting every missing field at once
 func (v *VerificationEmailPayload) Validate() error {
+	var errs ValidationErrors
 	if v.To == "" {
-		return ErrMissingRecipient
+		errs = append(errs, ErrMissingRecipient)
 	}
 	if v.Username == "" {
-		return &ValidationError{Field: "username", Message: "username is required"}
+		errs = append(errs, &ValidationError{Field: "username", Message: "username is required"})
 	}
 	// Either code or verify_url must be provided (or both for backward compatibility)
-	if v.Code == "" && v.VerifyURL == "" {
-		return &ValidationError{Field: "code_or_url", Message: "either verification code or verify_url is required"}
+	if v.VerificationCode() == "" && v.VerifyURL == "" {
+		errs = append(errs, &ValidationError{Field: "code_or_url", Message: "either verification code or verify_url is required"})
+	}
+	if v.TTLSeconds != 0 && (v.TTLSeconds < MinVerificationTTLSeconds || v.TTLSeconds > MaxVerificationTTLSeconds) {
+		errs = append(errs, &ValidationError{
+			Field:   "ttl_seconds",
+			Message: fmt.Sprintf("ttl_seconds must be between %d and %d", MinVerificationTTLSeconds, MaxVerificationTTLSeconds),
+		})
+	}
+	return errs.errOrNil()
+}
+
+// VerificationCode returns the code to display, falling back to the legacy Token field
+func (v *VerificationEmailPayload) VerificationCode() string {
+	if v.Code != "" {
+		return v.Code
+	}
+	return v.Token
+}
+
+// FormattedCode returns the verification code grouped for display, e.g. "123 456"; validation uses the raw code
+func (v *VerificationEmailPayload) FormattedCode() string {
+	return FormatCode(v.VerificationCode())
+}
+
+// FormatCode groups a verification code for display: lengths divisible by 3 in groups of three ("123 456"),
+// then lengths divisible by 4 in groups of four ("1234 5678"), others split in two halves.
+// Codes of up to four characters, or already containing spaces, are returned unchanged.
+func FormatCode(code string) string {
+	code = strings.TrimSpace(code)
+	runes := []rune(code)
+	if len(runes) <= 4 || strings.ContainsAny(code, " \t") {
+		return code
+	}
+
+	size := (len(runes) + 1) / 2
+	switch {
+	case len(runes)%3 == 0:
+		size = 3
+	case len(runes)%4 == 0:
+		size = 4
+	}
+
+	groups := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		groups = append(groups, string(runes[start:min(start+size, len(runes))]))
 	}
-	return nil
+	return strings.Join(groups, " ")
+}
+
+// UsesLink reports whether the email should show a verification link instead of a code
+func (v *VerificationEmailPayload) UsesLink() bool {
+	return v.VerificationCode() == "" && v.VerifyURL != ""
+}
+
+// Normalize trims the recipient address and lowercases its domain
+func (v *VerificationEmailPayload) Normalize() {
+	_, v.To = NormalizeAddress(v.To)
+}
+
+// ExpiresIn returns the verification code lifetime, falling back to DefaultVerificationTTL
+func (v *VerificationEmailPayload) ExpiresIn() time.Duration {
+	if v.TTLSeconds > 0 {
+		return time.Duration(v.TTLSeconds) * time.Second
+	}
+	return DefaultVerificationTTL
+}
+
+// SetExpiry stamps ExpiresAt from the payload TTL, relative to now
+func (v *VerificationEmailPayload) SetExpiry(now time.Time) {
+	v.ExpiresAt = now.Add(v.ExpiresIn()).UTC()
 }
 
 // ToJSON converts the verification payload to JSON bytes
@@ -107,6 +324,36 @@ func (v *VerificationEmailPayload) GenerateSubject() string {
 	return "Confirme sua conta - Verificação de Email"
 }
 
+// GeneratePlainText returns a readable plain text version of the email: the subject followed by the body without markup
+// and the call-to-action link, if any
+func (e *EmailPayload) GeneratePlainText() string {
+	text := e.Subject + "\n\n" + stripHTML(e.Body)
+	if e.CTAText != "" && e.CTAURL != "" {
+		text += "\n\n" + e.CTAText + ": " + e.CTAURL
+	}
+	return text
+}
+
+var (
+	// lineBreakTags are replaced with newlines so paragraphs survive the tag stripping
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr)>`)
+	htmlTags      = regexp.MustCompile(`<[^>]*>`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+)
+
+// stripHTML removes the markup from s, keeping line breaks and decoding entities
+func stripHTML(s string) string {
+	s = lineBreakTags.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
 // GenerateBody generates the HTML email body for verification
 func (v *VerificationEmailPayload) GenerateBody() string {
 	return fmt.Sprintf(`
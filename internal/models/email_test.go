@@ -0,0 +1,173 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEmailPayloadGetPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload EmailPayload
+		want    string
+	}{
+		{name: "default is low", payload: EmailPayload{}, want: PriorityLow},
+		{name: "verification template is high", payload: EmailPayload{Template: TemplateVerification}, want: PriorityHigh},
+		{name: "explicit priority wins", payload: EmailPayload{Template: TemplateVerification, Priority: PriorityLow}, want: PriorityLow},
+		{name: "explicit high", payload: EmailPayload{Priority: PriorityHigh}, want: PriorityHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.payload.GetPriority(); got != tt.want {
+				t.Errorf("GetPriority() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "1234", want: "1234"},
+		{code: "123456", want: "123 456"},
+		{code: "12345678", want: "1234 5678"},
+		{code: "12345", want: "123 45"},
+		{code: "123 456", want: "123 456"},
+		{code: " 123456 ", want: "123 456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := FormatCode(tt.code); got != tt.want {
+				t.Errorf("FormatCode(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailPayloadRecipientName(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload EmailPayload
+		want    string
+	}{
+		{name: "explicit name", payload: EmailPayload{To: "ana@example.com", Name: "Ana Souza"}, want: "Ana Souza"},
+		{name: "local part of the address", payload: EmailPayload{To: "ana@example.com"}, want: "ana"},
+		{name: "address without a domain", payload: EmailPayload{To: "ana"}, want: "ana"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.payload.RecipientName(); got != tt.want {
+				t.Errorf("RecipientName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailPayloadNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  EmailPayload
+		wantTo   string
+		wantName string
+	}{
+		{name: "bare address", payload: EmailPayload{To: " ana@Example.com "}, wantTo: "ana@example.com"},
+		{name: "display name fills Name", payload: EmailPayload{To: "Ana <ana@example.com>"}, wantTo: "ana@example.com", wantName: "Ana"},
+		{name: "explicit Name wins", payload: EmailPayload{To: "Ana <ana@example.com>", Name: "Ana Souza"}, wantTo: "ana@example.com", wantName: "Ana Souza"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payload.Normalize()
+			if tt.payload.To != tt.wantTo || tt.payload.Name != tt.wantName {
+				t.Errorf("Normalize() = %q, %q, want %q, %q", tt.payload.To, tt.payload.Name, tt.wantTo, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestEmailPayloadValidateHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    []string
+	}{
+		{name: "no headers"},
+		{name: "valid name", headers: map[string]string{"X-Entity-Ref-ID": "42"}},
+		{name: "name with a space", headers: map[string]string{"X Entity": "42"}, want: []string{"headers"}},
+		{name: "name with a colon", headers: map[string]string{"X-Entity:": "42"}, want: []string{"headers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá", Headers: tt.headers}
+			if got := violations(payload.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailPayloadGeneratePlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload EmailPayload
+		want    string
+	}{
+		{name: "plain body", payload: EmailPayload{Subject: "Oi", Body: "Olá, Ana"}, want: "Oi\n\nOlá, Ana"},
+		{
+			name:    "markup is stripped",
+			payload: EmailPayload{Subject: "Oi", Body: "<p>Olá, <b>Ana</b></p><p>Tudo bem?<br>Até logo</p>"},
+			want:    "Oi\n\nOlá, Ana\nTudo bem?\nAté logo",
+		},
+		{name: "entities are decoded", payload: EmailPayload{Subject: "Oi", Body: "Tom &amp; Jerry &lt;3"}, want: "Oi\n\nTom & Jerry <3"},
+		{
+			name:    "call to action",
+			payload: EmailPayload{Subject: "Oi", Body: "Olá", CTAText: "Abrir app", CTAURL: "https://northfi.com.br/app"},
+			want:    "Oi\n\nOlá\n\nAbrir app: https://northfi.com.br/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.payload.GeneratePlainText()
+			if got != tt.want {
+				t.Errorf("GeneratePlainText() = %q, want %q", got, tt.want)
+			}
+			if strings.ContainsAny(got, "<>") && !strings.Contains(tt.want, "<") {
+				t.Errorf("GeneratePlainText() = %q, still contains markup", got)
+			}
+		})
+	}
+}
+
+func TestVerificationEmailPayloadCodeOrLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  VerificationEmailPayload
+		wantCode string
+		wantLink bool
+	}{
+		{name: "code only", payload: VerificationEmailPayload{Code: "123456"}, wantCode: "123456"},
+		{name: "legacy token", payload: VerificationEmailPayload{Token: "654321"}, wantCode: "654321"},
+		{name: "code wins over the token", payload: VerificationEmailPayload{Code: "123456", Token: "654321"}, wantCode: "123456"},
+		{name: "url only", payload: VerificationEmailPayload{VerifyURL: "https://northfi.com.br/v"}, wantLink: true},
+		{name: "code and url", payload: VerificationEmailPayload{Code: "123456", VerifyURL: "https://northfi.com.br/v"}, wantCode: "123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.payload.VerificationCode(); got != tt.wantCode {
+				t.Errorf("VerificationCode() = %q, want %q", got, tt.wantCode)
+			}
+			if got := tt.payload.UsesLink(); got != tt.wantLink {
+				t.Errorf("UsesLink() = %v, want %v", got, tt.wantLink)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -14,6 +15,9 @@ var (
 
 	// ErrMissingBody is returned when the "body" field is empty
 	ErrMissingBody = errors.New("email body is required")
+
+	// ErrInvalidRecipient is returned when the "to" field is not a valid email address
+	ErrInvalidRecipient = errors.New("recipient email is not a valid address")
 )
 
 // ValidationError represents a field validation error
@@ -25,3 +29,27 @@ type ValidationError struct {
 func (v *ValidationError) Error() string {
 	return fmt.Sprintf("validation error for field '%s': %s", v.Field, v.Message)
 }
+
+// ValidationErrors aggregates every validation failure found in a payload
+type ValidationErrors []error
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap exposes the individual errors to errors.Is and errors.As
+func (v ValidationErrors) Unwrap() []error {
+	return v
+}
+
+// errOrNil returns the aggregated error, or nil when no violations were collected
+func (v ValidationErrors) errOrNil() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
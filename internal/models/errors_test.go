@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// violations returns the field of every *ValidationError in err, and the message of any other error
+func violations(err error) []string {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	found := make([]string, len(errs))
+	for i, violation := range errs {
+		var fieldErr *ValidationError
+		if errors.As(violation, &fieldErr) {
+			found[i] = fieldErr.Field
+		} else {
+			found[i] = violation.Error()
+		}
+	}
+	return found
+}
+
+func TestEmailPayloadValidate(t *testing.T) {
+	valid := EmailPayload{To: "ana@example.com", Subject: "Oi", Body: "Olá"}
+
+	tests := []struct {
+		name   string
+		modify func(p *EmailPayload)
+		want   []string
+	}{
+		{name: "valid", modify: func(*EmailPayload) {}},
+		{
+			name:   "every missing field at once",
+			modify: func(p *EmailPayload) { *p = EmailPayload{} },
+			want:   []string{ErrMissingRecipient.Error(), ErrMissingSubject.Error(), ErrMissingBody.Error()},
+		},
+		{name: "invalid recipient", modify: func(p *EmailPayload) { p.To = "ana" }, want: []string{ErrInvalidRecipient.Error()}},
+		{name: "named template needs no body", modify: func(p *EmailPayload) { p.Body, p.Template = "", TemplateWelcome }},
+		{name: "unknown template", modify: func(p *EmailPayload) { p.Template = "promo" }, want: []string{"template"}},
+		{name: "verification template without data", modify: func(p *EmailPayload) { p.Template = TemplateVerification }, want: []string{"data"}},
+		{name: "invalid content type", modify: func(p *EmailPayload) { p.ContentType = "xml" }, want: []string{"content_type"}},
+		{name: "invalid header name", modify: func(p *EmailPayload) { p.Headers = map[string]string{"Bad Header": "x"} }, want: []string{"headers"}},
+		{name: "markdown with text", modify: func(p *EmailPayload) { p.Format, p.ContentType = FormatMarkdown, ContentTypeText }, want: []string{"format"}},
+		{name: "invalid priority", modify: func(p *EmailPayload) { p.Priority = "urgent" }, want: []string{"priority"}},
+		{name: "template with text", modify: func(p *EmailPayload) { p.Template, p.ContentType = TemplateDefault, ContentTypeText }, want: []string{"template"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := valid
+			tt.modify(&payload)
+
+			got := violations(payload.Validate())
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerificationEmailPayloadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload VerificationEmailPayload
+		want    []string
+	}{
+		{name: "code", payload: VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "123456"}},
+		{name: "legacy token", payload: VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Token: "123456"}},
+		{name: "link", payload: VerificationEmailPayload{To: "ana@example.com", Username: "Ana", VerifyURL: "https://northfi.com.br/v"}},
+		{name: "empty", want: []string{ErrMissingRecipient.Error(), "username", "code_or_url"}},
+		{
+			name:    "ttl out of range",
+			payload: VerificationEmailPayload{To: "ana@example.com", Username: "Ana", Code: "1", TTLSeconds: 30},
+			want:    []string{"ttl_seconds"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(tt.payload.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserPayloadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload UserPayload
+		want    []string
+	}{
+		{name: "valid", payload: UserPayload{ID: "u1", Email: "ana@example.com", Name: "Ana"}},
+		{name: "empty", want: []string{"id", "email", "name"}},
+		{name: "blank name after normalizing", payload: UserPayload{ID: "u1", Email: "ana@example.com", Name: "  "}, want: []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payload.Normalize()
+			if got := violations(tt.payload.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
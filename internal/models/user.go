@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // UserPayload represents the structure of a user creation message
@@ -11,20 +13,56 @@ type UserPayload struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Username string `json:"username,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	Segment  string `json:"segment,omitempty"` // Optional: e.g. "business" or "individual", selects the welcome content
+
+	// Optional: when either is set a verification email is queued along with the welcome email
+	Code      string `json:"code,omitempty"`
+	VerifyURL string `json:"verify_url,omitempty"`
 }
 
-// Validate validates the user payload
+// Validate validates the user payload, reporting every missing field at once
 func (u *UserPayload) Validate() error {
+	var errs ValidationErrors
 	if u.ID == "" {
-		return fmt.Errorf("missing user ID")
+		errs = append(errs, &ValidationError{Field: "id", Message: "missing user ID"})
 	}
 	if u.Email == "" {
-		return fmt.Errorf("missing user email")
+		errs = append(errs, &ValidationError{Field: "email", Message: "missing user email"})
 	}
 	if u.Name == "" {
-		return fmt.Errorf("missing user name")
+		errs = append(errs, &ValidationError{Field: "name", Message: "missing user name"})
+	}
+	return errs.errOrNil()
+}
+
+// Normalize trims the user fields and lowercases the email domain
+func (u *UserPayload) Normalize() {
+	u.ID = strings.TrimSpace(u.ID)
+	u.Name = strings.TrimSpace(u.Name)
+	_, u.Email = NormalizeAddress(u.Email)
+}
+
+// NeedsVerification reports whether the user should also receive a verification email
+func (u *UserPayload) NeedsVerification() bool {
+	return u.Code != "" || u.VerifyURL != ""
+}
+
+// NewVerificationEmailPayload builds the verification email payload for a newly created user
+func NewVerificationEmailPayload(user *UserPayload, now time.Time) *VerificationEmailPayload {
+	username := user.Username
+	if username == "" {
+		username = user.Name
+	}
+
+	payload := &VerificationEmailPayload{
+		To:        user.Email,
+		Username:  username,
+		Code:      user.Code,
+		VerifyURL: user.VerifyURL,
 	}
-	return nil
+	payload.SetExpiry(now)
+	return payload
 }
 
 // ToJSON converts the payload to JSON bytes
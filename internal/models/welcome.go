@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DefaultLocale is the locale used when a payload doesn't specify one
+const DefaultLocale = "pt-BR"
+
+// WelcomeEmailPayload represents the structure of a welcome email message
+type WelcomeEmailPayload struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Locale  string `json:"locale,omitempty"`
+	UserID  string `json:"user_id,omitempty"` // Optional: ID of the user whose creation triggered the email
+	Segment string `json:"segment,omitempty"` // Optional: user segment selecting the welcome template
+
+	// Optional: call-to-action button replacing the default one, set together
+	CTAText string `json:"cta_text,omitempty"`
+	CTAURL  string `json:"cta_url,omitempty"`
+}
+
+// NewWelcomeEmailPayload builds the welcome email payload for a newly created user
+func NewWelcomeEmailPayload(user *UserPayload) *WelcomeEmailPayload {
+	return &WelcomeEmailPayload{
+		Name:    user.Name,
+		Email:   user.Email,
+		Locale:  user.Locale,
+		UserID:  user.ID,
+		Segment: user.Segment,
+	}
+}
+
+// Validate validates the welcome email payload, reporting every missing field at once
+func (w *WelcomeEmailPayload) Validate() error {
+	var errs ValidationErrors
+	if w.Email == "" {
+		errs = append(errs, ErrMissingRecipient)
+	}
+	if w.Name == "" {
+		errs = append(errs, &ValidationError{Field: "name", Message: "name is required"})
+	}
+	errs = append(errs, validateCTA(w.CTAText, w.CTAURL)...)
+	return errs.errOrNil()
+}
+
+// Normalize trims the recipient address and lowercases its domain
+func (w *WelcomeEmailPayload) Normalize() {
+	_, w.Email = NormalizeAddress(w.Email)
+}
+
+// ToJSON converts the welcome payload to JSON bytes
+func (w *WelcomeEmailPayload) ToJSON() ([]byte, error) {
+	return json.Marshal(w)
+}
+
+// GetLocale returns the payload locale, falling back to DefaultLocale
+func (w *WelcomeEmailPayload) GetLocale() string {
+	if w.Locale == "" {
+		return DefaultLocale
+	}
+	return w.Locale
+}
+
+// GenerateSubject generates the welcome email subject for the payload locale
+func (w *WelcomeEmailPayload) GenerateSubject() string {
+	if strings.HasPrefix(strings.ToLower(w.GetLocale()), "en") {
+		return "Welcome to NorthFi!"
+	}
+	return "Bem-vindo(a) à NorthFi!"
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWelcomeEmailPayloadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload WelcomeEmailPayload
+		want    []string
+	}{
+		{name: "valid", payload: WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com"}},
+		{name: "empty", want: []string{ErrMissingRecipient.Error(), "name"}},
+		{
+			name:    "cta without a url",
+			payload: WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com", CTAText: "Abrir app"},
+			want:    []string{"cta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(tt.payload.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWelcomeEmailPayloadGenerateSubject(t *testing.T) {
+	tests := []struct {
+		locale     string
+		wantLocale string
+		want       string
+	}{
+		{locale: "", wantLocale: DefaultLocale, want: "Bem-vindo(a) à NorthFi!"},
+		{locale: "pt-BR", wantLocale: "pt-BR", want: "Bem-vindo(a) à NorthFi!"},
+		{locale: "en-US", wantLocale: "en-US", want: "Welcome to NorthFi!"},
+		{locale: "EN", wantLocale: "EN", want: "Welcome to NorthFi!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			payload := WelcomeEmailPayload{Locale: tt.locale}
+			if got := payload.GetLocale(); got != tt.wantLocale {
+				t.Errorf("GetLocale() = %q, want %q", got, tt.wantLocale)
+			}
+			if got := payload.GenerateSubject(); got != tt.want {
+				t.Errorf("GenerateSubject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWelcomeEmailPayload(t *testing.T) {
+	user := &UserPayload{ID: "u1", Email: "ana@example.com", Name: "Ana", Locale: "en", Segment: "business"}
+
+	got := NewWelcomeEmailPayload(user)
+	want := WelcomeEmailPayload{Name: "Ana", Email: "ana@example.com", Locale: "en", UserID: "u1", Segment: "business"}
+	if *got != want {
+		t.Errorf("NewWelcomeEmailPayload() = %+v, want %+v", *got, want)
+	}
+}
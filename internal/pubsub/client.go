@@ -3,22 +3,62 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"sync"
+	"time"
 
+	"go_integration/internal/logging"
+	"go_integration/internal/metrics"
 	"go_integration/internal/models"
 
 	"cloud.google.com/go/pubsub"
 )
 
+// Options holds optional settings for the Pub/Sub client
+type Options struct {
+	// MaxConcurrency caps the number of messages processed at once per subscription (0 keeps the Pub/Sub default)
+	MaxConcurrency int
+
+	// SubscriptionConcurrency overrides MaxConcurrency for the subscriptions it lists by ID, e.g. to give
+	// a high priority lane more workers than the low priority one
+	SubscriptionConcurrency map[string]int
+
+	// NackOnParseError redelivers messages whose data cannot be decoded instead of acking and dropping them
+	NackOnParseError bool
+
+	// MessageTimeout cancels a handler still running after this long so the message is redelivered (0 disables it)
+	MessageTimeout time.Duration
+
+	// ExactlyOnce enables exactly-once delivery on the subscriptions ensured by EnsureSubscription
+	ExactlyOnce bool
+
+	// Publish tunes the batching of topics returned by EnsureTopic
+	Publish PublishOptions
+}
+
+// PublishOptions overrides the Pub/Sub publish batching settings (zero values keep the defaults)
+type PublishOptions struct {
+	DelayThreshold time.Duration // Max time a message waits in the batch before it is sent
+	CountThreshold int           // Max messages per batch
+	ByteThreshold  int           // Max bytes per batch
+	NumGoroutines  int           // Goroutines used to send batches
+}
+
 // Client wraps Google Cloud Pub/Sub client
 type Client struct {
 	client    *pubsub.Client
 	projectID string
+	options   Options
+
+	mu     sync.Mutex
+	topics []*pubsub.Topic // Topics returned by EnsureTopic, flushed on Close
 }
 
 // NewClient creates a new Pub/Sub client
-func NewClient(ctx context.Context, projectID string) (*Client, error) {
+func NewClient(ctx context.Context, projectID string, opts Options) (*Client, error) {
 	client, err := pubsub.NewClient(ctx, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
@@ -27,14 +67,34 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 	return &Client{
 		client:    client,
 		projectID: projectID,
+		options:   opts,
 	}, nil
 }
 
-// Close closes the client connection
+// Close flushes pending publishes on every ensured topic and closes the client connection
 func (c *Client) Close() error {
+	c.mu.Lock()
+	topics := c.topics
+	c.topics = nil
+	c.mu.Unlock()
+
+	// Stop blocks until the buffered messages of each topic are sent
+	for _, topic := range topics {
+		topic.Stop()
+	}
+
 	return c.client.Close()
 }
 
+// TopicExists reports whether a topic exists without creating it
+func (c *Client) TopicExists(ctx context.Context, topicID string) (bool, error) {
+	exists, err := c.client.Topic(topicID).Exists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if topic exists: %w", err)
+	}
+	return exists, nil
+}
+
 // EnsureTopic creates a topic if it doesn't exist
 func (c *Client) EnsureTopic(ctx context.Context, topicID string) (*pubsub.Topic, error) {
 	topic := c.client.Topic(topicID)
@@ -52,11 +112,31 @@ func (c *Client) EnsureTopic(ctx context.Context, topicID string) (*pubsub.Topic
 		log.Printf("Created topic: %s", topicID)
 	}
 
+	c.applyPublishSettings(topic)
+	c.trackTopic(topic)
 	return topic, nil
 }
 
-// EnsureSubscription creates a subscription if it doesn't exist
-func (c *Client) EnsureSubscription(ctx context.Context, subID string, topic *pubsub.Topic) (*pubsub.Subscription, error) {
+// trackTopic remembers topic so Close can flush its pending publishes
+func (c *Client) trackTopic(topic *pubsub.Topic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.topics = append(c.topics, topic)
+}
+
+// SubscriptionOptions holds the optional settings of a subscription ensured by EnsureSubscription
+type SubscriptionOptions struct {
+	// RetryPolicy sets the redelivery backoff, applied to new and existing subscriptions
+	RetryPolicy RetryPolicy
+
+	// Filter only delivers the messages whose attributes match the Pub/Sub filter expression,
+	// e.g. attributes.priority = "high". Filters cannot change once the subscription exists.
+	Filter string
+}
+
+// EnsureSubscription creates a subscription if it doesn't exist, or checks that an existing one matches opts
+func (c *Client) EnsureSubscription(ctx context.Context, subID string, topic *pubsub.Topic, opts SubscriptionOptions) (*pubsub.Subscription, error) {
 	sub := c.client.Subscription(subID)
 
 	exists, err := sub.Exists(ctx)
@@ -66,73 +146,292 @@ func (c *Client) EnsureSubscription(ctx context.Context, subID string, topic *pu
 
 	if !exists {
 		sub, err = c.client.CreateSubscription(ctx, subID, pubsub.SubscriptionConfig{
-			Topic: topic,
+			Topic:                     topic,
+			EnableExactlyOnceDelivery: c.options.ExactlyOnce,
+			RetryPolicy:               opts.RetryPolicy.toPubSub(),
+			Filter:                    opts.Filter,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create subscription: %w", err)
 		}
 		log.Printf("Created subscription: %s", subID)
+		return sub, nil
 	}
 
+	if c.options.ExactlyOnce || !opts.RetryPolicy.IsZero() || opts.Filter != "" {
+		if err := c.updateSubscription(ctx, sub, opts); err != nil {
+			return nil, err
+		}
+	}
 	return sub, nil
 }
 
-// Receive wraps the subscription Receive method with a handler function
-func (c *Client) Receive(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.EmailPayload) error) error {
-	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		var payload models.EmailPayload
-		if err := json.Unmarshal(msg.Data, &payload); err != nil {
-			log.Printf("Failed to unmarshal message: %v", err)
-			msg.Nack()
-			return
+// updateSubscription turns on exactly-once delivery and applies the retry policy on an existing subscription
+// created without them. A different filter is an error, as Pub/Sub only sets it on creation.
+func (c *Client) updateSubscription(ctx context.Context, sub *pubsub.Subscription, opts SubscriptionOptions) error {
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription config: %w", err)
+	}
+	if opts.Filter != "" && cfg.Filter != opts.Filter {
+		return fmt.Errorf("subscription %s has filter %q instead of %q: delete it to recreate it with the new filter", sub.ID(), cfg.Filter, opts.Filter)
+	}
+
+	var update pubsub.SubscriptionConfigToUpdate
+	changed := false
+	if c.options.ExactlyOnce && !cfg.EnableExactlyOnceDelivery {
+		update.EnableExactlyOnceDelivery = true
+		changed = true
+	}
+	if !opts.RetryPolicy.matches(cfg.RetryPolicy) {
+		update.RetryPolicy = opts.RetryPolicy.toPubSub()
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	if _, err := sub.Update(ctx, update); err != nil {
+		return fmt.Errorf("failed to update subscription %s: %w", sub.ID(), err)
+	}
+	log.Printf("Updated delivery settings of subscription: %s", sub.ID())
+	return nil
+}
+
+// TopicSpec declares a topic and, optionally, the subscription that consumes it
+type TopicSpec struct {
+	Name         string `yaml:"name" json:"name"`
+	Subscription string `yaml:"subscription,omitempty" json:"subscription,omitempty"`
+
+	// RetryPolicy sets the redelivery backoff of the subscription (zero keeps the Pub/Sub defaults)
+	RetryPolicy RetryPolicy `yaml:"retry_policy,omitempty" json:"retry_policy,omitzero"`
+
+	// Filter restricts the subscription to messages with matching attributes, see SubscriptionOptions.Filter
+	Filter string `yaml:"filter,omitempty" json:"filter,omitempty"`
+}
+
+// TopicHandles holds the topic and subscriptions ensured for the TopicSpecs of a topic
+type TopicHandles struct {
+	Topic        *pubsub.Topic
+	Subscription *pubsub.Subscription // First subscription of the topic, nil when its specs have none
+
+	// Subscriptions holds every subscription of the topic by ID, when several specs fan it out
+	Subscriptions map[string]*pubsub.Subscription
+}
+
+// EnsureAll creates every topic and subscription in specs that doesn't exist, returning the handles keyed by topic name.
+// Specs may repeat a topic to attach several subscriptions to it.
+func (c *Client) EnsureAll(ctx context.Context, specs []TopicSpec) (map[string]TopicHandles, error) {
+	handles := make(map[string]TopicHandles, len(specs))
+	for _, spec := range specs {
+		h, ok := handles[spec.Name]
+		if !ok {
+			topic, err := c.EnsureTopic(ctx, spec.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to ensure topic (%s): %w", spec.Name, err)
+			}
+			h = TopicHandles{Topic: topic, Subscriptions: make(map[string]*pubsub.Subscription)}
 		}
 
-		if err := handler(ctx, &payload); err != nil {
-			log.Printf("Failed to handle message: %v", err)
-			msg.Nack()
-			return
+		if spec.Subscription != "" {
+			sub, err := c.EnsureSubscription(ctx, spec.Subscription, h.Topic, SubscriptionOptions{
+				RetryPolicy: spec.RetryPolicy,
+				Filter:      spec.Filter,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to ensure subscription (%s): %w", spec.Subscription, err)
+			}
+			if h.Subscription == nil {
+				h.Subscription = sub
+			}
+			h.Subscriptions[spec.Subscription] = sub
 		}
+		handles[spec.Name] = h
+	}
+	return handles, nil
+}
 
-		msg.Ack()
-	})
+// DeleteTopic deletes a topic, doing nothing if it doesn't exist
+func (c *Client) DeleteTopic(ctx context.Context, topicID string) error {
+	topic := c.client.Topic(topicID)
+
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if topic exists: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	if err := topic.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", topicID, err)
+	}
+	log.Printf("Deleted topic: %s", topicID)
+	return nil
 }
 
-// ReceiveVerification wraps the subscription Receive method for verification emails
-func (c *Client) ReceiveVerification(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.VerificationEmailPayload) error) error {
-	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		var payload models.VerificationEmailPayload
-		if err := json.Unmarshal(msg.Data, &payload); err != nil {
-			log.Printf("Failed to unmarshal verification message: %v", err)
-			msg.Nack()
-			return
-		}
+// DeleteSubscription deletes a subscription, doing nothing if it doesn't exist
+func (c *Client) DeleteSubscription(ctx context.Context, subID string) error {
+	sub := c.client.Subscription(subID)
 
-		if err := handler(ctx, &payload); err != nil {
-			log.Printf("Failed to handle verification message: %v", err)
-			msg.Nack()
-			return
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if subscription exists: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	if err := sub.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete subscription %s: %w", subID, err)
+	}
+	log.Printf("Deleted subscription: %s", subID)
+	return nil
+}
+
+// CleanupAll deletes the given subscriptions and then the given topics, returning every failure joined
+func (c *Client) CleanupAll(ctx context.Context, subIDs, topicIDs []string) error {
+	var errs []error
+
+	// Subscriptions go first so they are not left detached from a deleted topic
+	for _, subID := range subIDs {
+		if err := c.DeleteSubscription(ctx, subID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, topicID := range topicIDs {
+		if err := c.DeleteTopic(ctx, topicID); err != nil {
+			errs = append(errs, err)
 		}
+	}
 
-		msg.Ack()
-	})
+	return errors.Join(errs...)
+}
+
+// applyPublishSettings configures the topic batching from the client options
+func (c *Client) applyPublishSettings(topic *pubsub.Topic) {
+	opts := c.options.Publish
+	if opts.DelayThreshold > 0 {
+		topic.PublishSettings.DelayThreshold = opts.DelayThreshold
+	}
+	if opts.CountThreshold > 0 {
+		topic.PublishSettings.CountThreshold = opts.CountThreshold
+	}
+	if opts.ByteThreshold > 0 {
+		topic.PublishSettings.ByteThreshold = opts.ByteThreshold
+	}
+	if opts.NumGoroutines > 0 {
+		topic.PublishSettings.NumGoroutines = opts.NumGoroutines
+	}
+}
+
+// applyReceiveSettings configures the subscription flow control from the client options
+func (c *Client) applyReceiveSettings(sub *pubsub.Subscription) {
+	concurrency := c.options.MaxConcurrency
+	if n, ok := c.options.SubscriptionConcurrency[sub.ID()]; ok && n > 0 {
+		concurrency = n
+	}
+	if concurrency <= 0 {
+		return
+	}
+
+	sub.ReceiveSettings.MaxOutstandingMessages = concurrency
+	// No point opening more streams than messages we are willing to process
+	sub.ReceiveSettings.NumGoroutines = min(concurrency, pubsub.DefaultReceiveSettings.NumGoroutines)
+}
+
+// Receive wraps the subscription Receive method with a handler function
+func (c *Client) Receive(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.EmailPayload) error) error {
+	return receiveJSON(ctx, c, sub, "email", handler)
+}
+
+// ReceiveVerification wraps the subscription Receive method for verification emails
+func (c *Client) ReceiveVerification(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.VerificationEmailPayload) error) error {
+	return receiveJSON(ctx, c, sub, "verification", handler)
 }
 
 // ReceiveUser wraps the subscription Receive method for user creation messages
 func (c *Client) ReceiveUser(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.UserPayload) error) error {
+	return receiveJSON(ctx, c, sub, "user", handler)
+}
+
+// ReceiveWelcome wraps the subscription Receive method for welcome emails
+func (c *Client) ReceiveWelcome(ctx context.Context, sub *pubsub.Subscription, handler func(context.Context, *models.WelcomeEmailPayload) error) error {
+	return receiveJSON(ctx, c, sub, "welcome", handler)
+}
+
+// receiveJSON receives messages from sub, decodes their JSON data into T and passes it to handler
+func receiveJSON[T any](ctx context.Context, c *Client, sub *pubsub.Subscription, kind string, handler func(context.Context, *T) error) error {
+	c.applyReceiveSettings(sub)
 	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		var payload models.UserPayload
-		if err := json.Unmarshal(msg.Data, &payload); err != nil {
-			log.Printf("Failed to unmarshal user message: %v", err)
-			msg.Nack()
+		ctx, cancel := handlerContext(ctx)
+		defer cancel()
+
+		ctx = logging.WithMessageID(ctx, msg.ID)
+		if !msg.PublishTime.IsZero() {
+			ctx = logging.WithPublishTime(ctx, msg.PublishTime)
+			metrics.QueueLatency.Observe(kind, time.Since(msg.PublishTime))
+		}
+		msgCtx := ctx // Acks outlive the message timeout applied to the handler
+
+		var payload T
+		data, err := decodeData(msg.Data, msg.Attributes)
+		if err == nil {
+			err = json.Unmarshal(data, &payload)
+		}
+		if err != nil {
+			if c.options.NackOnParseError {
+				log.Printf("Failed to unmarshal %s message %s: %v", kind, msg.ID, err)
+				nack(msgCtx, msg, kind)
+				return
+			}
+			// Redelivering a message that can never be decoded would loop forever, so drop it
+			log.Printf("Dropping malformed %s message %s (%d bytes): %v", kind, msg.ID, len(msg.Data), err)
+			ack(msgCtx, msg, kind)
 			return
 		}
 
-		if err := handler(ctx, &payload); err != nil {
-			log.Printf("Failed to handle user message: %v", err)
-			msg.Nack()
+		if c.options.MessageTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.options.MessageTimeout)
+			defer cancel()
+		}
+
+		if err := handleSafely(ctx, handler, &payload); err != nil {
+			log.Printf("Failed to handle %s message %s: %v", kind, msg.ID, err)
+			nack(msgCtx, msg, kind)
 			return
 		}
 
-		msg.Ack()
+		ack(msgCtx, msg, kind)
 	})
 }
+
+// ack acknowledges msg and waits for the result, which only reports failures with exactly-once delivery.
+// A failed ack means the message will be redelivered even though it was handled.
+func ack(ctx context.Context, msg *pubsub.Message, kind string) {
+	status, err := msg.AckWithResult().Get(ctx)
+	if err != nil || status != pubsub.AcknowledgeStatusSuccess {
+		log.Printf("Failed to ack %s message %s (status %d): %v", kind, msg.ID, status, err)
+	}
+}
+
+// nack negatively acknowledges msg and waits for the result, logging when Pub/Sub rejects it
+func nack(ctx context.Context, msg *pubsub.Message, kind string) {
+	status, err := msg.NackWithResult().Get(ctx)
+	if err != nil || status != pubsub.AcknowledgeStatusSuccess {
+		log.Printf("Failed to nack %s message %s (status %d): %v", kind, msg.ID, status, err)
+	}
+}
+
+// handleSafely calls handler, converting a panic into an error so a single message cannot crash the worker
+func handleSafely[T any](ctx context.Context, handler func(context.Context, *T) error, payload *T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in message handler (message %s): %v\n%s", logging.MessageID(ctx), r, debug.Stack())
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, payload)
+}
@@ -0,0 +1,503 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/models"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newTestClient returns a Client backed by an in-process Pub/Sub fake server
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	client, _ := newTestClientWithServer(t)
+	return client
+}
+
+// newTestClientWithServer returns a Client and the in-process Pub/Sub fake server backing it
+func newTestClientWithServer(t *testing.T) (*Client, *pstest.Server) {
+	t.Helper()
+
+	server := pstest.NewServer()
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial the fake server: %v", err)
+	}
+	client, err := pubsub.NewClient(context.Background(), "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create the pubsub client: %v", err)
+	}
+
+	c := &Client{client: client, projectID: "test-project"}
+	t.Cleanup(func() { c.Close() })
+	return c, server
+}
+
+func TestEnsureAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []TopicSpec
+		specs    []TopicSpec
+		wantSubs map[string][]string // Topic name to the subscription IDs of its handles
+	}{
+		{
+			name:     "creates topics and subscriptions",
+			specs:    []TopicSpec{{Name: "emails", Subscription: "emails-worker"}, {Name: "users"}},
+			wantSubs: map[string][]string{"emails": {"emails-worker"}, "users": nil},
+		},
+		{
+			name:     "keeps existing ones",
+			existing: []TopicSpec{{Name: "emails", Subscription: "emails-worker"}},
+			specs:    []TopicSpec{{Name: "emails", Subscription: "emails-worker"}},
+			wantSubs: map[string][]string{"emails": {"emails-worker"}},
+		},
+		{
+			name: "fans a topic out to several subscriptions",
+			specs: []TopicSpec{
+				{Name: "emails", Subscription: "emails-high", Filter: `attributes.priority = "high"`},
+				{Name: "emails", Subscription: "emails-low", Filter: `attributes.priority = "low"`},
+			},
+			wantSubs: map[string][]string{"emails": {"emails-high", "emails-low"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t)
+			if _, err := client.EnsureAll(ctx, tt.existing); err != nil {
+				t.Fatalf("failed to create the existing topics: %v", err)
+			}
+
+			handles, err := client.EnsureAll(ctx, tt.specs)
+			if err != nil {
+				t.Fatalf("EnsureAll failed: %v", err)
+			}
+
+			if len(handles) != len(tt.wantSubs) {
+				t.Fatalf("got handles for %d topics, want %d", len(handles), len(tt.wantSubs))
+			}
+			for topic, subs := range tt.wantSubs {
+				h := handles[topic]
+				if exists, err := client.TopicExists(ctx, topic); err != nil || !exists {
+					t.Errorf("topic %s exists = %v, %v", topic, exists, err)
+				}
+				if len(h.Subscriptions) != len(subs) {
+					t.Errorf("topic %s has %d subscriptions, want %d", topic, len(h.Subscriptions), len(subs))
+				}
+				if len(subs) > 0 && (h.Subscription == nil || h.Subscription.ID() != subs[0]) {
+					t.Errorf("topic %s first subscription = %v, want %s", topic, h.Subscription, subs[0])
+				}
+				for _, subID := range subs {
+					if _, ok := h.Subscriptions[subID]; !ok {
+						t.Errorf("topic %s is missing subscription %s", topic, subID)
+					}
+					if exists, err := client.client.Subscription(subID).Exists(ctx); err != nil || !exists {
+						t.Errorf("subscription %s exists = %v, %v", subID, exists, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureAllRejectsChangedFilter(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	if _, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-high", Filter: `attributes.priority = "high"`}}); err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+
+	if _, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-high", Filter: `attributes.priority = "low"`}}); err == nil {
+		t.Error("EnsureAll accepted a different filter on an existing subscription")
+	}
+}
+
+func TestFilteredSubscriptionsReceiveMatchingMessages(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+
+	ctx := context.Background()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{
+		{Name: "emails", Subscription: "emails-high", Filter: `attributes.priority = "high"`},
+		{Name: "emails", Subscription: "emails-all"},
+	})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+	for _, priority := range []string{"high", "low", "high"} {
+		server.Publish("projects/test-project/topics/emails", []byte("{}"), map[string]string{PriorityAttribute: priority})
+	}
+
+	tests := []struct {
+		sub  string
+		want []string
+	}{
+		{sub: "emails-high", want: []string{"high", "high"}},
+		{sub: "emails-all", want: []string{"high", "high", "low"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sub, func(t *testing.T) {
+			var (
+				mu  sync.Mutex
+				got []string
+			)
+			receiveCtx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			err := handles["emails"].Subscriptions[tt.sub].Receive(receiveCtx, func(_ context.Context, msg *pubsub.Message) {
+				mu.Lock()
+				got = append(got, msg.Attributes[PriorityAttribute])
+				mu.Unlock()
+				msg.Ack()
+			})
+			if err != nil {
+				t.Fatalf("Receive failed: %v", err)
+			}
+
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("received priorities %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupAll(t *testing.T) {
+	tests := []struct {
+		name   string
+		subs   []string
+		topics []string
+	}{
+		{name: "existing topic and subscription", subs: []string{"emails-worker"}, topics: []string{"emails"}},
+		{name: "missing ones are skipped", subs: []string{"emails-worker", "unknown-sub"}, topics: []string{"emails", "unknown"}},
+		{name: "nothing to delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t)
+			if _, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-worker"}}); err != nil {
+				t.Fatalf("EnsureAll failed: %v", err)
+			}
+
+			if err := client.CleanupAll(ctx, tt.subs, tt.topics); err != nil {
+				t.Fatalf("CleanupAll failed: %v", err)
+			}
+
+			for _, subID := range tt.subs {
+				if exists, err := client.client.Subscription(subID).Exists(ctx); err != nil || exists {
+					t.Errorf("subscription %s exists = %v, %v after cleanup", subID, exists, err)
+				}
+			}
+			for _, topicID := range tt.topics {
+				if exists, err := client.TopicExists(ctx, topicID); err != nil || exists {
+					t.Errorf("topic %s exists = %v, %v after cleanup", topicID, exists, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteTopicAndSubscription(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	topic, err := client.EnsureTopic(ctx, "emails")
+	if err != nil {
+		t.Fatalf("EnsureTopic failed: %v", err)
+	}
+	if _, err := client.EnsureSubscription(ctx, "emails-worker", topic, SubscriptionOptions{}); err != nil {
+		t.Fatalf("EnsureSubscription failed: %v", err)
+	}
+
+	if err := client.DeleteSubscription(ctx, "emails-worker"); err != nil {
+		t.Fatalf("DeleteSubscription failed: %v", err)
+	}
+	if exists, err := client.client.Subscription("emails-worker").Exists(ctx); err != nil || exists {
+		t.Errorf("subscription exists = %v, %v after delete", exists, err)
+	}
+
+	if err := client.DeleteTopic(ctx, "emails"); err != nil {
+		t.Fatalf("DeleteTopic failed: %v", err)
+	}
+	if exists, err := client.TopicExists(ctx, "emails"); err != nil || exists {
+		t.Errorf("topic exists = %v, %v after delete", exists, err)
+	}
+
+	// Deleting again is a no-op
+	if err := client.DeleteSubscription(ctx, "emails-worker"); err != nil {
+		t.Errorf("DeleteSubscription of a missing subscription failed: %v", err)
+	}
+	if err := client.DeleteTopic(ctx, "emails"); err != nil {
+		t.Errorf("DeleteTopic of a missing topic failed: %v", err)
+	}
+}
+
+// waitForAck polls the fake server until its only message is acked or nacked, or ctx is done
+func waitForAck(ctx context.Context, server *pstest.Server) (acked, nacked bool) {
+	for !acked && !nacked && ctx.Err() == nil {
+		for _, msg := range server.Messages() {
+			acked = msg.Acks > 0
+			nacked = slices.ContainsFunc(msg.Modacks, func(m pstest.Modack) bool { return m.AckDeadline == 0 })
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return acked, nacked
+}
+
+func TestReceiveMalformedMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		nack     bool
+		wantAck  bool
+		wantNack bool
+	}{
+		{name: "dropped by default", wantAck: true},
+		{name: "redelivered when configured", nack: true, wantNack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClientWithServer(t)
+			client.options.NackOnParseError = tt.nack
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-worker"}})
+			if err != nil {
+				t.Fatalf("EnsureAll failed: %v", err)
+			}
+			server.Publish("projects/test-project/topics/emails", []byte("{not json"), nil)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- client.Receive(ctx, handles["emails"].Subscription, func(context.Context, *models.EmailPayload) error {
+					t.Error("handler called for a malformed message")
+					return nil
+				})
+			}()
+
+			acked, nacked := waitForAck(ctx, server)
+			cancel()
+			<-done
+
+			if acked != tt.wantAck || nacked != tt.wantNack {
+				t.Errorf("acked = %v, nacked = %v, want %v and %v", acked, nacked, tt.wantAck, tt.wantNack)
+			}
+		})
+	}
+}
+
+func TestEnsureTopicPublishSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		opts PublishOptions
+		want pubsub.PublishSettings
+	}{
+		{name: "defaults", want: pubsub.DefaultPublishSettings},
+		{
+			name: "configured",
+			opts: PublishOptions{DelayThreshold: 50 * time.Millisecond, CountThreshold: 500, ByteThreshold: 1 << 20, NumGoroutines: 4},
+			want: pubsub.PublishSettings{DelayThreshold: 50 * time.Millisecond, CountThreshold: 500, ByteThreshold: 1 << 20, NumGoroutines: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			client.options.Publish = tt.opts
+
+			topic, err := client.EnsureTopic(context.Background(), "emails")
+			if err != nil {
+				t.Fatalf("EnsureTopic failed: %v", err)
+			}
+
+			got := topic.PublishSettings
+			if got.DelayThreshold != tt.want.DelayThreshold || got.CountThreshold != tt.want.CountThreshold ||
+				got.ByteThreshold != tt.want.ByteThreshold || got.NumGoroutines != tt.want.NumGoroutines {
+				t.Errorf("PublishSettings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloseFlushesPendingPublishes(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+	// Batch everything so nothing is sent before Close
+	client.options.Publish = PublishOptions{DelayThreshold: time.Hour, CountThreshold: 1000}
+
+	topic, err := client.EnsureTopic(context.Background(), "emails")
+	if err != nil {
+		t.Fatalf("EnsureTopic failed: %v", err)
+	}
+
+	const count = 3
+	for range count {
+		topic.Publish(context.Background(), &pubsub.Message{Data: []byte("{}")})
+	}
+	if got := len(server.Messages()); got != 0 {
+		t.Fatalf("%d messages sent before Close, want them batched", got)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := len(server.Messages()); got != count {
+		t.Errorf("%d messages flushed on Close, want %d", got, count)
+	}
+}
+
+func TestReceiveRecoversFromPanics(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-worker"}})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+	server.Publish("projects/test-project/topics/emails", []byte(`{"to":"ana@example.com"}`), nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Receive(ctx, handles["emails"].Subscription, func(context.Context, *models.EmailPayload) error {
+			var payload *models.EmailPayload
+			_ = payload.To // nil pointer dereference
+			return nil
+		})
+	}()
+
+	acked, nacked := waitForAck(ctx, server)
+	if acked || !nacked {
+		t.Errorf("acked = %v, nacked = %v, want the panicking message nacked", acked, nacked)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Receive stopped after a handler panic: %v", err)
+	default:
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Receive failed: %v", err)
+	}
+}
+
+func TestReceiveMessageTimeout(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+	client.options.MessageTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-worker"}})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+	server.Publish("projects/test-project/topics/emails", []byte(`{"to":"ana@example.com"}`), nil)
+
+	handlerErr := make(chan error, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Receive(ctx, handles["emails"].Subscription, func(ctx context.Context, _ *models.EmailPayload) error {
+			// A hung send only returns once the message deadline cancels it
+			<-ctx.Done()
+			select {
+			case handlerErr <- ctx.Err():
+			default:
+			}
+			return ctx.Err()
+		})
+	}()
+
+	acked, nacked := waitForAck(ctx, server)
+	cancel()
+	<-done
+
+	if err := <-handlerErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handler context error = %v, want the message deadline", err)
+	}
+	if acked || !nacked {
+		t.Errorf("acked = %v, nacked = %v, want the timed out message nacked", acked, nacked)
+	}
+}
+
+func TestEnsureSubscriptionExactlyOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "new subscription"},
+		{name: "existing subscription is updated", existing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t)
+			topic, err := client.EnsureTopic(ctx, "users")
+			if err != nil {
+				t.Fatalf("EnsureTopic failed: %v", err)
+			}
+			if tt.existing {
+				if _, err := client.EnsureSubscription(ctx, "users-worker", topic, SubscriptionOptions{}); err != nil {
+					t.Fatalf("EnsureSubscription failed: %v", err)
+				}
+			}
+
+			client.options.ExactlyOnce = true
+			sub, err := client.EnsureSubscription(ctx, "users-worker", topic, SubscriptionOptions{})
+			if err != nil {
+				t.Fatalf("EnsureSubscription failed: %v", err)
+			}
+
+			cfg, err := sub.Config(ctx)
+			if err != nil {
+				t.Fatalf("failed to read the subscription config: %v", err)
+			}
+			if !cfg.EnableExactlyOnceDelivery {
+				t.Error("exactly-once delivery is not enabled")
+			}
+		})
+	}
+}
+
+func TestReceiveAcksWithExactlyOnce(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+	client.options.ExactlyOnce = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "users", Subscription: "users-worker"}})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+	server.Publish("projects/test-project/topics/users", []byte(`{"id":"u1","email":"ana@example.com","name":"Ana"}`), nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.ReceiveUser(ctx, handles["users"].Subscription, func(context.Context, *models.UserPayload) error {
+			return nil
+		})
+	}()
+
+	acked, nacked := waitForAck(ctx, server)
+	cancel()
+	<-done
+
+	if !acked || nacked {
+		t.Errorf("acked = %v, nacked = %v, want the handled message acked", acked, nacked)
+	}
+}
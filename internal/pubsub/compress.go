@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+)
+
+// ContentEncodingAttribute is the message attribute naming the encoding of the message data
+const ContentEncodingAttribute = "content_encoding"
+
+// ContentEncodingGzip marks message data compressed with gzip
+const ContentEncodingGzip = "gzip"
+
+// GzipPublisher compresses messages of at least minSize bytes before handing them to the next Publisher
+type GzipPublisher struct {
+	next    Publisher
+	minSize int
+}
+
+// NewGzipPublisher wraps next so that payloads of at least minSize bytes are published gzip-compressed
+func NewGzipPublisher(next Publisher, minSize int) *GzipPublisher {
+	return &GzipPublisher{
+		next:    next,
+		minSize: minSize,
+	}
+}
+
+// Publish compresses data when it is large enough, marking it with the content_encoding attribute.
+// Data that already carries a content_encoding, such as a replayed dead letter, is published unchanged.
+func (p *GzipPublisher) Publish(ctx context.Context, data []byte, attrs map[string]string) (string, error) {
+	if len(data) < p.minSize || attrs[ContentEncodingAttribute] != "" {
+		return p.next.Publish(ctx, data, attrs)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("failed to compress message: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress message: %w", err)
+	}
+
+	compressedAttrs := maps.Clone(attrs)
+	if compressedAttrs == nil {
+		compressedAttrs = make(map[string]string, 1)
+	}
+	compressedAttrs[ContentEncodingAttribute] = ContentEncodingGzip
+	return p.next.Publish(ctx, buf.Bytes(), compressedAttrs)
+}
+
+// decodeData returns the message data, decompressing it when its content_encoding attribute says gzip
+func decodeData(data []byte, attrs map[string]string) ([]byte, error) {
+	switch encoding := attrs[ContentEncodingAttribute]; encoding {
+	case "", "identity":
+		return data, nil
+	case ContentEncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress message: %w", err)
+		}
+		defer zr.Close()
+
+		decoded, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress message: %w", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGzipPublisherRoundTrip(t *testing.T) {
+	large := []byte(`{"body":"` + strings.Repeat("Olá ", 500) + `"}`)
+
+	tests := []struct {
+		name           string
+		data           []byte
+		attrs          map[string]string
+		wantCompressed bool
+	}{
+		{name: "small payload is sent as is", data: []byte(`{"to":"ana@example.com"}`)},
+		{name: "large payload is compressed", data: large, wantCompressed: true},
+		{name: "attributes are kept", data: large, attrs: map[string]string{PriorityAttribute: "high"}, wantCompressed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingPublisher{}
+			if _, err := NewGzipPublisher(next, 1024).Publish(context.Background(), tt.data, tt.attrs); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+
+			data, attrs := next.data[0], next.attrs[0]
+			if compressed := attrs[ContentEncodingAttribute] == ContentEncodingGzip; compressed != tt.wantCompressed {
+				t.Fatalf("compressed = %v, want %v", compressed, tt.wantCompressed)
+			}
+			if tt.wantCompressed && len(data) >= len(tt.data) {
+				t.Errorf("compressed size %d is not smaller than %d", len(data), len(tt.data))
+			}
+			for key, value := range tt.attrs {
+				if attrs[key] != value {
+					t.Errorf("attribute %s = %q, want %q", key, attrs[key], value)
+				}
+			}
+
+			decoded, err := decodeData(data, attrs)
+			if err != nil {
+				t.Fatalf("decodeData failed: %v", err)
+			}
+			if !bytes.Equal(decoded, tt.data) {
+				t.Error("decoded data differs from the published data")
+			}
+		})
+	}
+}
+
+func TestGzipPublisherReplaysCompressedMessage(t *testing.T) {
+	payload := []byte(`{"body":"` + strings.Repeat("Olá ", 500) + `"}`)
+
+	// The API compresses the original message, which later ends up in a dead-letter subscription
+	original := &recordingPublisher{}
+	if _, err := NewGzipPublisher(original, 1024).Publish(context.Background(), payload, nil); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	// Replaying republishes the dead letter's data and attributes through the same wrapper
+	replayed := &recordingPublisher{}
+	if _, err := NewGzipPublisher(replayed, 1024).Publish(context.Background(), original.data[0], original.attrs[0]); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if !bytes.Equal(replayed.data[0], original.data[0]) {
+		t.Error("replayed data was compressed again")
+	}
+	decoded, err := decodeData(replayed.data[0], replayed.attrs[0])
+	if err != nil {
+		t.Fatalf("decodeData failed: %v", err)
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Error("decoded replay differs from the original payload")
+	}
+}
+
+func TestDecodeDataErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+	}{
+		{name: "unsupported encoding", encoding: "br"},
+		{name: "corrupt gzip", encoding: ContentEncodingGzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeData([]byte("not compressed"), map[string]string{ContentEncodingAttribute: tt.encoding}); err == nil {
+				t.Error("decodeData succeeded, want an error")
+			}
+		})
+	}
+}
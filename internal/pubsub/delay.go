@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Attributes of messages published to the delay topic
+const (
+	// DeliverAtAttribute holds the RFC 3339 time the message is released to its target topic
+	DeliverAtAttribute = "deliver_at"
+
+	// TargetTopicAttribute names the topic the message is republished to once it is due
+	TargetTopicAttribute = "target_topic"
+)
+
+// DefaultDelayMaxHold is how long ReceiveDelayed holds a message before handing it back to the delay topic
+const DefaultDelayMaxHold = 10 * time.Minute
+
+// DefaultDelayMaxHeld is how many messages ReceiveDelayed holds at once
+const DefaultDelayMaxHeld = 1000
+
+// DelayOptions tunes how ReceiveDelayed holds scheduled messages (zero values use the defaults)
+type DelayOptions struct {
+	// MaxHold is how long a message due later is held before it is republished to the delay topic
+	MaxHold time.Duration
+
+	// MaxHeld caps the messages held at once; the delay subscription uses it instead of the worker
+	// concurrency, since held messages are only waiting
+	MaxHeld int
+}
+
+// DelayAttributes returns the attributes that hold a message on the delay topic until deliverAt
+func DelayAttributes(targetTopic string, deliverAt time.Time) map[string]string {
+	return map[string]string{
+		DeliverAtAttribute:   deliverAt.UTC().Format(time.RFC3339),
+		TargetTopicAttribute: targetTopic,
+	}
+}
+
+// ReceiveDelayed holds the messages of the delay subscription until their deliver_at time and then
+// republishes them to their target topic, looked up in targets by name. Messages due later than MaxHold
+// are republished to the delay topic through delay after MaxHold, so holding them never uses up delivery
+// attempts the way a nack does.
+func (c *Client) ReceiveDelayed(ctx context.Context, sub *pubsub.Subscription, delay Publisher, targets map[string]Publisher, opts DelayOptions) error {
+	releaser := newDelayReleaser(delay, targets, opts.MaxHold)
+	maxHeld := opts.MaxHeld
+	if maxHeld <= 0 {
+		maxHeld = DefaultDelayMaxHeld
+	}
+
+	// Held messages sit in flow control until they are acked, so size it for holding rather than processing
+	sub.ReceiveSettings.MaxOutstandingMessages = maxHeld
+	sub.ReceiveSettings.NumGoroutines = 1
+	// Keep extending the ack deadline while a message is held
+	sub.ReceiveSettings.MaxExtension = releaser.maxHold + time.Minute
+
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		if releaser.handle(ctx, msg) {
+			ack(ctx, msg, "delayed")
+		} else {
+			nack(ctx, msg, "delayed")
+		}
+	})
+}
+
+// delayReleaser releases the messages of the delay topic once they are due
+type delayReleaser struct {
+	delay   Publisher
+	targets map[string]Publisher
+	maxHold time.Duration
+	now     func() time.Time
+	sleep   func(context.Context, time.Duration) error
+}
+
+// newDelayReleaser creates a delayReleaser holding messages for at most maxHold (<= 0 uses DefaultDelayMaxHold)
+func newDelayReleaser(delay Publisher, targets map[string]Publisher, maxHold time.Duration) *delayReleaser {
+	if maxHold <= 0 {
+		maxHold = DefaultDelayMaxHold
+	}
+	return &delayReleaser{
+		delay:   delay,
+		targets: targets,
+		maxHold: maxHold,
+		now:     time.Now,
+		sleep:   sleepCtx,
+	}
+}
+
+// handle holds msg until it is due and publishes it to its target topic, or back to the delay topic when
+// it is due after maxHold. It reports whether msg can be acked; false means it must be redelivered.
+func (d *delayReleaser) handle(ctx context.Context, msg *pubsub.Message) bool {
+	targetTopic := msg.Attributes[TargetTopicAttribute]
+	target, ok := d.targets[targetTopic]
+	if !ok {
+		// Redelivering a message without a known destination would loop forever, so drop it
+		log.Printf("Dropping delayed message %s with unknown target topic %q", msg.ID, targetTopic)
+		return true
+	}
+
+	deliverAt, err := time.Parse(time.RFC3339, msg.Attributes[DeliverAtAttribute])
+	if err != nil {
+		// Without a valid time there is nothing to wait for, so release it right away
+		log.Printf("Invalid %s on delayed message %s, releasing it now: %v", DeliverAtAttribute, msg.ID, err)
+		deliverAt = d.now()
+	}
+
+	wait := deliverAt.Sub(d.now())
+	if wait > d.maxHold {
+		return d.requeue(ctx, msg, deliverAt)
+	}
+	if wait > 0 {
+		if err := d.sleep(ctx, wait); err != nil {
+			return false
+		}
+	}
+
+	attrs := maps.Clone(msg.Attributes)
+	delete(attrs, DeliverAtAttribute)
+	delete(attrs, TargetTopicAttribute)
+
+	id, err := target.Publish(ctx, msg.Data, attrs)
+	if err != nil {
+		log.Printf("Failed to release delayed message %s: %v", msg.ID, err)
+		return false
+	}
+
+	log.Printf("Released delayed message %s to %s as %s", msg.ID, targetTopic, id)
+	return true
+}
+
+// requeue holds msg for maxHold and then republishes it unchanged to the delay topic, where it starts
+// over with a fresh delivery attempt count
+func (d *delayReleaser) requeue(ctx context.Context, msg *pubsub.Message, deliverAt time.Time) bool {
+	if err := d.sleep(ctx, d.maxHold); err != nil {
+		return false
+	}
+
+	id, err := d.delay.Publish(ctx, msg.Data, msg.Attributes)
+	if err != nil {
+		log.Printf("Failed to requeue delayed message %s: %v", msg.ID, err)
+		return false
+	}
+
+	log.Printf("Requeued delayed message %s due at %s as %s", msg.ID, deliverAt.Format(time.RFC3339), id)
+	return true
+}
+
+// sleepCtx waits for d, returning early with the context error when ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("delay interrupted: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// recordingPublisher records every message it publishes, failing with err when set
+type recordingPublisher struct {
+	mu    sync.Mutex
+	err   error
+	data  [][]byte
+	attrs []map[string]string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, data []byte, attrs map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return "", p.err
+	}
+	p.data = append(p.data, data)
+	p.attrs = append(p.attrs, attrs)
+	return "id", nil
+}
+
+func TestDelayReleaserHandle(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	const maxHold = 10 * time.Minute
+
+	tests := []struct {
+		name         string
+		attrs        map[string]string
+		sleepErr     error
+		targetErr    error
+		wantAck      bool
+		wantSleep    time.Duration
+		wantReleased int
+		wantRequeued int
+	}{
+		{
+			name:         "due now is released",
+			attrs:        DelayAttributes("emails", now),
+			wantAck:      true,
+			wantReleased: 1,
+		},
+		{
+			name:         "due soon is held then released",
+			attrs:        DelayAttributes("emails", now.Add(time.Minute)),
+			wantAck:      true,
+			wantSleep:    time.Minute,
+			wantReleased: 1,
+		},
+		{
+			name:         "due later is requeued instead of nacked",
+			attrs:        DelayAttributes("emails", now.Add(time.Hour)),
+			wantAck:      true,
+			wantSleep:    maxHold,
+			wantRequeued: 1,
+		},
+		{
+			name:    "unknown target is dropped",
+			attrs:   DelayAttributes("other", now),
+			wantAck: true,
+		},
+		{
+			name:         "invalid time is released now",
+			attrs:        map[string]string{TargetTopicAttribute: "emails", DeliverAtAttribute: "soon"},
+			wantAck:      true,
+			wantReleased: 1,
+		},
+		{
+			name:      "interrupted hold is redelivered",
+			attrs:     DelayAttributes("emails", now.Add(time.Hour)),
+			sleepErr:  context.Canceled,
+			wantSleep: maxHold,
+		},
+		{
+			name:      "failed release is redelivered",
+			attrs:     DelayAttributes("emails", now),
+			targetErr: errors.New("unavailable"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := &recordingPublisher{}
+			target := &recordingPublisher{err: tt.targetErr}
+			releaser := newDelayReleaser(delay, map[string]Publisher{"emails": target}, maxHold)
+			releaser.now = func() time.Time { return now }
+
+			var slept time.Duration
+			releaser.sleep = func(_ context.Context, d time.Duration) error {
+				slept = d
+				return tt.sleepErr
+			}
+
+			msg := &pubsub.Message{ID: "m1", Data: []byte("{}"), Attributes: tt.attrs}
+			if got := releaser.handle(context.Background(), msg); got != tt.wantAck {
+				t.Errorf("ack = %v, want %v", got, tt.wantAck)
+			}
+			if slept != tt.wantSleep {
+				t.Errorf("held for %v, want %v", slept, tt.wantSleep)
+			}
+			if len(target.attrs) != tt.wantReleased || len(delay.attrs) != tt.wantRequeued {
+				t.Fatalf("released %d and requeued %d, want %d and %d", len(target.attrs), len(delay.attrs), tt.wantReleased, tt.wantRequeued)
+			}
+
+			// Released messages lose the delay attributes, requeued ones keep them to be held again
+			for _, attrs := range target.attrs {
+				if _, ok := attrs[DeliverAtAttribute]; ok {
+					t.Errorf("released message kept %s", DeliverAtAttribute)
+				}
+			}
+			for _, attrs := range delay.attrs {
+				if attrs[DeliverAtAttribute] != tt.attrs[DeliverAtAttribute] {
+					t.Errorf("requeued %s = %q, want %q", DeliverAtAttribute, attrs[DeliverAtAttribute], tt.attrs[DeliverAtAttribute])
+				}
+			}
+		})
+	}
+}
+
+// releasePublisher reports the time of every message it publishes
+type releasePublisher struct {
+	released chan time.Time
+}
+
+func (p *releasePublisher) Publish(context.Context, []byte, map[string]string) (string, error) {
+	p.released <- time.Now()
+	return "id", nil
+}
+
+func TestReceiveDelayedReleasesWhenDue(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails-delay", Subscription: "emails-delay-worker"}})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+
+	// deliver_at has second precision, so schedule on a whole second
+	deliverAt := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	server.Publish("projects/test-project/topics/emails-delay", []byte("{}"), DelayAttributes("emails", deliverAt))
+
+	target := &releasePublisher{released: make(chan time.Time, 1)}
+	done := make(chan error, 1)
+	go func() {
+		done <- client.ReceiveDelayed(ctx, handles["emails-delay"].Subscription, &recordingPublisher{}, map[string]Publisher{"emails": target}, DelayOptions{})
+	}()
+
+	select {
+	case releasedAt := <-target.released:
+		if releasedAt.Before(deliverAt) {
+			t.Errorf("released at %s, before its scheduled time %s", releasedAt.Format(time.RFC3339Nano), deliverAt.Format(time.RFC3339))
+		}
+	case <-ctx.Done():
+		t.Fatal("delayed message was never released")
+	}
+
+	if acked, _ := waitForAck(ctx, server); !acked {
+		t.Error("released message was not acked")
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// DefaultDrainIdleTimeout is how long Drain waits for a new message before assuming the subscription is empty
+const DefaultDrainIdleTimeout = 5 * time.Second
+
+// Drain receives up to maxMessages from sub (0 means no limit), stopping once the subscription stays idle
+// for DefaultDrainIdleTimeout. Messages the handler fails are nacked. It returns the number handled successfully.
+func (c *Client) Drain(ctx context.Context, sub *pubsub.Subscription, maxMessages int, handler func(context.Context, *pubsub.Message) error) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		received int
+		handled  int
+	)
+	activity := make(chan struct{}, 1)
+
+	// Stop receiving once no message arrived for a while
+	go func() {
+		timer := time.NewTimer(DefaultDrainIdleTimeout)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-activity:
+				timer.Reset(DefaultDrainIdleTimeout)
+			case <-timer.C:
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+
+		mu.Lock()
+		if maxMessages > 0 && received >= maxMessages {
+			mu.Unlock()
+			msg.Nack()
+			cancel()
+			return
+		}
+		received++
+		mu.Unlock()
+
+		if err := handler(ctx, msg); err != nil {
+			log.Printf("Failed to drain message %s: %v", msg.ID, err)
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+	if err != nil {
+		return handled, fmt.Errorf("failed to drain subscription %s: %w", sub.ID(), err)
+	}
+	return handled, nil
+}
+
+// ReplayDeadLetters republishes up to maxMessages from the dead-letter subscription subID to publisher,
+// keeping each message's data and attributes. It returns the number of messages replayed.
+func (c *Client) ReplayDeadLetters(ctx context.Context, subID string, publisher Publisher, maxMessages int) (int, error) {
+	sub := c.client.Subscription(subID)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if subscription exists: %w", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("dead-letter subscription %s does not exist", subID)
+	}
+
+	// Replay one message at a time so maxMessages is honored exactly
+	sub.ReceiveSettings.MaxOutstandingMessages = 1
+	sub.ReceiveSettings.NumGoroutines = 1
+
+	return c.Drain(ctx, sub, maxMessages, func(ctx context.Context, msg *pubsub.Message) error {
+		id, err := publisher.Publish(ctx, msg.Data, msg.Attributes)
+		if err != nil {
+			return err
+		}
+		log.Printf("Replayed dead-letter message %s as %s", msg.ID, id)
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+// Package memory provides an in-process stand-in for Pub/Sub so services and
+// handlers can be exercised without the emulator.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go_integration/internal/logging"
+)
+
+// Message is a message delivered to a subscriber
+type Message struct {
+	ID         string
+	Data       []byte
+	Attributes map[string]string
+}
+
+// Handler processes a delivered message; returning an error nacks it
+type Handler func(ctx context.Context, msg *Message) error
+
+// Broker routes messages published to a topic to every handler subscribed to it
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+	nacked      []*Message
+	nextID      atomic.Int64
+}
+
+// NewBroker creates an empty in-memory broker
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[string][]Handler)}
+}
+
+// Topic returns a publisher for topicID
+func (b *Broker) Topic(topicID string) *Topic {
+	return &Topic{broker: b, id: topicID}
+}
+
+// Subscribe registers handler for the messages published to topicID
+func (b *Broker) Subscribe(topicID string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[topicID] = append(b.subscribers[topicID], handler)
+}
+
+// Nacked returns the messages a handler failed to process
+func (b *Broker) Nacked() []*Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return append([]*Message(nil), b.nacked...)
+}
+
+// deliver synchronously hands msg to every subscriber of topicID
+func (b *Broker) deliver(ctx context.Context, topicID string, msg *Message) {
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.subscribers[topicID]...)
+	b.mu.RUnlock()
+
+	ctx = logging.WithMessageID(ctx, msg.ID)
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil {
+			b.mu.Lock()
+			b.nacked = append(b.nacked, msg)
+			b.mu.Unlock()
+		}
+	}
+}
+
+// Topic publishes messages to the subscribers of a single topic; it implements pubsub.Publisher
+type Topic struct {
+	broker *Broker
+	id     string
+}
+
+// Publish delivers data to the topic subscribers before returning the message ID
+func (t *Topic) Publish(ctx context.Context, data []byte, attrs map[string]string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	msg := &Message{
+		ID:         strconv.FormatInt(t.broker.nextID.Add(1), 10),
+		Data:       append([]byte(nil), data...),
+		Attributes: attrs,
+	}
+	t.broker.deliver(ctx, t.id, msg)
+	return msg.ID, nil
+}
+
+// SubscribeJSON registers handler for topicID, decoding each message's JSON data into T
+// like the Pub/Sub client's Receive methods do
+func SubscribeJSON[T any](b *Broker, topicID string, handler func(context.Context, *T) error) {
+	b.Subscribe(topicID, func(ctx context.Context, msg *Message) error {
+		var payload T
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal message %s: %w", msg.ID, err)
+		}
+		return handler(ctx, &payload)
+	})
+}
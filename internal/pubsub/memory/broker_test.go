@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go_integration/internal/logging"
+	"go_integration/internal/models"
+)
+
+func TestBrokerDelivery(t *testing.T) {
+	tests := []struct {
+		name         string
+		handlerErrs  []error
+		wantNacked   int
+		wantReceived int
+	}{
+		{name: "single subscriber", handlerErrs: []error{nil}, wantReceived: 1},
+		{name: "fan out", handlerErrs: []error{nil, nil}, wantReceived: 2},
+		{name: "failed handler nacks", handlerErrs: []error{errors.New("boom"), nil}, wantReceived: 2, wantNacked: 1},
+		{name: "no subscribers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewBroker()
+			received := 0
+			for _, err := range tt.handlerErrs {
+				broker.Subscribe("emails", func(ctx context.Context, msg *Message) error {
+					received++
+					if logging.MessageID(ctx) != msg.ID {
+						t.Errorf("context message ID = %q, want %q", logging.MessageID(ctx), msg.ID)
+					}
+					return err
+				})
+			}
+			// Subscribers of other topics never see the message
+			broker.Subscribe("users", func(context.Context, *Message) error {
+				t.Error("message delivered to another topic")
+				return nil
+			})
+
+			id, err := broker.Topic("emails").Publish(context.Background(), []byte("{}"), map[string]string{"priority": "high"})
+			if err != nil || id == "" {
+				t.Fatalf("Publish = %q, %v, want a message ID", id, err)
+			}
+			if received != tt.wantReceived || len(broker.Nacked()) != tt.wantNacked {
+				t.Errorf("received %d and nacked %d, want %d and %d", received, len(broker.Nacked()), tt.wantReceived, tt.wantNacked)
+			}
+		})
+	}
+}
+
+func TestBrokerPublishCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewBroker().Topic("emails").Publish(ctx, []byte("{}"), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Publish error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSubscribeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantTo     string
+		wantNacked int
+	}{
+		{name: "decodes the payload", data: `{"to":"ana@example.com","subject":"Oi"}`, wantTo: "ana@example.com"},
+		{name: "invalid JSON is nacked", data: `{"to":`, wantNacked: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewBroker()
+			var got string
+			SubscribeJSON(broker, "emails", func(_ context.Context, payload *models.EmailPayload) error {
+				got = payload.To
+				return nil
+			})
+
+			broker.Topic("emails").Publish(context.Background(), []byte(tt.data), nil)
+
+			if got != tt.wantTo || len(broker.Nacked()) != tt.wantNacked {
+				t.Errorf("received %q with %d nacked, want %q and %d", got, len(broker.Nacked()), tt.wantTo, tt.wantNacked)
+			}
+		})
+	}
+}
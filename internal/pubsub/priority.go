@@ -0,0 +1,24 @@
+package pubsub
+
+import (
+	"fmt"
+
+	"go_integration/internal/models"
+)
+
+// PriorityAttribute carries the priority lane of an email message, "high" or "low"
+const PriorityAttribute = "priority"
+
+// PriorityAttributes returns the attributes routing a message to the lane of priority
+func PriorityAttributes(priority string) map[string]string {
+	return map[string]string{PriorityAttribute: priority}
+}
+
+// PriorityFilter returns the subscription filter of a priority lane. The low lane takes every message
+// that is not high priority, so messages published without the attribute are never lost.
+func PriorityFilter(priority string) string {
+	if priority == models.PriorityHigh {
+		return fmt.Sprintf("attributes.%s = %q", PriorityAttribute, models.PriorityHigh)
+	}
+	return fmt.Sprintf("NOT attributes.%s = %q", PriorityAttribute, models.PriorityHigh)
+}
@@ -0,0 +1,26 @@
+package pubsub
+
+import (
+	"testing"
+
+	"go_integration/internal/models"
+)
+
+func TestPriorityFilter(t *testing.T) {
+	tests := []struct {
+		priority string
+		want     string
+	}{
+		{priority: models.PriorityHigh, want: `attributes.priority = "high"`},
+		{priority: models.PriorityLow, want: `NOT attributes.priority = "high"`},
+		{priority: "", want: `NOT attributes.priority = "high"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority, func(t *testing.T) {
+			if got := PriorityFilter(tt.priority); got != tt.want {
+				t.Errorf("PriorityFilter(%q) = %s, want %s", tt.priority, got, tt.want)
+			}
+		})
+	}
+}
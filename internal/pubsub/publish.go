@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// DefaultPublishTimeout bounds how long a publish waits for the server acknowledgement
+const DefaultPublishTimeout = 10 * time.Second
+
+// Publisher publishes raw message data with optional attributes and returns the message ID
+type Publisher interface {
+	Publish(ctx context.Context, data []byte, attrs map[string]string) (string, error)
+}
+
+// TopicPublisher is a Publisher backed by a Pub/Sub topic
+type TopicPublisher struct {
+	topic   *pubsub.Topic
+	timeout time.Duration
+}
+
+// NewTopicPublisher creates a Publisher for topic, waiting at most timeout for each acknowledgement (0 disables the timeout)
+func NewTopicPublisher(topic *pubsub.Topic, timeout time.Duration) *TopicPublisher {
+	return &TopicPublisher{
+		topic:   topic,
+		timeout: timeout,
+	}
+}
+
+// Publish publishes data to the topic and waits for the server acknowledgement
+func (p *TopicPublisher) Publish(ctx context.Context, data []byte, attrs map[string]string) (string, error) {
+	result := p.topic.Publish(ctx, &pubsub.Message{
+		Data:       data,
+		Attributes: attrs,
+	})
+	return getWithTimeout(ctx, result, p.timeout)
+}
+
+// getWithTimeout waits for a publish result, giving up after timeout (0 waits as long as ctx allows)
+func getWithTimeout(ctx context.Context, result *pubsub.PublishResult, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	id, err := result.Get(ctx)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("publish timed out after %s: %w", timeout, err)
+	}
+	return id, err
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+)
+
+func TestTopicPublisherTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		autoRespond  bool
+		timeout      time.Duration
+		wantTimedOut bool
+	}{
+		{name: "acknowledged", autoRespond: true, timeout: time.Second},
+		{name: "acknowledgement never arrives", timeout: 50 * time.Millisecond, wantTimedOut: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClientWithServer(t)
+			server.SetAutoPublishResponse(tt.autoRespond)
+
+			ctx := context.Background()
+			topic, err := client.client.CreateTopic(ctx, "emails")
+			if err != nil {
+				t.Fatalf("failed to create topic: %v", err)
+			}
+			defer topic.Stop()
+
+			id, err := NewTopicPublisher(topic, tt.timeout).Publish(ctx, []byte("{}"), nil)
+			if tt.wantTimedOut {
+				// Release the pending publish so the topic can stop
+				server.AddPublishResponse(&pubsubpb.PublishResponse{MessageIds: []string{"released"}}, nil)
+				if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out") {
+					t.Fatalf("Publish error = %v, want a publish timeout", err)
+				}
+				return
+			}
+			if err != nil || id == "" {
+				t.Fatalf("Publish = %q, %v, want a message ID", id, err)
+			}
+		})
+	}
+}
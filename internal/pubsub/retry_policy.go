@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// MaxBackoff is the longest redelivery backoff Pub/Sub accepts
+const MaxBackoff = 600 * time.Second
+
+// RetryPolicy sets the redelivery backoff Pub/Sub applies to nacked or expired messages of a subscription.
+// Zero values keep the Pub/Sub defaults (10s minimum, 600s maximum).
+type RetryPolicy struct {
+	MinBackoff time.Duration `yaml:"min_backoff,omitempty" json:"min_backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+}
+
+// IsZero reports whether the policy leaves the Pub/Sub defaults in place
+func (p RetryPolicy) IsZero() bool {
+	return p.MinBackoff == 0 && p.MaxBackoff == 0
+}
+
+// Validate checks that both backoffs are within the range Pub/Sub accepts and in order
+func (p RetryPolicy) Validate() error {
+	if p.MinBackoff < 0 || p.MinBackoff > MaxBackoff {
+		return fmt.Errorf("min backoff must be between 0 and %s", MaxBackoff)
+	}
+	if p.MaxBackoff < 0 || p.MaxBackoff > MaxBackoff {
+		return fmt.Errorf("max backoff must be between 0 and %s", MaxBackoff)
+	}
+	if p.MinBackoff > 0 && p.MaxBackoff > 0 && p.MinBackoff > p.MaxBackoff {
+		return fmt.Errorf("min backoff %s is greater than max backoff %s", p.MinBackoff, p.MaxBackoff)
+	}
+	return nil
+}
+
+// toPubSub converts the policy for a subscription config, returning nil when it is zero
+func (p RetryPolicy) toPubSub() *pubsub.RetryPolicy {
+	if p.IsZero() {
+		return nil
+	}
+
+	policy := &pubsub.RetryPolicy{}
+	if p.MinBackoff > 0 {
+		policy.MinimumBackoff = p.MinBackoff
+	}
+	if p.MaxBackoff > 0 {
+		policy.MaximumBackoff = p.MaxBackoff
+	}
+	return policy
+}
+
+// matches reports whether current, read from an existing subscription, already applies the policy
+func (p RetryPolicy) matches(current *pubsub.RetryPolicy) bool {
+	if current == nil {
+		return p.IsZero()
+	}
+
+	minBackoff, _ := current.MinimumBackoff.(time.Duration)
+	maxBackoff, _ := current.MaximumBackoff.(time.Duration)
+	return (p.MinBackoff == 0 || p.MinBackoff == minBackoff) && (p.MaxBackoff == 0 || p.MaxBackoff == maxBackoff)
+}
@@ -0,0 +1,79 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		wantErr bool
+	}{
+		{name: "defaults", policy: RetryPolicy{}},
+		{name: "in range", policy: RetryPolicy{MinBackoff: 10 * time.Second, MaxBackoff: 5 * time.Minute}},
+		{name: "only max", policy: RetryPolicy{MaxBackoff: MaxBackoff}},
+		{name: "negative min", policy: RetryPolicy{MinBackoff: -time.Second}, wantErr: true},
+		{name: "max above the Pub/Sub limit", policy: RetryPolicy{MaxBackoff: MaxBackoff + time.Second}, wantErr: true},
+		{name: "min above max", policy: RetryPolicy{MinBackoff: time.Minute, MaxBackoff: 10 * time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		current *pubsub.RetryPolicy
+		want    bool
+	}{
+		{name: "both unset", want: true},
+		{name: "policy missing on the subscription", policy: RetryPolicy{MinBackoff: 10 * time.Second}},
+		{
+			name:    "same backoffs",
+			policy:  RetryPolicy{MinBackoff: 10 * time.Second, MaxBackoff: time.Minute},
+			current: &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second, MaximumBackoff: time.Minute},
+			want:    true,
+		},
+		{
+			name:    "unset side is ignored",
+			policy:  RetryPolicy{MaxBackoff: time.Minute},
+			current: &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second, MaximumBackoff: time.Minute},
+			want:    true,
+		},
+		{
+			name:    "different max",
+			policy:  RetryPolicy{MaxBackoff: 5 * time.Minute},
+			current: &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second, MaximumBackoff: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.matches(tt.current); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyToPubSub(t *testing.T) {
+	if got := (RetryPolicy{}).toPubSub(); got != nil {
+		t.Errorf("zero policy = %+v, want nil to keep the Pub/Sub defaults", got)
+	}
+
+	policy := RetryPolicy{MinBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	if !policy.matches(policy.toPubSub()) {
+		t.Error("converted policy does not match itself")
+	}
+}
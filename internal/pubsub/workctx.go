@@ -0,0 +1,29 @@
+package pubsub
+
+import "context"
+
+// workContextKey holds the context message handlers run under, set by WithWorkContext
+type workContextKey struct{}
+
+// WithWorkContext returns a copy of the receive context ctx whose message handlers run under work instead.
+// Canceling ctx then only stops pulling new messages (a drain): the messages already received keep running,
+// and are acked, until they finish or work is done.
+func WithWorkContext(ctx, work context.Context) context.Context {
+	return context.WithValue(ctx, workContextKey{}, work)
+}
+
+// handlerContext detaches the message context ctx from the receive cancellation when a work context is set,
+// canceling it with the work context instead
+func handlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	work, ok := ctx.Value(workContextKey{}).(context.Context)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(work, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_integration/internal/models"
+)
+
+func TestHandlerContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		withWork   bool
+		cancelWork bool
+		wantDone   bool
+	}{
+		{name: "without a work context the receive cancellation stops the handler", wantDone: true},
+		{name: "a drain leaves the handler running", withWork: true},
+		{name: "canceling the work stops the handler", withWork: true, cancelWork: true, wantDone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiveCtx, stopReceiving := context.WithCancel(context.Background())
+			work, cancelWork := context.WithCancel(context.Background())
+			defer cancelWork()
+
+			ctx := receiveCtx
+			if tt.withWork {
+				ctx = WithWorkContext(receiveCtx, work)
+			}
+			handlerCtx, cancel := handlerContext(ctx)
+			defer cancel()
+
+			stopReceiving()
+			if tt.cancelWork {
+				cancelWork()
+			}
+
+			select {
+			case <-handlerCtx.Done():
+				if !tt.wantDone {
+					t.Error("handler context canceled, want it still running")
+				}
+			case <-time.After(50 * time.Millisecond):
+				if tt.wantDone {
+					t.Error("handler context still running, want it canceled")
+				}
+			}
+		})
+	}
+}
+
+func TestReceiveDrainFinishesInFlightMessages(t *testing.T) {
+	client, server := newTestClientWithServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	handles, err := client.EnsureAll(ctx, []TopicSpec{{Name: "emails", Subscription: "emails-worker"}})
+	if err != nil {
+		t.Fatalf("EnsureAll failed: %v", err)
+	}
+	server.Publish("projects/test-project/topics/emails", []byte(`{"to":"ana@example.com","subject":"Oi","body":"Olá"}`), nil)
+
+	receiveCtx, drain := context.WithCancel(ctx)
+	started := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Receive(WithWorkContext(receiveCtx, ctx), handles["emails"].Subscription, func(ctx context.Context, _ *models.EmailPayload) error {
+			close(started)
+			// Keep working past the drain, as a slow send would
+			time.Sleep(200 * time.Millisecond)
+			handlerErr <- ctx.Err()
+			return nil
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("message was never received")
+	}
+	drain()
+
+	if err := <-handlerErr; err != nil {
+		t.Errorf("handler context canceled by the drain: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Receive failed: %v", err)
+	}
+	if acked, _ := waitForAck(ctx, server); !acked {
+		t.Error("in-flight message was not acked after the drain")
+	}
+}
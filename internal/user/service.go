@@ -4,26 +4,73 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"go_integration/internal/audit"
+	"go_integration/internal/email"
+	"go_integration/internal/logging"
 	"go_integration/internal/models"
+	ipubsub "go_integration/internal/pubsub"
 
 	"cloud.google.com/go/pubsub"
 )
 
 // Service handles user-related operations
 type Service struct {
-	userTopic *pubsub.Topic
+	userPublisher    ipubsub.Publisher
+	auditLogger      audit.Logger
+	verifications    email.VerificationStore
+	batchConcurrency int
 }
 
 // NewService creates a new user service
-func NewService(userTopic *pubsub.Topic) *Service {
+func NewService(userPublisher ipubsub.Publisher) *Service {
 	return &Service{
-		userTopic: userTopic,
+		userPublisher:    userPublisher,
+		batchConcurrency: email.DefaultBatchConcurrency,
+	}
+}
+
+// SetBatchConcurrency caps the publishes CreateUsers keeps in flight at once (<= 0 uses email.DefaultBatchConcurrency)
+func (s *Service) SetBatchConcurrency(concurrency int) {
+	if concurrency <= 0 {
+		concurrency = email.DefaultBatchConcurrency
+	}
+	s.batchConcurrency = concurrency
+}
+
+// SetAuditLogger records every successful publish in an audit trail (nil disables auditing)
+func (s *Service) SetAuditLogger(logger audit.Logger) {
+	s.auditLogger = logger
+}
+
+// SetVerificationStore records the verification email the worker sends for each created user so
+// POST /resend-verification can find it (nil disables recording)
+func (s *Service) SetVerificationStore(store email.VerificationStore) {
+	s.verifications = store
+}
+
+// rememberVerification saves the verification email the worker will send for payload, if it needs one
+func (s *Service) rememberVerification(ctx context.Context, payload *models.UserPayload) {
+	if s.verifications == nil || !payload.NeedsVerification() {
+		return
+	}
+	if err := s.verifications.Save(ctx, models.NewVerificationEmailPayload(payload, time.Now())); err != nil {
+		log.Printf("Failed to store verification email for %s: %v", logging.Recipient(payload.Email), err)
+	}
+}
+
+// audit records a successful publish when an audit logger is configured
+func (s *Service) audit(ctx context.Context, recipient, messageID string) {
+	if s.auditLogger != nil {
+		s.auditLogger.Record(ctx, audit.NewEntry(audit.TypeUser, recipient, messageID))
 	}
 }
 
 // CreateUser publishes a user creation message to the topic
 func (s *Service) CreateUser(ctx context.Context, payload *models.UserPayload) (string, error) {
+	payload.Normalize()
 	if err := payload.Validate(); err != nil {
 		return "", fmt.Errorf("invalid payload: %w", err)
 	}
@@ -33,16 +80,63 @@ func (s *Service) CreateUser(ctx context.Context, payload *models.UserPayload) (
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	result := s.userTopic.Publish(ctx, &pubsub.Message{Data: data})
-	id, err := result.Get(ctx)
+	id, err := s.userPublisher.Publish(ctx, data, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to publish message: %w", err)
 	}
 
 	log.Printf("Published user creation message with ID: %s", id)
+	s.audit(ctx, payload.Email, id)
+	s.rememberVerification(ctx, payload)
 	return id, nil
 }
 
+// CreateUsers publishes a batch of user creation messages, returning the message ID or error for each item by index
+func (s *Service) CreateUsers(ctx context.Context, payloads []*models.UserPayload) ([]string, []error) {
+	ids := make([]string, len(payloads))
+	errs := make([]error, len(payloads))
+
+	// Publish concurrently so the Pub/Sub client can batch the messages, bounded by the semaphore
+	sem := make(chan struct{}, s.batchConcurrency)
+	var wg sync.WaitGroup
+	for i, payload := range payloads {
+		if payload == nil {
+			errs[i] = fmt.Errorf("invalid payload: empty user")
+			continue
+		}
+		payload.Normalize()
+		if err := payload.Validate(); err != nil {
+			errs[i] = fmt.Errorf("invalid payload: %w", err)
+			continue
+		}
+
+		data, err := payload.ToJSON()
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal payload: %w", err)
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			id, err := s.userPublisher.Publish(ctx, data, nil)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to publish message: %w", err)
+				return
+			}
+			ids[i] = id
+			s.audit(ctx, payload.Email, id)
+			s.rememberVerification(ctx, payload)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("Published user creation batch with %d messages", len(payloads))
+	return ids, errs
+}
+
 // MessageHandler defines the function signature for processing user messages
 type MessageHandler func(ctx context.Context, payload *models.UserPayload) error
 
@@ -55,7 +149,7 @@ func ProcessMessage(ctx context.Context, msg *pubsub.Message, handler MessageHan
 		return
 	}
 
-	log.Printf("Processing user creation: ID=%s, Email=%s, Name=%s", payload.ID, payload.Email, payload.Name)
+	log.Printf("Processing user creation: ID=%s, Email=%s, Name=%s", payload.ID, logging.Recipient(payload.Email), payload.Name)
 
 	if err := handler(ctx, payload); err != nil {
 		log.Printf("Failed to process user message: %v", err)
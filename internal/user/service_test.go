@@ -0,0 +1,164 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_integration/internal/email"
+	"go_integration/internal/models"
+)
+
+// stubPublisher hands out sequential message IDs, failing with err when set
+type stubPublisher struct {
+	mu    sync.Mutex
+	count int
+	err   error
+}
+
+func (p *stubPublisher) Publish(context.Context, []byte, map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return "", p.err
+	}
+	p.count++
+	return strconv.Itoa(p.count), nil
+}
+
+func TestCreateUserPublish(t *testing.T) {
+	unavailable := errors.New("pubsub unavailable")
+
+	tests := []struct {
+		name       string
+		publishErr error
+		wantID     string
+	}{
+		{name: "published", wantID: "1"},
+		{name: "publish error", publishErr: unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&stubPublisher{err: tt.publishErr})
+
+			id, err := service.CreateUser(context.Background(), &models.UserPayload{ID: "u1", Email: "ana@example.com", Name: "Ana"})
+			if !errors.Is(err, tt.publishErr) {
+				t.Fatalf("CreateUser error = %v, want %v", err, tt.publishErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("CreateUser = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestCreateUserRemembersVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     models.UserPayload
+		wantCode string
+	}{
+		{name: "with a code", user: models.UserPayload{ID: "u1", Email: "ana@example.com", Name: "Ana", Code: "123456"}, wantCode: "123456"},
+		{name: "without verification", user: models.UserPayload{ID: "u2", Email: "ana@example.com", Name: "Ana"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := email.NewMemoryVerificationStore(time.Hour)
+			service := NewService(&stubPublisher{})
+			service.SetVerificationStore(store)
+
+			if _, err := service.CreateUser(context.Background(), &tt.user); err != nil {
+				t.Fatalf("CreateUser failed: %v", err)
+			}
+
+			got, err := store.Latest(context.Background(), tt.user.Email)
+			if tt.wantCode == "" {
+				if !errors.Is(err, email.ErrVerificationNotFound) {
+					t.Errorf("Latest = %+v, %v, want ErrVerificationNotFound", got, err)
+				}
+				return
+			}
+			if err != nil || got.Code != tt.wantCode {
+				t.Errorf("Latest = %+v, %v, want code %q", got, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateUsersRemembersVerification(t *testing.T) {
+	store := email.NewMemoryVerificationStore(time.Hour)
+	service := NewService(&stubPublisher{})
+	service.SetVerificationStore(store)
+
+	users := []*models.UserPayload{
+		{ID: "u1", Email: "ana@example.com", Name: "Ana", Code: "111111"},
+		{ID: "u2", Email: "eva@example.com", Name: "Eva", Code: "222222"},
+	}
+	if _, errs := service.CreateUsers(context.Background(), users); errors.Join(errs...) != nil {
+		t.Fatalf("CreateUsers failed: %v", errors.Join(errs...))
+	}
+
+	for _, user := range users {
+		if got, err := store.Latest(context.Background(), user.Email); err != nil || got.Code != user.Code {
+			t.Errorf("Latest(%s) = %+v, %v, want code %q", user.Email, got, err, user.Code)
+		}
+	}
+}
+
+// concurrencyPublisher records the largest number of publishes in flight at once
+type concurrencyPublisher struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (p *concurrencyPublisher) Publish(context.Context, []byte, map[string]string) (string, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.peak = max(p.peak, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return "id", nil
+}
+
+func TestCreateUsersConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantMax     int
+	}{
+		{name: "one at a time", concurrency: 1, wantMax: 1},
+		{name: "bounded", concurrency: 3, wantMax: 3},
+		{name: "default", concurrency: 0, wantMax: email.DefaultBatchConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &concurrencyPublisher{}
+			service := NewService(publisher)
+			service.SetBatchConcurrency(tt.concurrency)
+
+			users := make([]*models.UserPayload, 20)
+			for i := range users {
+				users[i] = &models.UserPayload{ID: strconv.Itoa(i), Email: "ana@example.com", Name: "Ana"}
+			}
+			if _, errs := service.CreateUsers(context.Background(), users); errors.Join(errs...) != nil {
+				t.Fatalf("CreateUsers failed: %v", errors.Join(errs...))
+			}
+
+			if publisher.peak > tt.wantMax {
+				t.Errorf("%d publishes in flight, want at most %d", publisher.peak, tt.wantMax)
+			}
+		})
+	}
+}